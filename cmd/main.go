@@ -1,6 +1,7 @@
 package main
 
 import (
+	"EWSBE/internal/auth"
 	"EWSBE/internal/config"
 	"EWSBE/internal/db"
 	deliver "EWSBE/internal/delivery"
@@ -8,6 +9,7 @@ import (
 	"EWSBE/internal/model"
 	"EWSBE/internal/mqtt"
 	"EWSBE/internal/usecase"
+	"EWSBE/internal/webhook"
 	ws "EWSBE/internal/websocket"
 	"context"
 	"log"
@@ -39,10 +41,24 @@ func main() {
 		log.Fatalf("failed to connect db: %v", err)
 	}
 
+	// accounts created before roles existed came from open registration, so
+	// they start with the lowest role; see ADMIN_PROMOTE_USER for the admin
+	hadRoles := gormDB.Migrator().HasColumn(&entity.User{}, "Role")
+
 	// auto migrate
-	if err := gormDB.AutoMigrate(&entity.SensorData{}, &entity.User{}, &entity.News{}); err != nil {
+	if err := gormDB.AutoMigrate(&entity.Station{}, &entity.SensorData{}, &entity.User{}, &entity.News{}, &entity.AlertRule{}, &entity.Alert{}, &entity.Webhook{}, &entity.WebhookDelivery{}, &entity.StationCommand{}, &entity.DeadLetter{}, &entity.DeviceKey{}, &entity.Invite{}, &entity.Session{}, &entity.RefreshToken{}, &entity.LoginAttempt{}, &entity.LoginLockout{}); err != nil {
 		log.Fatalf("automigrate: %v", err)
 	}
+	if !hadRoles {
+		// the new column defaults to viewer; news authors keep publishing
+		res := gormDB.Model(&entity.User{}).
+			Where("id IN (?)", gormDB.Model(&entity.News{}).Distinct("author_id")).
+			Update("role", entity.RoleEditor)
+		if res.Error != nil {
+			log.Fatalf("migrate user roles: %v", res.Error)
+		}
+		log.Printf("Existing users migrated to the viewer role, %d news authors to editor", res.RowsAffected)
+	}
 	log.Println("Database migration completed")
 
 	// Initialize WebSocket hub
@@ -50,48 +66,234 @@ func main() {
 	go hub.Run()
 	log.Println("WebSocket hub started")
 
+	// webhooks
+	webhookRepo := model.NewWebhookRepo(gormDB)
+	dispatcher := webhook.NewDispatcher(webhookRepo, webhook.Config{
+		Workers:     config.GetEnvInt("WEBHOOK_WORKERS", 2),
+		QueueSize:   config.GetEnvInt("WEBHOOK_QUEUE_SIZE", 256),
+		MaxAttempts: config.GetEnvInt("WEBHOOK_MAX_ATTEMPTS", 5),
+		BaseBackoff: config.GetEnvDuration("WEBHOOK_BACKOFF", 2*time.Second),
+		MaxBackoff:  config.GetEnvDuration("WEBHOOK_MAX_BACKOFF", 5*time.Minute),
+		Timeout:     config.GetEnvDuration("WEBHOOK_TIMEOUT", 10*time.Second),
+	})
+	dispatcher.Start()
+	webhookUc := usecase.NewWebhookUsecase(webhookRepo, dispatcher)
+
+	// alert engine
+	alertRepo := model.NewAlertRepo(gormDB)
+	alertUc := usecase.NewAlertUsecase(alertRepo)
+	if err := alertUc.Load(); err != nil {
+		log.Fatalf("load alert rules: %v", err)
+	}
+
+	alertUc.Subscribe(func(ev entity.AlertEvent) {
+		log.Printf("alert %s: rule %s %s -> %s (value %.2f)",
+			ev.Type, ev.Alert.Rule.Name, ev.PreviousSeverity, ev.Alert.Severity, ev.Alert.LastValue)
+		if err := hub.BroadcastAlert(ev); err != nil {
+			log.Printf("alert: failed to broadcast: %v", err)
+		}
+		webhookUc.Publish(entity.WebhookEventForAlert(ev.Type), ev)
+	})
+
+	// stations
+	stationRepo := model.NewStationRepo(gormDB)
+	stationUc := usecase.NewStationUsecase(stationRepo)
+
+	// heartbeat monitor: a station is stale after STATION_STALE_FACTOR and
+	// offline after STATION_OFFLINE_FACTOR expected intervals without data
+	monitor := usecase.NewStationMonitor(stationRepo, stationUc, usecase.MonitorConfig{
+		DefaultInterval: config.GetEnvDuration("STATION_DEFAULT_INTERVAL", time.Minute),
+		StaleFactor:     config.GetEnvFloat("STATION_STALE_FACTOR", 2),
+		OfflineFactor:   config.GetEnvFloat("STATION_OFFLINE_FACTOR", 5),
+		CheckEvery:      config.GetEnvDuration("STATION_CHECK_INTERVAL", 15*time.Second),
+	})
+	if err := monitor.Load(); err != nil {
+		log.Fatalf("load station heartbeats: %v", err)
+	}
+
+	monitor.Subscribe(func(ev entity.StationStatusEvent) {
+		log.Printf("station %s: %s -> %s", ev.Station.Code, ev.PreviousStatus, ev.Station.Status)
+		if err := hub.BroadcastStationStatus(ev); err != nil {
+			log.Printf("station: failed to broadcast status: %v", err)
+		}
+		switch {
+		case ev.Station.Status == entity.StationOffline:
+			webhookUc.Publish(entity.WebhookEventStationOffline, ev)
+		case ev.Station.Status == entity.StationOnline && ev.PreviousStatus == entity.StationOffline:
+			webhookUc.Publish(entity.WebhookEventStationOnline, ev)
+		}
+	})
+
+	monitorCtx, stopMonitor := context.WithCancel(context.Background())
+	monitorDone := make(chan struct{})
+	go func() {
+		monitor.Run(monitorCtx)
+		close(monitorDone)
+	}()
+
 	// wiring repo -> usecase -> handler (GIN)
 	dataRepo := model.NewDataRepo(gormDB)
-	dataUc := usecase.NewDataUsecase(dataRepo)
+	dataUc := usecase.NewDataUsecase(dataRepo, alertUc, stationUc, monitor, usecase.PowerConfig{
+		Chemistry:     config.GetEnv("POWER_BATTERY_CHEMISTRY", entity.BatteryLiIon),
+		Cells:         config.GetEnvInt("POWER_BATTERY_CELLS", 1),
+		ChargeCurrent: config.GetEnvFloat("POWER_CHARGE_CURRENT_MA", 10),
+		TrendWindow:   config.GetEnvDuration("POWER_TREND_WINDOW", 6*time.Hour),
+		SeedWindow:    config.GetEnvDuration("POWER_SEED_WINDOW", 72*time.Hour),
+	}, usecase.DedupConfig{
+		CacheSize: config.GetEnvInt("INGEST_DEDUP_CACHE_SIZE", 10000),
+		CacheTTL:  config.GetEnvDuration("INGEST_DEDUP_TTL", 24*time.Hour),
+	})
 
 	// auth components
+	jwtCfg, err := config.LoadJWTConfig()
+	if err != nil {
+		log.Fatalf("jwt: %v", err)
+	}
+	tokens, err := auth.NewTokenService(jwtCfg)
+	if err != nil {
+		log.Fatalf("jwt: %v", err)
+	}
 	userRepo := model.NewUserRepo(gormDB)
-	authUc := usecase.NewAuthUsecase(userRepo)
+	sessionUc := usecase.NewSessionUsecase(model.NewSessionRepo(gormDB), userRepo, usecase.SessionConfig{
+		AccessTTL:  config.GetEnvDuration("ACCESS_TOKEN_TTL", 15*time.Minute),
+		RefreshTTL: config.GetEnvDuration("REFRESH_TOKEN_TTL", 7*24*time.Hour),
+	})
+	loginGuard := usecase.NewLoginGuard(model.NewLoginRepo(gormDB), usecase.LoginGuardConfig{
+		FreeAttempts:   config.GetEnvInt("LOGIN_FREE_ATTEMPTS", 3),
+		BaseDelay:      config.GetEnvDuration("LOGIN_BASE_DELAY", time.Second),
+		MaxDelay:       config.GetEnvDuration("LOGIN_MAX_DELAY", 30*time.Second),
+		UsernameLockAt: config.GetEnvInt("LOGIN_USERNAME_LOCK_AT", 10),
+		IPLockAt:       config.GetEnvInt("LOGIN_IP_LOCK_AT", 50),
+		LockFor:        config.GetEnvDuration("LOGIN_LOCKOUT", 15*time.Minute),
+		Window:         config.GetEnvDuration("LOGIN_FAILURE_WINDOW", time.Hour),
+		Retention:      config.GetEnvDuration("LOGIN_AUDIT_RETENTION", 90*24*time.Hour),
+	})
+	// purge expired refresh tokens, stale sessions, quiet login counters and
+	// old login attempts
+	authCtx, stopAuth := context.WithCancel(context.Background())
+	go sessionUc.Run(authCtx)
+	go loginGuard.Run(authCtx)
+	authUc := usecase.NewAuthUsecase(userRepo, config.GetEnv("REGISTRATION_MODE", usecase.RegistrationInvite), loginGuard)
+	userUc := usecase.NewUserUsecase(userRepo, sessionUc)
+	if created, err := userUc.Bootstrap(config.GetEnv("ADMIN_USERNAME", ""), config.GetEnv("ADMIN_PASSWORD", "")); err != nil {
+		log.Printf("Admin bootstrap: %v", err)
+	} else if created {
+		log.Println("Created the initial admin account")
+	}
+	// existing databases get their first admin from a named account
+	if promoted, err := userUc.PromoteFirstAdmin(config.GetEnv("ADMIN_PROMOTE_USER", "")); err != nil {
+		log.Printf("Admin promotion: %v", err)
+	} else if promoted {
+		log.Println("Granted the admin role to ADMIN_PROMOTE_USER")
+	}
 
 	// news components
 	newsRepo := model.NewNewsRepo(gormDB)
 	newsUc := usecase.NewNewsUsecase(newsRepo)
+	newsUc.Subscribe(func(n entity.News) {
+		webhookUc.Publish(entity.WebhookEventNewsPublished, n)
+	})
+
+	mqttCfg := config.LoadMQTTConfig()
+
+	// downlink commands, published once the MQTT connection is up
+	commandUc := usecase.NewCommandUsecase(model.NewCommandRepo(gormDB), stationUc, usecase.CommandConfig{
+		Topic:       mqttCfg.CommandTopic,
+		QoS:         mqttCfg.QoS,
+		Timeout:     config.GetEnvDuration("COMMAND_TIMEOUT", time.Minute),
+		MaxTimeout:  config.GetEnvDuration("COMMAND_MAX_TIMEOUT", 15*time.Minute),
+		SweepEvery:  config.GetEnvDuration("COMMAND_SWEEP_INTERVAL", 5*time.Second),
+		MinInterval: config.GetEnvDuration("COMMAND_MIN_INTERVAL", 5*time.Second),
+		MaxInterval: config.GetEnvDuration("COMMAND_MAX_INTERVAL", 24*time.Hour),
+	})
+	commandUc.Subscribe(func(cmd entity.StationCommand) {
+		log.Printf("command %s (%s) for station %d: %s", cmd.CommandID, cmd.Type, cmd.StationID, cmd.Status)
+		if err := hub.BroadcastCommand(cmd); err != nil {
+			log.Printf("command: failed to broadcast: %v", err)
+		}
+	})
+
+	commandCtx, stopCommands := context.WithCancel(context.Background())
+	commandsDone := make(chan struct{})
+	go func() {
+		commandUc.Run(commandCtx)
+		close(commandsDone)
+	}()
+
+	// rejected MQTT messages, replayed through the pipeline set up below
+	deadLetterUc := usecase.NewDeadLetterUsecase(model.NewDeadLetterRepo(gormDB), usecase.DeadLetterConfig{
+		Retention: config.GetEnvDuration("DEAD_LETTER_RETENTION", 30*24*time.Hour),
+	})
+	deadLetterCtx, stopDeadLetters := context.WithCancel(context.Background())
+	go deadLetterUc.Run(deadLetterCtx)
+
+	// per-station API keys for POST /api/data
+	deviceKeyUc := usecase.NewDeviceKeyUsecase(model.NewDeviceKeyRepo(gormDB), stationUc)
 
 	// unified handler
-	handler := deliver.NewHandler(dataUc, authUc, newsUc, hub)
+	handler := deliver.NewHandler(dataUc, authUc, newsUc, alertUc, stationUc, monitor, webhookUc, commandUc, deadLetterUc, deviceKeyUc, userUc, sessionUc, tokens, loginGuard, hub)
 
 	// mqtt init
-	broker := os.Getenv("MQTT_BROKER")
-	clientID := os.Getenv("MQTT_CLIENT_ID")
-	topic := os.Getenv("MQTT_TOPIC")
+	sensorCfg := mqtt.SensorTopicConfig{
+		DefaultStation:    mqttCfg.DefaultStation,
+		AutoRegister:      mqttCfg.AutoRegister,
+		TimestampStrategy: mqttCfg.TimestampStrategy,
+		Decoders:          usecase.NewDecoderRegistry(),
+	}
+	for _, route := range mqttCfg.DecoderTopics {
+		if err := sensorCfg.Decoders.Route(route.Match, route.Decoder); err != nil {
+			log.Fatalf("mqtt decoder for topic %s: %v", route.Match, err)
+		}
+	}
+	for _, route := range mqttCfg.DecoderVersions {
+		if err := sensorCfg.Decoders.MapVersion(route.Match, route.Decoder); err != nil {
+			log.Fatalf("mqtt decoder for version %s: %v", route.Match, err)
+		}
+	}
+	switch sensorCfg.TimestampStrategy {
+	case entity.TimestampDevice, entity.TimestampServer, entity.TimestampAuto:
+	default:
+		sensorCfg.TimestampStrategy = entity.TimestampAuto
+	}
+
+	// decoding and station routing; rejected messages become dead letters
+	pipeline := mqtt.NewPipeline(dataUc, stationUc, deadLetterUc, sensorCfg)
+	deadLetterUc.SetReplayer(pipeline.Replay)
+
+	// readings are queued and written in batches by a worker pool
+	ingestor := mqtt.NewIngestor(dataUc, hub, pipeline, mqtt.IngestConfig{
+		QueueSize:     config.GetEnvInt("INGEST_QUEUE_SIZE", 1000),
+		Workers:       config.GetEnvInt("INGEST_WORKERS", 2),
+		BatchSize:     config.GetEnvInt("INGEST_BATCH_SIZE", 100),
+		FlushInterval: config.GetEnvDuration("INGEST_FLUSH_INTERVAL", time.Second),
+		EnqueueWait:   config.GetEnvDuration("INGEST_ENQUEUE_WAIT", 2*time.Second),
+	})
+	ingestor.Start()
+	handler.AddHealthCheck("ingestion", func() interface{} { return ingestor.Stats() })
 
-	if broker == "" {
+	var mqttConn *mqtt.Connection
+	if mqttCfg.Broker == "" {
 		log.Println("Warning: MQTT_BROKER not set, skipping MQTT connection")
 	} else {
-		mqttClient, err := mqtt.Connect(broker, clientID)
+		// connects in the background and resubscribes after every reconnect
+		mqttConn, err = mqtt.Connect(mqttCfg)
 		if err != nil {
-			log.Printf("mqtt connect error: %v (continuing without MQTT)", err)
-		} else {
-			// subscribe to sensor topic with WebSocket hub for broadcasting
-			if err := mqtt.SubscribeSensorTopic(mqttClient, topic, 0, dataUc, hub); err != nil {
+			log.Fatalf("mqtt: %v", err)
+		}
+		handler.AddHealthCheck("mqtt", func() interface{} { return mqttConn.Status() })
+
+		// subscribe to sensor topics; the ingestor stores and broadcasts
+		for _, t := range mqttCfg.Topics {
+			if err := mqtt.SubscribeSensorTopic(mqttConn, t, mqttCfg.QoS, ingestor, pipeline); err != nil {
 				log.Printf("mqtt subscribe error: %v", err)
-			} else {
-				log.Printf("mqtt subscribed to topic: %s", topic)
 			}
+		}
 
-			// graceful MQTT disconnect on shutdown
-			defer func() {
-				if mqttClient != nil && mqttClient.IsConnected() {
-					mqttClient.Disconnect(250)
-					log.Println("MQTT client disconnected")
-				}
-			}()
+		// command acknowledgements from the stations
+		if err := mqtt.SubscribeCommandAcks(mqttConn, mqttCfg.AckTopic, mqttCfg.QoS, commandUc); err != nil {
+			log.Printf("mqtt subscribe error: %v", err)
 		}
+		commandUc.SetPublisher(mqttConn)
 	}
 
 	// http server
@@ -130,6 +332,26 @@ func main() {
 	} else {
 		log.Println("Server gracefully stopped")
 	}
+
+	// disconnect before stopping the ingestor so no message is acked after
+	// the queue closes; a persistent session keeps the rest on the broker
+	if mqttConn != nil {
+		mqttConn.Unsubscribe(2 * time.Second)
+		mqttConn.Disconnect(250)
+	}
+	ingestor.Stop(ctx)
+
+	// persist the last heartbeats
+	stopMonitor()
+	<-monitorDone
+
+	stopCommands()
+	<-commandsDone
+	stopAuth()
+	stopDeadLetters()
+
+	// flush queued webhook deliveries
+	dispatcher.Stop(ctx)
 }
 
 func normalizeAddr(port string) string {
@@ -0,0 +1,64 @@
+#!/bin/sh
+# Generates a local CA, a broker certificate and a client certificate for the
+# backend, plus the Mosquitto password file used by mosquitto-secure.conf.
+#
+#   ./docker/mqtt/gen-certs.sh [broker-host] [backend-password]
+#
+# The broker certificate is valid for localhost, mqtt (the compose service)
+# and broker-host if given. Output goes to docker/mqtt/certs (git-ignored).
+set -eu
+
+DIR=$(cd "$(dirname "$0")" && pwd)
+OUT="$DIR/certs"
+HOST=${1:-}
+PASS=${2:-changeme}
+DAYS=825
+
+mkdir -p "$OUT"
+cd "$OUT"
+
+SAN="DNS:localhost,DNS:mqtt,IP:127.0.0.1"
+if [ -n "$HOST" ]; then
+	SAN="$SAN,DNS:$HOST"
+fi
+
+# certificate authority
+openssl req -x509 -newkey rsa:4096 -sha256 -nodes -days "$DAYS" \
+	-keyout ca.key -out ca.crt -subj "/CN=EWSBE Local MQTT CA"
+
+# broker
+openssl req -newkey rsa:2048 -nodes -keyout server.key -out server.csr \
+	-subj "/CN=${HOST:-localhost}"
+printf "subjectAltName=%s\nextendedKeyUsage=serverAuth\n" "$SAN" > server.ext
+openssl x509 -req -sha256 -days "$DAYS" -in server.csr -CA ca.crt -CAkey ca.key \
+	-CAcreateserial -out server.crt -extfile server.ext
+
+# backend client; the CN must match MQTT_USERNAME
+openssl req -newkey rsa:2048 -nodes -keyout client.key -out client.csr \
+	-subj "/CN=ewsbe_backend"
+printf "extendedKeyUsage=clientAuth\n" > client.ext
+openssl x509 -req -sha256 -days "$DAYS" -in client.csr -CA ca.crt -CAkey ca.key \
+	-CAcreateserial -out client.crt -extfile client.ext
+
+rm -f server.csr server.ext client.csr client.ext
+chmod 644 ca.crt server.crt client.crt
+chmod 600 ca.key server.key client.key
+
+# the broker runs as uid/gid 1883 in the image and reads its key through the group
+if chgrp 1883 server.key 2>/dev/null; then
+	chmod 640 server.key
+else
+	echo "warning: could not give group 1883 access to server.key; run: sudo chgrp 1883 $OUT/server.key && chmod 640 $OUT/server.key" >&2
+fi
+
+# password file, hashed by mosquitto_passwd from the broker image
+if command -v mosquitto_passwd >/dev/null 2>&1; then
+	mosquitto_passwd -b -c passwd ewsbe_backend "$PASS"
+else
+	docker run --rm -v "$OUT":/work eclipse-mosquitto:2 \
+		mosquitto_passwd -b -c /work/passwd ewsbe_backend "$PASS"
+fi
+chmod 644 passwd
+
+echo "certificates and password file written to $OUT"
+echo "add station users with: mosquitto_passwd -b $OUT/passwd <station-code> <password>"
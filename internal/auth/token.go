@@ -0,0 +1,188 @@
+package auth
+
+import (
+	"EWSBE/internal/config"
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Claims carried by an access token
+type Claims struct {
+	UserID      uint     `json:"user_id"`
+	SessionID   string   `json:"sid"`
+	Role        string   `json:"role"`
+	Permissions []string `json:"permissions"`
+	jwt.RegisteredClaims
+}
+
+type key struct {
+	id     string
+	method jwt.SigningMethod
+	sign   interface{} // nil for verification-only keys
+	verify interface{}
+}
+
+// TokenService signs and validates access tokens. Every token names its key
+// in the kid header, so old keys can keep verifying while new tokens are
+// signed with another one.
+type TokenService struct {
+	issuer   string
+	audience string
+	leeway   time.Duration
+	keys     map[string]key
+	signing  key
+	methods  []string
+}
+
+// NewTokenService loads the configured keys and fails when none can sign
+func NewTokenService(cfg config.JWTConfig) (*TokenService, error) {
+	if cfg.Issuer == "" || cfg.Audience == "" {
+		return nil, errors.New("jwt issuer and audience are required")
+	}
+
+	s := &TokenService{
+		issuer:   cfg.Issuer,
+		audience: cfg.Audience,
+		leeway:   cfg.Leeway,
+		keys:     make(map[string]key),
+	}
+	seen := make(map[string]bool) // algorithms in use, the only ones accepted
+	for _, kc := range cfg.Keys {
+		if _, dup := s.keys[kc.ID]; dup {
+			return nil, fmt.Errorf("jwt key %q defined twice", kc.ID)
+		}
+		k, err := loadKey(kc)
+		if err != nil {
+			return nil, fmt.Errorf("jwt key %q: %w", kc.ID, err)
+		}
+		s.keys[k.id] = k
+		if alg := k.method.Alg(); !seen[alg] {
+			seen[alg] = true
+			s.methods = append(s.methods, alg)
+		}
+	}
+
+	if len(s.keys) == 0 {
+		return nil, errors.New("no jwt keys configured, set JWT_SECRET or JWT_KEYS")
+	}
+	signing, ok := s.keys[cfg.SigningKey]
+	if !ok {
+		return nil, fmt.Errorf("signing key %q is not configured", cfg.SigningKey)
+	}
+	if signing.sign == nil {
+		return nil, fmt.Errorf("signing key %q has no private key", cfg.SigningKey)
+	}
+	s.signing = signing
+	return s, nil
+}
+
+func loadKey(kc config.JWTKey) (key, error) {
+	k := key{id: kc.ID}
+	if kc.Value == "" {
+		return k, errors.New("empty secret or key file")
+	}
+
+	switch kc.Algorithm {
+	case "HS256":
+		k.method = jwt.SigningMethodHS256
+		k.sign, k.verify = []byte(kc.Value), []byte(kc.Value)
+		return k, nil
+	case "RS256", "EdDSA":
+	default:
+		return k, fmt.Errorf("unsupported algorithm %q (want HS256, RS256 or EdDSA)", kc.Algorithm)
+	}
+
+	pem, err := os.ReadFile(kc.Value)
+	if err != nil {
+		return k, err
+	}
+
+	if kc.Algorithm == "RS256" {
+		k.method = jwt.SigningMethodRS256
+		if priv, err := jwt.ParseRSAPrivateKeyFromPEM(pem); err == nil {
+			k.sign, k.verify = priv, &priv.PublicKey
+			return k, nil
+		}
+		pub, err := jwt.ParseRSAPublicKeyFromPEM(pem)
+		if err != nil {
+			return k, errors.New("no RSA key in PEM file")
+		}
+		k.verify = pub
+		return k, nil
+	}
+
+	k.method = jwt.SigningMethodEdDSA
+	if priv, err := jwt.ParseEdPrivateKeyFromPEM(pem); err == nil {
+		if priv, ok := priv.(ed25519.PrivateKey); ok {
+			k.sign, k.verify = priv, priv.Public()
+			return k, nil
+		}
+	}
+	pub, err := jwt.ParseEdPublicKeyFromPEM(pem)
+	if err != nil {
+		return k, errors.New("no Ed25519 key in PEM file")
+	}
+	k.verify = pub
+	return k, nil
+}
+
+// Issue signs an access token with the current signing key
+func (s *TokenService) Issue(claims Claims, ttl time.Duration) (string, error) {
+	now := time.Now()
+	claims.RegisteredClaims = jwt.RegisteredClaims{
+		Issuer:    s.issuer,
+		Subject:   fmt.Sprint(claims.UserID),
+		Audience:  jwt.ClaimStrings{s.audience},
+		IssuedAt:  jwt.NewNumericDate(now),
+		NotBefore: jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+	}
+
+	token := jwt.NewWithClaims(s.signing.method, claims)
+	token.Header["kid"] = s.signing.id
+	return token.SignedString(s.signing.sign)
+}
+
+// Parse validates a token's signature, algorithm, issuer, audience and
+// lifetime and returns its claims
+func (s *TokenService) Parse(tokenString string) (*Claims, error) {
+	claims := &Claims{}
+	_, err := jwt.ParseWithClaims(tokenString, claims, s.keyFor,
+		jwt.WithValidMethods(s.methods),
+		jwt.WithIssuer(s.issuer),
+		jwt.WithAudience(s.audience),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(s.leeway),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if claims.NotBefore == nil {
+		return nil, errors.New("token has no nbf claim")
+	}
+	if claims.UserID == 0 || claims.SessionID == "" {
+		return nil, errors.New("token is missing user_id or sid")
+	}
+	return claims, nil
+}
+
+// keyFor picks the verification key named by the kid header; the key's own
+// algorithm must match the header so a key cannot be used with another one
+func (s *TokenService) keyFor(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	k, ok := s.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown key %q", kid)
+	}
+	if token.Method.Alg() != k.method.Alg() {
+		return nil, fmt.Errorf("key %q does not sign %s", kid, token.Method.Alg())
+	}
+	return k.verify, nil
+}
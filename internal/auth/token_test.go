@@ -0,0 +1,251 @@
+package auth
+
+import (
+	"EWSBE/internal/config"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	testIssuer   = "ewsbe"
+	testAudience = "ewsbe-api"
+	testSecret   = "0123456789abcdef0123456789abcdef"
+)
+
+// writePEM stores a DER block in a temp file and returns its path
+func writePEM(t *testing.T, name, blockType string, der []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// testKeys returns an HS256, an RS256 and an EdDSA key config plus the PEM
+// bytes of the RSA public key
+func testKeys(t *testing.T) ([]config.JWTKey, []byte) {
+	t.Helper()
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaPriv, err := x509.MarshalPKCS8PrivateKey(rsaKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaPub, err := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	edPriv, err := x509.MarshalPKCS8PrivateKey(edKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return []config.JWTKey{
+		{ID: "hs", Algorithm: "HS256", Value: testSecret},
+		{ID: "rs", Algorithm: "RS256", Value: writePEM(t, "rs.pem", "PRIVATE KEY", rsaPriv)},
+		{ID: "ed", Algorithm: "EdDSA", Value: writePEM(t, "ed.pem", "PRIVATE KEY", edPriv)},
+	}, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: rsaPub})
+}
+
+func newTestService(t *testing.T, signing string, keys []config.JWTKey) *TokenService {
+	t.Helper()
+	s, err := NewTokenService(config.JWTConfig{
+		Issuer:     testIssuer,
+		Audience:   testAudience,
+		Keys:       keys,
+		SigningKey: signing,
+		Leeway:     time.Second,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+// validClaims are the claims Issue would produce
+func validClaims() jwt.MapClaims {
+	now := time.Now()
+	return jwt.MapClaims{
+		"user_id": 1,
+		"sid":     "family-1",
+		"role":    "admin",
+		"iss":     testIssuer,
+		"aud":     testAudience,
+		"iat":     now.Unix(),
+		"nbf":     now.Unix(),
+		"exp":     now.Add(time.Minute).Unix(),
+	}
+}
+
+// forge signs claims with an arbitrary method, key and kid
+func forge(t *testing.T, method jwt.SigningMethod, kid string, key interface{}, edit func(jwt.MapClaims)) string {
+	t.Helper()
+	claims := validClaims()
+	if edit != nil {
+		edit(claims)
+	}
+	token := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+func TestParse(t *testing.T) {
+	keys, rsaPublicPEM := testKeys(t)
+	s := newTestService(t, "hs", keys)
+	secret := []byte(testSecret)
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr string
+	}{
+		{
+			name:  "valid HS256",
+			token: forge(t, jwt.SigningMethodHS256, "hs", secret, nil),
+		},
+		{
+			name:    "HS256 signed with the RSA public key against the RS256 kid",
+			token:   forge(t, jwt.SigningMethodHS256, "rs", rsaPublicPEM, nil),
+			wantErr: `key "rs" does not sign HS256`,
+		},
+		{
+			name:    "HS256 with the secret under the RS256 kid",
+			token:   forge(t, jwt.SigningMethodHS256, "rs", secret, nil),
+			wantErr: `key "rs" does not sign HS256`,
+		},
+		{
+			name:    "unknown kid",
+			token:   forge(t, jwt.SigningMethodHS256, "retired", secret, nil),
+			wantErr: `unknown key "retired"`,
+		},
+		{
+			name:    "missing kid",
+			token:   forge(t, jwt.SigningMethodHS256, "", secret, nil),
+			wantErr: `unknown key ""`,
+		},
+		{
+			name:    "unsigned",
+			token:   forge(t, jwt.SigningMethodNone, "hs", jwt.UnsafeAllowNoneSignatureType, nil),
+			wantErr: "signing method none is invalid",
+		},
+		{
+			name:    "wrong secret",
+			token:   forge(t, jwt.SigningMethodHS256, "hs", []byte("another secret"), nil),
+			wantErr: "signature is invalid",
+		},
+		{
+			name:    "missing nbf",
+			token:   forge(t, jwt.SigningMethodHS256, "hs", secret, func(c jwt.MapClaims) { delete(c, "nbf") }),
+			wantErr: "token has no nbf claim",
+		},
+		{
+			name:    "missing sid",
+			token:   forge(t, jwt.SigningMethodHS256, "hs", secret, func(c jwt.MapClaims) { delete(c, "sid") }),
+			wantErr: "token is missing user_id or sid",
+		},
+		{
+			name:    "missing user_id",
+			token:   forge(t, jwt.SigningMethodHS256, "hs", secret, func(c jwt.MapClaims) { delete(c, "user_id") }),
+			wantErr: "token is missing user_id or sid",
+		},
+		{
+			name:    "missing exp",
+			token:   forge(t, jwt.SigningMethodHS256, "hs", secret, func(c jwt.MapClaims) { delete(c, "exp") }),
+			wantErr: "token is missing required claim: exp claim is required",
+		},
+		{
+			name: "expired",
+			token: forge(t, jwt.SigningMethodHS256, "hs", secret, func(c jwt.MapClaims) {
+				c["exp"] = time.Now().Add(-time.Minute).Unix()
+			}),
+			wantErr: "token is expired",
+		},
+		{
+			name: "not yet valid",
+			token: forge(t, jwt.SigningMethodHS256, "hs", secret, func(c jwt.MapClaims) {
+				c["nbf"] = time.Now().Add(time.Minute).Unix()
+			}),
+			wantErr: "token is not valid yet",
+		},
+		{
+			name:    "wrong issuer",
+			token:   forge(t, jwt.SigningMethodHS256, "hs", secret, func(c jwt.MapClaims) { c["iss"] = "someone-else" }),
+			wantErr: "token has invalid issuer",
+		},
+		{
+			name:    "wrong audience",
+			token:   forge(t, jwt.SigningMethodHS256, "hs", secret, func(c jwt.MapClaims) { c["aud"] = "another-api" }),
+			wantErr: "token has invalid audience",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := s.Parse(tt.token)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Parse: %v", err)
+				}
+				if claims.UserID != 1 || claims.SessionID != "family-1" {
+					t.Errorf("claims = %+v", claims)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("err = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestIssueAndRotate(t *testing.T) {
+	keys, _ := testKeys(t)
+
+	for _, signing := range []string{"hs", "rs", "ed"} {
+		t.Run(signing, func(t *testing.T) {
+			s := newTestService(t, signing, keys)
+			token, err := s.Issue(Claims{UserID: 7, SessionID: "family-7", Role: "viewer"}, time.Minute)
+			if err != nil {
+				t.Fatal(err)
+			}
+			claims, err := s.Parse(token)
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			if claims.UserID != 7 || claims.SessionID != "family-7" || claims.Subject != "7" {
+				t.Errorf("claims = %+v", claims)
+			}
+
+			// after a rotation the old key still verifies
+			rotated := newTestService(t, "hs", keys)
+			if _, err := rotated.Parse(token); err != nil {
+				t.Errorf("token signed with %s rejected after rotation: %v", signing, err)
+			}
+		})
+	}
+}
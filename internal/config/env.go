@@ -0,0 +1,72 @@
+package config
+
+import (
+	"log"
+	"os"
+	"strconv"
+	"time"
+)
+
+// GetEnv returns the environment variable or def when unset
+func GetEnv(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
+
+// GetEnvInt parses an integer environment variable, falling back to def
+func GetEnvInt(key string, def int) int {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		log.Printf("Warning: invalid %s=%q, using %d", key, v, def)
+		return def
+	}
+	return n
+}
+
+// GetEnvFloat parses a float environment variable, falling back to def
+func GetEnvFloat(key string, def float64) float64 {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		log.Printf("Warning: invalid %s=%q, using %g", key, v, def)
+		return def
+	}
+	return f
+}
+
+// GetEnvBool parses a boolean environment variable, falling back to def
+func GetEnvBool(key string, def bool) bool {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		log.Printf("Warning: invalid %s=%q, using %t", key, v, def)
+		return def
+	}
+	return b
+}
+
+// GetEnvDuration parses a duration such as "30s" or "5m", falling back to def
+func GetEnvDuration(key string, def time.Duration) time.Duration {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		log.Printf("Warning: invalid %s=%q, using %s", key, v, def)
+		return def
+	}
+	return d
+}
@@ -0,0 +1,58 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// JWTKey is one signing or verification key. Value is the secret for HS256
+// and the path of a PEM file for RS256 and EdDSA: a private key can sign,
+// a public key only verifies tokens signed before a rotation.
+type JWTKey struct {
+	ID        string
+	Algorithm string
+	Value     string
+}
+
+type JWTConfig struct {
+	Issuer     string
+	Audience   string
+	Keys       []JWTKey
+	SigningKey string        // ID of the key new tokens are signed with
+	Leeway     time.Duration // clock skew allowed on exp and nbf
+}
+
+// LoadJWTConfig reads the JWT settings; a malformed JWT_KEYS entry is an
+// error rather than a key silently left out
+func LoadJWTConfig() (JWTConfig, error) {
+	c := JWTConfig{
+		Issuer:   GetEnv("JWT_ISSUER", "ewsbe"),
+		Audience: GetEnv("JWT_AUDIENCE", "ewsbe-api"),
+		Leeway:   GetEnvDuration("JWT_LEEWAY", 30*time.Second),
+	}
+
+	// "kid:ALG:value,..." e.g. "2025a:HS256:secret,2025b:RS256:/keys/jwt.pem"
+	for i, entry := range strings.Split(GetEnv("JWT_KEYS", ""), ",") {
+		if entry = strings.TrimSpace(entry); entry == "" {
+			continue
+		}
+		// the entry itself is not echoed, it may hold a secret
+		parts := strings.SplitN(entry, ":", 3)
+		if len(parts) != 3 || parts[0] == "" || parts[1] == "" || parts[2] == "" {
+			return c, fmt.Errorf("JWT_KEYS entry %d: expected kid:ALG:value", i+1)
+		}
+		c.Keys = append(c.Keys, JWTKey{ID: parts[0], Algorithm: parts[1], Value: parts[2]})
+	}
+
+	// the single shared secret used before key rotation was supported
+	if secret := GetEnv("JWT_SECRET", ""); secret != "" {
+		c.Keys = append(c.Keys, JWTKey{ID: "default", Algorithm: "HS256", Value: secret})
+	}
+
+	c.SigningKey = GetEnv("JWT_SIGNING_KEY", "")
+	if c.SigningKey == "" && len(c.Keys) > 0 {
+		c.SigningKey = c.Keys[0].ID
+	}
+	return c, nil
+}
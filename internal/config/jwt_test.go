@@ -0,0 +1,45 @@
+package config
+
+import "testing"
+
+func TestLoadJWTConfig(t *testing.T) {
+	t.Setenv("JWT_SECRET", "legacy")
+	t.Setenv("JWT_SIGNING_KEY", "")
+	t.Setenv("JWT_KEYS", "2025a:HS256:secret:with:colons, 2025b:RS256:/keys/jwt.pem")
+
+	c, err := LoadJWTConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []JWTKey{
+		{ID: "2025a", Algorithm: "HS256", Value: "secret:with:colons"},
+		{ID: "2025b", Algorithm: "RS256", Value: "/keys/jwt.pem"},
+		{ID: "default", Algorithm: "HS256", Value: "legacy"},
+	}
+	if len(c.Keys) != len(want) {
+		t.Fatalf("keys = %+v", c.Keys)
+	}
+	for i := range want {
+		if c.Keys[i] != want[i] {
+			t.Errorf("key %d = %+v, want %+v", i, c.Keys[i], want[i])
+		}
+	}
+	if c.SigningKey != "2025a" {
+		t.Errorf("signing key = %q, want the first key", c.SigningKey)
+	}
+}
+
+func TestLoadJWTConfigRejectsMalformedKeys(t *testing.T) {
+	for _, keys := range []string{
+		"2025a:HS256",
+		":HS256:secret",
+		"2025a::secret",
+		"2025a:HS256:",
+		"2025a:HS256:secret,oops",
+	} {
+		t.Setenv("JWT_KEYS", keys)
+		if _, err := LoadJWTConfig(); err == nil {
+			t.Errorf("JWT_KEYS=%q accepted", keys)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package config
+
+import (
+	"log"
+	"strings"
+	"time"
+)
+
+type MQTTConfig struct {
+	Broker   string
+	ClientID string
+	Topics   []string
+	QoS      byte
+
+	// broker authentication
+	Username string
+	Password string
+
+	// TLS is used for ssl://, tls://, mqtts:// and wss:// brokers. The
+	// server certificate is verified against CAFile (system roots when
+	// empty) and ServerName (host of Broker when empty); CertFile and
+	// KeyFile enable client certificate authentication.
+	CAFile     string
+	CertFile   string
+	KeyFile    string
+	ServerName string
+
+	// persistent session: the broker keeps subscriptions and queues QoS 1/2
+	// messages while we are away; needs a stable client ID
+	CleanSession bool
+
+	KeepAlive            time.Duration
+	ConnectTimeout       time.Duration
+	InitialRetryDelay    time.Duration // first wait after a failed connect, doubled per attempt
+	MaxReconnectInterval time.Duration // cap for the reconnect backoff
+
+	DefaultStation    string
+	TimestampStrategy string
+	AutoRegister      bool // create stations for unknown topic codes
+
+	// downlink: commands go to CommandTopic and stations answer on AckTopic;
+	// "{station}" stands for the station code
+	CommandTopic string
+	AckTopic     string
+
+	// payload decoder selection, in order: topic pattern -> decoder, then
+	// payload version field -> decoder, otherwise detected from the payload
+	DecoderTopics   []DecoderRoute
+	DecoderVersions []DecoderRoute
+}
+
+type DecoderRoute struct {
+	Match   string // topic pattern or version value
+	Decoder string
+}
+
+func LoadMQTTConfig() MQTTConfig {
+	c := MQTTConfig{
+		Broker:               GetEnv("MQTT_BROKER", ""),
+		ClientID:             GetEnv("MQTT_CLIENT_ID", "ewsbe_client"),
+		Username:             GetEnv("MQTT_USERNAME", ""),
+		Password:             GetEnv("MQTT_PASSWORD", ""),
+		CAFile:               GetEnv("MQTT_TLS_CA_FILE", ""),
+		CertFile:             GetEnv("MQTT_TLS_CERT_FILE", ""),
+		KeyFile:              GetEnv("MQTT_TLS_KEY_FILE", ""),
+		ServerName:           GetEnv("MQTT_TLS_SERVER_NAME", ""),
+		CleanSession:         GetEnvBool("MQTT_CLEAN_SESSION", false),
+		KeepAlive:            GetEnvDuration("MQTT_KEEP_ALIVE", 30*time.Second),
+		ConnectTimeout:       GetEnvDuration("MQTT_CONNECT_TIMEOUT", 30*time.Second),
+		InitialRetryDelay:    GetEnvDuration("MQTT_RETRY_DELAY", time.Second),
+		MaxReconnectInterval: GetEnvDuration("MQTT_MAX_RECONNECT_INTERVAL", 2*time.Minute),
+		DefaultStation:       GetEnv("MQTT_DEFAULT_STATION", "default"),
+		TimestampStrategy:    GetEnv("MQTT_TIMESTAMP_STRATEGY", "auto"),
+		AutoRegister:         GetEnvBool("MQTT_AUTO_REGISTER_STATIONS", false),
+		CommandTopic:         GetEnv("MQTT_COMMAND_TOPIC", "ewsbe/{station}/cmd"),
+		AckTopic:             GetEnv("MQTT_ACK_TOPIC", "ewsbe/{station}/ack"),
+	}
+
+	// comma-separated, e.g. "sensors/ewsbe,sensors/ewsbe/+"
+	for _, t := range strings.Split(GetEnv("MQTT_TOPIC", ""), ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			c.Topics = append(c.Topics, t)
+		}
+	}
+
+	// "pattern=decoder,..." e.g. "sensors/partner/+=cbor,sensors/sdlog/#=csv"
+	c.DecoderTopics = parseDecoderRoutes("MQTT_DECODER_TOPICS")
+	// "version=decoder,..." e.g. "2=json"
+	c.DecoderVersions = parseDecoderRoutes("MQTT_DECODER_VERSIONS")
+
+	qos := GetEnvInt("MQTT_QOS", 1)
+	if qos < 0 || qos > 2 {
+		log.Printf("Warning: invalid MQTT_QOS=%d, using 1", qos)
+		qos = 1
+	}
+	c.QoS = byte(qos)
+
+	return c
+}
+
+func parseDecoderRoutes(key string) []DecoderRoute {
+	var routes []DecoderRoute
+	for _, pair := range strings.Split(GetEnv(key, ""), ",") {
+		if pair = strings.TrimSpace(pair); pair == "" {
+			continue
+		}
+		match, decoder, ok := strings.Cut(pair, "=")
+		if !ok {
+			log.Printf("Warning: ignoring %s entry %q, expected match=decoder", key, pair)
+			continue
+		}
+		routes = append(routes, DecoderRoute{Match: strings.TrimSpace(match), Decoder: strings.TrimSpace(decoder)})
+	}
+	return routes
+}
@@ -11,7 +11,7 @@ import (
 func InitDB(cfg config.Config) (*gorm.DB, error) {
 	switch cfg.DBDriver {
     case "postgres":
-        return gorm.Open(postgres.Open(cfg.DSN), &gorm.Config{})
+        return gorm.Open(postgres.Open(cfg.DSN), &gorm.Config{TranslateError: true})
     default:
         return nil, errors.New("unsupported db driver: " + cfg.DBDriver)
     }
@@ -0,0 +1,184 @@
+package http
+
+import (
+	"EWSBE/internal/entity"
+	"EWSBE/internal/usecase"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AlertHandler struct {
+	alertUc   *usecase.AlertUsecase
+	stationUc *usecase.StationUsecase
+}
+
+func NewAlertHandler(alertUc *usecase.AlertUsecase, stationUc *usecase.StationUsecase) *AlertHandler {
+	return &AlertHandler{alertUc: alertUc, stationUc: stationUc}
+}
+
+func (h *AlertHandler) GetRules(c *gin.Context) {
+	c.JSON(http.StatusOK, h.alertUc.GetRules())
+}
+
+func (h *AlertHandler) CreateRule(c *gin.Context) {
+	var rule entity.AlertRule
+	if err := c.ShouldBindJSON(&rule); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	rule.ID = 0
+	if err := h.alertUc.CreateRule(&rule); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, rule)
+}
+
+func (h *AlertHandler) UpdateRule(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	var input entity.AlertRuleUpdate
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	rule, err := h.alertUc.UpdateRule(uint(id), &input)
+	if err != nil {
+		if err.Error() == "rule not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, rule)
+}
+
+func (h *AlertHandler) GetAlerts(c *gin.Context) {
+	filter := entity.AlertFilter{
+		Status:   c.Query("status"),
+		Severity: c.Query("severity"),
+	}
+
+	if code := c.Query("station"); code != "" {
+		station, err := h.stationUc.GetStationByCode(code)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "station not found"})
+			return
+		}
+		filter.StationID = station.ID
+	}
+
+	if ruleStr := c.Query("rule"); ruleStr != "" {
+		ruleID, err := strconv.ParseUint(ruleStr, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid rule id"})
+			return
+		}
+		filter.RuleID = uint(ruleID)
+	}
+
+	for param, target := range map[string]**time.Time{"from": &filter.From, "to": &filter.To} {
+		if v := c.Query(param); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + param + " time format (use RFC3339)"})
+				return
+			}
+			*target = &t
+		}
+	}
+
+	filter.Limit, _ = strconv.Atoi(c.DefaultQuery("limit", "100"))
+	filter.Offset, _ = strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if filter.Offset < 0 {
+		filter.Offset = 0
+	}
+
+	alerts, total, err := h.alertUc.GetAlerts(filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"total": total,
+		"count": len(alerts),
+		"data":  alerts,
+	})
+}
+
+func (h *AlertHandler) GetAlertByID(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	alert, err := h.alertUc.GetAlertByID(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, alert)
+}
+
+func (h *AlertHandler) AcknowledgeAlert(c *gin.Context) {
+	h.transition(c, h.alertUc.Acknowledge)
+}
+
+func (h *AlertHandler) ResolveAlert(c *gin.Context) {
+	h.transition(c, h.alertUc.Resolve)
+}
+
+// transition runs an operator action that takes the alert ID, the acting user and a comment
+func (h *AlertHandler) transition(c *gin.Context, action func(id, userID uint, comment string) (*entity.Alert, error)) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	var req struct {
+		Comment string `json:"comment"`
+	}
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	alert, err := action(uint(id), userID.(uint), req.Comment)
+	if err != nil {
+		switch err.Error() {
+		case "alert not found":
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case "alert already resolved", "alert already acknowledged":
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, alert)
+}
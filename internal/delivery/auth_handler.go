@@ -1,27 +1,32 @@
 package http
 
 import (
+	"EWSBE/internal/auth"
+	"EWSBE/internal/entity"
 	"EWSBE/internal/usecase"
+	"errors"
+	"math"
 	"net/http"
-	"os"
-	"time"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt/v5"
 )
 
 type AuthHandler struct {
-	authUc *usecase.AuthUsecase
+	authUc    *usecase.AuthUsecase
+	sessionUc *usecase.SessionUsecase
+	tokens    *auth.TokenService
 }
 
-func NewAuthHandler(authUc *usecase.AuthUsecase) *AuthHandler {
-	return &AuthHandler{authUc: authUc}
+func NewAuthHandler(authUc *usecase.AuthUsecase, sessionUc *usecase.SessionUsecase, tokens *auth.TokenService) *AuthHandler {
+	return &AuthHandler{authUc: authUc, sessionUc: sessionUc, tokens: tokens}
 }
 
 func (h *AuthHandler) Register(c *gin.Context) {
 	var req struct {
-		Username string `json:"username" binding:"required"`
-		Password string `json:"password" binding:"required"`
+		Username   string `json:"username" binding:"required"`
+		Password   string `json:"password" binding:"required"`
+		InviteCode string `json:"invite_code"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -29,14 +34,26 @@ func (h *AuthHandler) Register(c *gin.Context) {
 		return
 	}
 
-	if err := h.authUc.Register(req.Username, req.Password); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := h.authUc.Register(req.Username, req.Password, req.InviteCode); err != nil {
+		switch err.Error() {
+		case "registration is disabled", "an invite code is required", "invalid or expired invite code":
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		case "username already taken":
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		}
 		return
 	}
 
 	c.JSON(http.StatusCreated, gin.H{"message": "user registered successfully"})
 }
 
+// RegistrationMode tells clients whether to offer sign-up and ask for an invite
+func (h *AuthHandler) RegistrationMode(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"mode": h.authUc.RegistrationMode()})
+}
+
 func (h *AuthHandler) Login(c *gin.Context) {
 	var req struct {
 		Username string `json:"username" binding:"required"`
@@ -48,27 +65,91 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
-	user, err := h.authUc.Login(req.Username, req.Password)
+	user, err := h.authUc.Login(req.Username, req.Password, c.ClientIP(), c.Request.UserAgent())
 	if err != nil {
+		var blocked *usecase.LoginBlockedError
+		if errors.As(err, &blocked) {
+			retry := int(math.Ceil(blocked.RetryAfter.Seconds()))
+			c.Header("Retry-After", strconv.Itoa(retry))
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error(), "retry_after": retry})
+			return
+		}
+		if err.Error() == "account disabled" {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
 		return
 	}
 
-	token, err := h.generateToken(user.ID)
+	session, refresh, err := h.sessionUc.Start(user, c.Request.UserAgent(), c.ClientIP())
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to generate token"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to start session"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"token": token, "user": user})
+	h.respondTokens(c, user, session, refresh)
 }
 
-func (h *AuthHandler) generateToken(userID uint) (string, error) {
-	claims := jwt.MapClaims{
-		"user_id": userID,
-		"exp":     time.Now().Add(time.Hour * 24).Unix(),
+// Refresh trades a refresh token for a new access token and the next refresh
+// token; each refresh token works once
+func (h *AuthHandler) Refresh(c *gin.Context) {
+	var req struct {
+		RefreshToken string `json:"refresh_token" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(os.Getenv("JWT_SECRET")))
+	user, session, refresh, err := h.sessionUc.Refresh(req.RefreshToken)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.respondTokens(c, user, session, refresh)
+}
+
+// Logout ends the session of a refresh token, including its access tokens
+func (h *AuthHandler) Logout(c *gin.Context) {
+	var req struct {
+		RefreshToken string `json:"refresh_token" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.sessionUc.Logout(req.RefreshToken); err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "logged out"})
+}
+
+func (h *AuthHandler) respondTokens(c *gin.Context, user *entity.User, session *entity.Session, refresh string) {
+	token, err := h.generateToken(user, session.FamilyID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to generate token"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"token":         token,
+		"expires_in":    int(h.sessionUc.AccessTTL().Seconds()),
+		"refresh_token": refresh,
+		"user":          user,
+		"permissions":   user.Permissions(),
+	})
+}
+
+func (h *AuthHandler) generateToken(user *entity.User, sessionID string) (string, error) {
+	return h.tokens.Issue(auth.Claims{
+		UserID:      user.ID,
+		SessionID:   sessionID,
+		Role:        user.Role,
+		Permissions: user.Permissions(),
+	}, h.sessionUc.AccessTTL())
 }
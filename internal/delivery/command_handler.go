@@ -0,0 +1,100 @@
+package http
+
+import (
+	"EWSBE/internal/entity"
+	"EWSBE/internal/usecase"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CommandHandler struct {
+	commandUc *usecase.CommandUsecase
+	stationUc *usecase.StationUsecase
+}
+
+func NewCommandHandler(commandUc *usecase.CommandUsecase, stationUc *usecase.StationUsecase) *CommandHandler {
+	return &CommandHandler{commandUc: commandUc, stationUc: stationUc}
+}
+
+// SendCommand publishes a command to the station; the response holds the
+// pending command, poll it or listen for "station:command" on the WebSocket
+func (h *CommandHandler) SendCommand(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var req usecase.CommandRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	cmd, err := h.commandUc.Send(c.Param("code"), userID.(uint), req)
+	if err != nil {
+		switch err.Error() {
+		case "station not found":
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case "mqtt not connected":
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	if cmd.Status == entity.CommandFailed {
+		c.JSON(http.StatusBadGateway, cmd)
+		return
+	}
+	c.JSON(http.StatusAccepted, cmd)
+}
+
+func (h *CommandHandler) GetStationCommands(c *gin.Context) {
+	station, err := h.stationUc.GetStationByCode(c.Param("code"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "station not found"})
+		return
+	}
+
+	filter := entity.CommandFilter{
+		StationID: station.ID,
+		Status:    c.Query("status"),
+	}
+	filter.Limit, _ = strconv.Atoi(c.DefaultQuery("limit", "100"))
+	filter.Offset, _ = strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if filter.Offset < 0 {
+		filter.Offset = 0
+	}
+
+	cmds, total, err := h.commandUc.GetCommands(filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"total": total,
+		"count": len(cmds),
+		"data":  cmds,
+	})
+}
+
+func (h *CommandHandler) GetCommandByID(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	cmd, err := h.commandUc.GetCommandByID(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, cmd)
+}
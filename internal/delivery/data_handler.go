@@ -4,6 +4,9 @@ import (
 	"EWSBE/internal/entity"
 	"EWSBE/internal/usecase"
 	ws "EWSBE/internal/websocket"
+	"encoding/json"
+	"errors"
+	"log"
 	"net/http"
 	"strconv"
 	"time"
@@ -13,8 +16,14 @@ import (
 )
 
 type DataHandler struct {
-	dataUc *usecase.DataUsecase
-	hub    *ws.Hub
+	dataUc    *usecase.DataUsecase
+	stationUc *usecase.StationUsecase
+	alertUc   *usecase.AlertUsecase
+	monitor   *usecase.StationMonitor
+	hub       *ws.Hub
+
+	// extra sections of the health report, e.g. MQTT ingestion
+	healthChecks map[string]func() interface{}
 }
 
 var upgrader = websocket.Upgrader{
@@ -26,16 +35,40 @@ var upgrader = websocket.Upgrader{
 	},
 }
 
-func NewDataHandler(dataUc *usecase.DataUsecase, hub *ws.Hub) *DataHandler {
-	return &DataHandler{dataUc: dataUc, hub: hub}
+func NewDataHandler(dataUc *usecase.DataUsecase, stationUc *usecase.StationUsecase, alertUc *usecase.AlertUsecase, monitor *usecase.StationMonitor, hub *ws.Hub) *DataHandler {
+	return &DataHandler{dataUc: dataUc, stationUc: stationUc, alertUc: alertUc, monitor: monitor, hub: hub, healthChecks: make(map[string]func() interface{})}
+}
+
+// stationFilter resolves the optional ?station=<code> query parameter.
+// It returns 0 when no filter is given and writes a 404 for unknown codes.
+func (h *DataHandler) stationFilter(c *gin.Context) (uint, bool) {
+	code := c.Query("station")
+	if code == "" {
+		return 0, true
+	}
+
+	station, err := h.stationUc.GetStationByCode(code)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "station not found"})
+		return 0, false
+	}
+	return station.ID, true
 }
 
 func (h *DataHandler) HealthCheck(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{
+	health := gin.H{
 		"status":    "ok",
 		"wsClients": h.hub.ClientCount(),
 		"timestamp": time.Now().Format(time.RFC3339),
-	})
+	}
+	if h.monitor != nil {
+		health["stations"] = h.monitor.Counts()
+	}
+	for name, check := range h.healthChecks {
+		health[name] = check()
+	}
+
+	c.JSON(http.StatusOK, health)
 }
 
 // HandleWebSocket upgrades HTTP connection to WebSocket
@@ -49,39 +82,197 @@ func (h *DataHandler) HandleWebSocket(c *gin.Context) {
 	client := ws.NewClient(h.hub, conn)
 	h.hub.Register(client)
 
+	// bring a newly connected dashboard up to date with alerts still in force
+	if h.alertUc != nil {
+		if err := client.SendEvent("alert:active", h.alertUc.GetActiveAlerts()); err != nil {
+			log.Printf("websocket: failed to send active alerts: %v", err)
+		}
+	}
+	if h.monitor != nil {
+		if statuses, err := h.monitor.Statuses(); err == nil {
+			if err := client.SendEvent("station:statuses", statuses); err != nil {
+				log.Printf("websocket: failed to send station statuses: %v", err)
+			}
+		}
+	}
+
 	// start client read/write pumps
 	go client.WritePump()
 	go client.ReadPump()
 }
 
+// CreateData stores a reading posted by a station with its API key. The body
+// is a SensorData object, or the firmware's MQTT payload for stations that
+// only have a GSM link.
 func (h *DataHandler) CreateData(c *gin.Context) {
-	var d entity.SensorData
-	if err := c.ShouldBindJSON(&d); err != nil {
+	station, ok := c.MustGet("deviceStation").(*entity.Station)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	if code := c.Query("station"); code != "" && code != station.Code {
+		c.JSON(http.StatusForbidden, gin.H{"error": "api key does not belong to station " + code})
+		return
+	}
+
+	body, err := c.GetRawData()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	d, err := parseReading(body, time.Now())
+	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	d.StationID = &station.ID
 
-	if err := h.dataUc.Create(&d); err != nil {
+	if err := h.dataUc.Create(d); err != nil {
+		// a retried request succeeds without storing the reading twice
+		if errors.Is(err, usecase.ErrDuplicateReading) {
+			c.JSON(http.StatusOK, gin.H{"message": "duplicate reading ignored", "duplicate": true})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
 	// broadcast to WebSocket clients
 	if h.hub != nil {
-		h.hub.BroadcastSensorData(&d)
+		h.hub.BroadcastSensorData(d)
 	}
 
 	c.JSON(http.StatusCreated, d)
 }
 
+// reading body with SensorData field names; fields the backend derives, such
+// as quality flags, time source and water level, cannot be set by the client
+type readingRequest struct {
+	Timestamp     time.Time              `json:"timestamp"`
+	Temperature   float64                `json:"temperature"`
+	Humidity      float64                `json:"humidity"`
+	Pressure      float64                `json:"pressure"`
+	Altitude      float64                `json:"altitude"`
+	Co2           float64                `json:"co2"`
+	Distance      float64                `json:"distance"`
+	WindSpeed     float64                `json:"windSpeed"`
+	WindDirection float64                `json:"windDirection"`
+	Rainfall      float64                `json:"rainfall"`
+	Voltage       float64                `json:"voltage"`
+	BusVoltage    float64                `json:"busVoltage"`
+	Current       float64                `json:"current"`
+	Extra         map[string]interface{} `json:"extra"`
+	MessageID     string                 `json:"messageId"`
+}
+
+// parseReading decodes a readingRequest, or an MQTTSensorPayload when the
+// body carries firmware field names
+func parseReading(body []byte, receivedAt time.Time) (*entity.SensorData, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return nil, err
+	}
+
+	for _, key := range []string{"waktu", "suhu", "lembap", "tekanan"} {
+		if _, ok := fields[key]; ok {
+			reading, err := (usecase.JSONDecoder{}).Decode(body)
+			if err != nil {
+				return nil, err
+			}
+			return reading.ToSensorData(entity.TimestampAuto, receivedAt), nil
+		}
+	}
+
+	var req readingRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		return nil, err
+	}
+	return &entity.SensorData{
+		Timestamp:     req.Timestamp,
+		ReceivedAt:    receivedAt,
+		Temperature:   req.Temperature,
+		Humidity:      req.Humidity,
+		Pressure:      req.Pressure,
+		Altitude:      req.Altitude,
+		Co2:           req.Co2,
+		Distance:      req.Distance,
+		WindSpeed:     req.WindSpeed,
+		WindDirection: req.WindDirection,
+		Rainfall:      req.Rainfall,
+		Voltage:       req.Voltage,
+		BusVoltage:    req.BusVoltage,
+		Current:       req.Current,
+		Extra:         req.Extra,
+		MessageID:     req.MessageID,
+	}, nil
+}
+
+// ImportCSV bulk-loads an SD card log for a station (multipart form:
+// station, file, optional mapping JSON, timestampStrategy and bootTime)
+func (h *DataHandler) ImportCSV(c *gin.Context) {
+	station, err := h.stationUc.GetStationByCode(c.PostForm("station"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "station not found"})
+		return
+	}
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "file is required"})
+		return
+	}
+
+	opts := usecase.ImportOptions{
+		StationID:         station.ID,
+		TimestampStrategy: c.DefaultPostForm("timestampStrategy", entity.TimestampAuto),
+	}
+
+	if mapping := c.PostForm("mapping"); mapping != "" {
+		if err := json.Unmarshal([]byte(mapping), &opts.Mapping); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid mapping JSON"})
+			return
+		}
+	}
+
+	if bootStr := c.PostForm("bootTime"); bootStr != "" {
+		bootTime, err := time.Parse(time.RFC3339, bootStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid bootTime format (use RFC3339)"})
+			return
+		}
+		opts.BootTime = &bootTime
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "failed to read file"})
+		return
+	}
+	defer file.Close()
+
+	result, err := h.dataUc.ImportCSV(file, opts)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
 func (h *DataHandler) GetAllData(c *gin.Context) {
+	stationID, ok := h.stationFilter(c)
+	if !ok {
+		return
+	}
+
 	limitStr := c.DefaultQuery("limit", "100")
 	limit, err := strconv.Atoi(limitStr)
 	if err != nil || limit <= 0 {
 		limit = 100
 	}
 
-	data, err := h.dataUc.GetDataByLimit(limit)
+	data, err := h.dataUc.GetDataByLimit(stationID, limit)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -91,7 +282,12 @@ func (h *DataHandler) GetAllData(c *gin.Context) {
 }
 
 func (h *DataHandler) GetLatestData(c *gin.Context) {
-	data, err := h.dataUc.GetLatestData()
+	stationID, ok := h.stationFilter(c)
+	if !ok {
+		return
+	}
+
+	data, err := h.dataUc.GetLatestData(stationID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -106,6 +302,11 @@ func (h *DataHandler) GetLatestData(c *gin.Context) {
 }
 
 func (h *DataHandler) GetDataHistory(c *gin.Context) {
+	stationID, ok := h.stationFilter(c)
+	if !ok {
+		return
+	}
+
 	startStr := c.Query("start")
 	endStr := c.Query("end")
 	interval := c.DefaultQuery("interval", "raw")
@@ -134,7 +335,8 @@ func (h *DataHandler) GetDataHistory(c *gin.Context) {
 	}
 
 	if interval != "raw" && interval != "" {
-		data, err := h.dataUc.GetAggregatedData(interval, start, end)
+		includeBad := c.Query("includeBad") == "true"
+		data, err := h.dataUc.GetAggregatedData(stationID, interval, start, end, includeBad)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
@@ -149,7 +351,7 @@ func (h *DataHandler) GetDataHistory(c *gin.Context) {
 		return
 	}
 
-	data, err := h.dataUc.GetDataByTimeRange(start, end)
+	data, err := h.dataUc.GetDataByTimeRange(stationID, start, end)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -164,7 +366,13 @@ func (h *DataHandler) GetDataHistory(c *gin.Context) {
 }
 
 func (h *DataHandler) GetDataInsights(c *gin.Context) {
-	insights, err := h.dataUc.GetDataInsights()
+	stationID, ok := h.stationFilter(c)
+	if !ok {
+		return
+	}
+
+	includeBad := c.Query("includeBad") == "true"
+	insights, err := h.dataUc.GetDataInsights(stationID, includeBad)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -172,3 +380,118 @@ func (h *DataHandler) GetDataInsights(c *gin.Context) {
 
 	c.JSON(http.StatusOK, insights)
 }
+
+// GetClockDrift reports receive time minus reading time per time source,
+// used to audit device clocks
+func (h *DataHandler) GetClockDrift(c *gin.Context) {
+	stationID, ok := h.stationFilter(c)
+	if !ok {
+		return
+	}
+
+	hours, err := strconv.Atoi(c.DefaultQuery("hours", "24"))
+	if err != nil || hours <= 0 {
+		hours = 24
+	}
+
+	drift, err := h.dataUc.GetClockDrift(stationID, time.Now().Add(-time.Duration(hours)*time.Hour))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"hours": hours,
+		"data":  drift,
+	})
+}
+
+// GetRainfall returns rolling accumulations, daily totals and rain events for a station
+func (h *DataHandler) GetRainfall(c *gin.Context) {
+	if c.Query("station") == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "station is required"})
+		return
+	}
+	stationID, ok := h.stationFilter(c)
+	if !ok {
+		return
+	}
+
+	days, err := strconv.Atoi(c.DefaultQuery("days", "7"))
+	if err != nil || days <= 0 || days > 90 {
+		days = 7
+	}
+
+	gapMinutes, err := strconv.Atoi(c.DefaultQuery("eventGap", "60"))
+	if err != nil || gapMinutes <= 0 {
+		gapMinutes = 60
+	}
+
+	summary, err := h.dataUc.GetRainfallSummary(stationID, days, time.Duration(gapMinutes)*time.Minute)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, summary)
+}
+
+// GetPower returns battery state, voltage trend, daily energy and power alerts for a station
+func (h *DataHandler) GetPower(c *gin.Context) {
+	if c.Query("station") == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "station is required"})
+		return
+	}
+	stationID, ok := h.stationFilter(c)
+	if !ok {
+		return
+	}
+
+	days, err := strconv.Atoi(c.DefaultQuery("days", "7"))
+	if err != nil || days <= 0 || days > 90 {
+		days = 7
+	}
+
+	summary, err := h.dataUc.GetPowerSummary(stationID, days)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, summary)
+}
+
+// GetWindRose returns wind frequency by 16 compass sectors and speed bins
+func (h *DataHandler) GetWindRose(c *gin.Context) {
+	stationID, ok := h.stationFilter(c)
+	if !ok {
+		return
+	}
+
+	start := time.Now().Add(-7 * 24 * time.Hour)
+	end := time.Now()
+	var err error
+
+	if startStr := c.Query("start"); startStr != "" {
+		start, err = time.Parse(time.RFC3339, startStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid start time format (use RFC3339)"})
+			return
+		}
+	}
+	if endStr := c.Query("end"); endStr != "" {
+		end, err = time.Parse(time.RFC3339, endStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid end time format (use RFC3339)"})
+			return
+		}
+	}
+
+	rose, err := h.dataUc.GetWindRose(stationID, start, end)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, rose)
+}
@@ -0,0 +1,155 @@
+package http
+
+import (
+	"EWSBE/internal/entity"
+	"EWSBE/internal/usecase"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type DeadLetterHandler struct {
+	deadLetterUc *usecase.DeadLetterUsecase
+}
+
+func NewDeadLetterHandler(deadLetterUc *usecase.DeadLetterUsecase) *DeadLetterHandler {
+	return &DeadLetterHandler{deadLetterUc: deadLetterUc}
+}
+
+func (h *DeadLetterHandler) GetDeadLetters(c *gin.Context) {
+	filter := entity.DeadLetterFilter{
+		Topic:  c.Query("topic"),
+		Stage:  c.Query("stage"),
+		Status: c.Query("status"),
+	}
+
+	for param, target := range map[string]**time.Time{"from": &filter.From, "to": &filter.To} {
+		if v := c.Query(param); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + param + " time format (use RFC3339)"})
+				return
+			}
+			*target = &t
+		}
+	}
+
+	filter.Limit, _ = strconv.Atoi(c.DefaultQuery("limit", "100"))
+	filter.Offset, _ = strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if filter.Offset < 0 {
+		filter.Offset = 0
+	}
+
+	letters, total, err := h.deadLetterUc.GetDeadLetters(filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"total": total,
+		"count": len(letters),
+		"data":  letters,
+	})
+}
+
+func (h *DeadLetterHandler) GetDeadLetterByID(c *gin.Context) {
+	id, ok := deadLetterID(c)
+	if !ok {
+		return
+	}
+
+	dl, err := h.deadLetterUc.GetDeadLetterByID(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, dl)
+}
+
+func (h *DeadLetterHandler) DeleteDeadLetter(c *gin.Context) {
+	id, ok := deadLetterID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.deadLetterUc.DeleteDeadLetter(id); err != nil {
+		if err.Error() == "dead letter not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "dead letter deleted successfully"})
+}
+
+// ReplayDeadLetters replays the dead letters listed in the body, in order
+func (h *DeadLetterHandler) ReplayDeadLetters(c *gin.Context) {
+	var req struct {
+		IDs []uint `json:"ids" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.replay(c, req.IDs)
+}
+
+func (h *DeadLetterHandler) ReplayDeadLetter(c *gin.Context) {
+	id, ok := deadLetterID(c)
+	if !ok {
+		return
+	}
+
+	h.replay(c, []uint{id})
+}
+
+func (h *DeadLetterHandler) replay(c *gin.Context, ids []uint) {
+	results, err := h.deadLetterUc.Replay(ids)
+	if err != nil {
+		switch err.Error() {
+		case "ingestion pipeline not available":
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		default:
+			if results == nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error(), "data": results})
+		}
+		return
+	}
+
+	replayed, duplicates := 0, 0
+	for _, r := range results {
+		switch {
+		case r.Error != "":
+		case r.Status == entity.DeadLetterReplayed:
+			replayed++
+		case r.Status == entity.DeadLetterDuplicate:
+			duplicates++
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"replayed":   replayed,
+		"duplicates": duplicates,
+		"count":      len(results),
+		"data":       results,
+	})
+}
+
+func deadLetterID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return 0, false
+	}
+	return uint(id), true
+}
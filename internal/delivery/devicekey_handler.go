@@ -0,0 +1,109 @@
+package http
+
+import (
+	"EWSBE/internal/usecase"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type DeviceKeyHandler struct {
+	deviceKeyUc *usecase.DeviceKeyUsecase
+}
+
+func NewDeviceKeyHandler(deviceKeyUc *usecase.DeviceKeyUsecase) *DeviceKeyHandler {
+	return &DeviceKeyHandler{deviceKeyUc: deviceKeyUc}
+}
+
+func deviceKeyError(c *gin.Context, err error) {
+	switch err.Error() {
+	case "station not found", "device key not found":
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	}
+}
+
+func (h *DeviceKeyHandler) GetKeys(c *gin.Context) {
+	keys, err := h.deviceKeyUc.GetKeys(c.Param("code"))
+	if err != nil {
+		deviceKeyError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, keys)
+}
+
+func (h *DeviceKeyHandler) CreateKey(c *gin.Context) {
+	var req struct {
+		Name string `json:"name"`
+	}
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	userID, _ := c.Get("userID")
+	uid, _ := userID.(uint)
+
+	key, plain, err := h.deviceKeyUc.Create(c.Param("code"), req.Name, uid)
+	if err != nil {
+		deviceKeyError(c, err)
+		return
+	}
+
+	// the key is only ever returned here
+	c.JSON(http.StatusCreated, gin.H{"key": key, "api_key": plain})
+}
+
+// RotateKey replaces a key; ?grace=<seconds> keeps the old one working for a while
+func (h *DeviceKeyHandler) RotateKey(c *gin.Context) {
+	id, ok := deviceKeyID(c)
+	if !ok {
+		return
+	}
+	grace, err := strconv.Atoi(c.DefaultQuery("grace", "0"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid grace"})
+		return
+	}
+
+	userID, _ := c.Get("userID")
+	uid, _ := userID.(uint)
+
+	key, plain, err := h.deviceKeyUc.Rotate(c.Param("code"), id, time.Duration(grace)*time.Second, uid)
+	if err != nil {
+		deviceKeyError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"key": key, "api_key": plain})
+}
+
+func (h *DeviceKeyHandler) RevokeKey(c *gin.Context) {
+	id, ok := deviceKeyID(c)
+	if !ok {
+		return
+	}
+
+	key, err := h.deviceKeyUc.Revoke(c.Param("code"), id)
+	if err != nil {
+		deviceKeyError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, key)
+}
+
+func deviceKeyID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return 0, false
+	}
+	return uint(id), true
+}
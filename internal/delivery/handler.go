@@ -1,42 +1,86 @@
 package http
 
 import (
+	"EWSBE/internal/auth"
+	"EWSBE/internal/config"
+	"EWSBE/internal/entity"
 	"EWSBE/internal/usecase"
 	ws "EWSBE/internal/websocket"
+	"log"
 	"net/http"
+	"strings"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 )
 
 type Handler struct {
-	dataHandler *DataHandler
-	authHandler *AuthHandler
-	newsHandler *NewsHandler
-	r           *gin.Engine
+	dataHandler       *DataHandler
+	authHandler       *AuthHandler
+	newsHandler       *NewsHandler
+	alertHandler      *AlertHandler
+	stationHandler    *StationHandler
+	webhookHandler    *WebhookHandler
+	commandHandler    *CommandHandler
+	deadLetterHandler *DeadLetterHandler
+	deviceKeyHandler  *DeviceKeyHandler
+	userHandler       *UserHandler
+	sessionUc         *usecase.SessionUsecase
+	tokens            *auth.TokenService
+	deviceKeyUc       *usecase.DeviceKeyUsecase
+	r                 *gin.Engine
 }
 
-func NewHandler(dataUc *usecase.DataUsecase, authUc *usecase.AuthUsecase, newsUc *usecase.NewsUsecase, hub *ws.Hub) *Handler {
+func NewHandler(dataUc *usecase.DataUsecase, authUc *usecase.AuthUsecase, newsUc *usecase.NewsUsecase, alertUc *usecase.AlertUsecase, stationUc *usecase.StationUsecase, monitor *usecase.StationMonitor, webhookUc *usecase.WebhookUsecase, commandUc *usecase.CommandUsecase, deadLetterUc *usecase.DeadLetterUsecase, deviceKeyUc *usecase.DeviceKeyUsecase, userUc *usecase.UserUsecase, sessionUc *usecase.SessionUsecase, tokens *auth.TokenService, loginGuard *usecase.LoginGuard, hub *ws.Hub) *Handler {
 	r := gin.Default()
 
+	// per-IP login limits need the real client address, so X-Forwarded-For
+	// is only believed from these proxies (comma-separated IPs or CIDRs)
+	var proxies []string
+	for _, p := range strings.Split(config.GetEnv("TRUSTED_PROXIES", ""), ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			proxies = append(proxies, p)
+		}
+	}
+	if err := r.SetTrustedProxies(proxies); err != nil {
+		log.Printf("Warning: invalid TRUSTED_PROXIES: %v", err)
+	}
+
 	// CORS configuration
 	r.Use(cors.New(cors.Config{
 		AllowOriginFunc:  func(origin string) bool { return true },
 		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization", "X-Requested-With"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization", "X-API-Key", "X-Requested-With"},
 		ExposeHeaders:    []string{"Content-Length"},
 		AllowCredentials: true,
 	}))
 
-	dataHandler := NewDataHandler(dataUc, hub)
-	authHandler := NewAuthHandler(authUc)
+	dataHandler := NewDataHandler(dataUc, stationUc, alertUc, monitor, hub)
+	authHandler := NewAuthHandler(authUc, sessionUc, tokens)
 	newsHandler := NewNewsHandler(newsUc)
+	alertHandler := NewAlertHandler(alertUc, stationUc)
+	stationHandler := NewStationHandler(stationUc, monitor)
+	webhookHandler := NewWebhookHandler(webhookUc)
+	commandHandler := NewCommandHandler(commandUc, stationUc)
+	deadLetterHandler := NewDeadLetterHandler(deadLetterUc)
+	deviceKeyHandler := NewDeviceKeyHandler(deviceKeyUc)
+	userHandler := NewUserHandler(userUc, loginGuard)
 
 	h := &Handler{
-		dataHandler: dataHandler,
-		authHandler: authHandler,
-		newsHandler: newsHandler,
-		r:           r,
+		dataHandler:       dataHandler,
+		authHandler:       authHandler,
+		newsHandler:       newsHandler,
+		alertHandler:      alertHandler,
+		stationHandler:    stationHandler,
+		webhookHandler:    webhookHandler,
+		commandHandler:    commandHandler,
+		deadLetterHandler: deadLetterHandler,
+		deviceKeyHandler:  deviceKeyHandler,
+		userHandler:       userHandler,
+		sessionUc:         sessionUc,
+		tokens:            tokens,
+		deviceKeyUc:       deviceKeyUc,
+		r:                 r,
 	}
 
 	h.routes()
@@ -52,18 +96,31 @@ func (h *Handler) routes() {
 	api := h.r.Group("/api")
 
 	// Data Routes
-	api.POST("/data", h.dataHandler.CreateData)
+	api.POST("/data", DeviceAuthMiddleware(h.deviceKeyUc), h.dataHandler.CreateData)
 	api.GET("/data", h.dataHandler.GetAllData)
 	api.GET("/data/latest", h.dataHandler.GetLatestData)
 	api.GET("/data/history", h.dataHandler.GetDataHistory)
 	api.GET("/data/insights", h.dataHandler.GetDataInsights)
+	api.GET("/data/clock", h.dataHandler.GetClockDrift)
+	api.GET("/data/rainfall", h.dataHandler.GetRainfall)
+	api.GET("/data/windrose", h.dataHandler.GetWindRose)
+	api.GET("/data/power", h.dataHandler.GetPower)
 	api.GET("/health", h.dataHandler.HealthCheck)
 
+	dataAdmin := api.Group("/data")
+	dataAdmin.Use(AuthMiddleware(h.tokens, h.sessionUc))
+	{
+		dataAdmin.POST("/import", RequirePermission(entity.PermDataImport), h.dataHandler.ImportCSV)
+	}
+
 	// Auth Routes
 	authGroup := api.Group("/auth")
 	{
 		authGroup.POST("/register", h.authHandler.Register)
 		authGroup.POST("/login", h.authHandler.Login)
+		authGroup.POST("/refresh", h.authHandler.Refresh)
+		authGroup.POST("/logout", h.authHandler.Logout)
+		authGroup.GET("/registration", h.authHandler.RegistrationMode)
 	}
 
 	// News Routes
@@ -75,12 +132,101 @@ func (h *Handler) routes() {
 
 	// Protected routes
 	authorized := api.Group("/news")
-	authorized.Use(AuthMiddleware())
+	authorized.Use(AuthMiddleware(h.tokens, h.sessionUc), RequirePermission(entity.PermNewsWrite))
 	{
 		authorized.POST("", h.newsHandler.CreateNews)
 		authorized.PUT("/:id", h.newsHandler.UpdateNews)
 		authorized.DELETE("/:id", h.newsHandler.DeleteNews)
 	}
+
+	// Station Routes
+	api.GET("/stations", h.stationHandler.GetAllStations)
+	api.GET("/stations/status", h.stationHandler.GetStatuses)
+	api.GET("/stations/:code", h.stationHandler.GetStationByCode)
+	api.GET("/stations/:code/status", h.stationHandler.GetStatus)
+
+	stationAdmin := api.Group("/stations")
+	stationAdmin.Use(AuthMiddleware(h.tokens, h.sessionUc))
+	{
+		stationAdmin.POST("", RequirePermission(entity.PermStationsWrite), h.stationHandler.CreateStation)
+		stationAdmin.PUT("/:code", RequirePermission(entity.PermStationsWrite), h.stationHandler.UpdateStation)
+		stationAdmin.POST("/:code/commands", RequirePermission(entity.PermCommandsSend), h.commandHandler.SendCommand)
+		stationAdmin.GET("/:code/commands", RequirePermission(entity.PermCommandsRead), h.commandHandler.GetStationCommands)
+		stationAdmin.GET("/:code/keys", RequirePermission(entity.PermStationsWrite), h.deviceKeyHandler.GetKeys)
+		stationAdmin.POST("/:code/keys", RequirePermission(entity.PermStationsWrite), h.deviceKeyHandler.CreateKey)
+		stationAdmin.POST("/:code/keys/:id/rotate", RequirePermission(entity.PermStationsWrite), h.deviceKeyHandler.RotateKey)
+		stationAdmin.DELETE("/:code/keys/:id", RequirePermission(entity.PermStationsWrite), h.deviceKeyHandler.RevokeKey)
+	}
+
+	// Command Routes
+	commandAdmin := api.Group("/commands")
+	commandAdmin.Use(AuthMiddleware(h.tokens, h.sessionUc), RequirePermission(entity.PermCommandsRead))
+	{
+		commandAdmin.GET("/:id", h.commandHandler.GetCommandByID)
+	}
+
+	// Alert Routes
+	api.GET("/alerts/rules", h.alertHandler.GetRules)
+
+	alertAdmin := api.Group("/alerts")
+	alertAdmin.Use(AuthMiddleware(h.tokens, h.sessionUc))
+	{
+		alertAdmin.POST("/rules", RequirePermission(entity.PermAlertRulesWrite), h.alertHandler.CreateRule)
+		alertAdmin.PUT("/rules/:id", RequirePermission(entity.PermAlertRulesWrite), h.alertHandler.UpdateRule)
+		alertAdmin.GET("", RequirePermission(entity.PermAlertsRead), h.alertHandler.GetAlerts)
+		alertAdmin.GET("/:id", RequirePermission(entity.PermAlertsRead), h.alertHandler.GetAlertByID)
+		alertAdmin.POST("/:id/acknowledge", RequirePermission(entity.PermAlertsHandle), h.alertHandler.AcknowledgeAlert)
+		alertAdmin.POST("/:id/resolve", RequirePermission(entity.PermAlertsHandle), h.alertHandler.ResolveAlert)
+	}
+
+	// Webhook Routes
+	webhookAdmin := api.Group("/webhooks")
+	webhookAdmin.Use(AuthMiddleware(h.tokens, h.sessionUc), RequirePermission(entity.PermWebhooksManage))
+	{
+		webhookAdmin.GET("", h.webhookHandler.GetAllWebhooks)
+		webhookAdmin.POST("", h.webhookHandler.CreateWebhook)
+		webhookAdmin.PUT("/:id", h.webhookHandler.UpdateWebhook)
+		webhookAdmin.DELETE("/:id", h.webhookHandler.DeleteWebhook)
+		webhookAdmin.GET("/:id/deliveries", h.webhookHandler.GetDeliveries)
+		webhookAdmin.POST("/:id/test", h.webhookHandler.TestWebhook)
+	}
+
+	// Dead-letter Routes
+	deadLetterAdmin := api.Group("/deadletters")
+	deadLetterAdmin.Use(AuthMiddleware(h.tokens, h.sessionUc), RequirePermission(entity.PermDeadLettersManage))
+	{
+		deadLetterAdmin.GET("", h.deadLetterHandler.GetDeadLetters)
+		deadLetterAdmin.POST("/replay", h.deadLetterHandler.ReplayDeadLetters)
+		deadLetterAdmin.GET("/:id", h.deadLetterHandler.GetDeadLetterByID)
+		deadLetterAdmin.DELETE("/:id", h.deadLetterHandler.DeleteDeadLetter)
+		deadLetterAdmin.POST("/:id/replay", h.deadLetterHandler.ReplayDeadLetter)
+	}
+
+	// User Routes
+	userAdmin := api.Group("/users")
+	userAdmin.Use(AuthMiddleware(h.tokens, h.sessionUc), RequirePermission(entity.PermUsersManage))
+	{
+		userAdmin.GET("", h.userHandler.GetUsers)
+		userAdmin.POST("", h.userHandler.CreateUser)
+		userAdmin.GET("/login-attempts", h.userHandler.GetLoginAttempts)
+		userAdmin.GET("/lockouts", h.userHandler.GetLockouts)
+		userAdmin.DELETE("/lockouts/:id", h.userHandler.ClearLockout)
+		userAdmin.GET("/invites", h.userHandler.GetInvites)
+		userAdmin.POST("/invites", h.userHandler.CreateInvite)
+		userAdmin.DELETE("/invites/:id", h.userHandler.DeleteInvite)
+		userAdmin.GET("/:id", h.userHandler.GetUserByID)
+		userAdmin.DELETE("/:id", h.userHandler.DeleteUser)
+		userAdmin.PUT("/:id/role", h.userHandler.UpdateRole)
+		userAdmin.PUT("/:id/password", h.userHandler.ResetPassword)
+		userAdmin.POST("/:id/disable", h.userHandler.DisableUser)
+		userAdmin.POST("/:id/enable", h.userHandler.EnableUser)
+	}
+}
+
+// AddHealthCheck adds a section to the /api/health report; register checks
+// before the server starts
+func (h *Handler) AddHealthCheck(name string, check func() interface{}) {
+	h.dataHandler.healthChecks[name] = check
 }
 
 func (h *Handler) Router() http.Handler {
@@ -1,14 +1,15 @@
 package http
 
 import (
-	"os"
+	"EWSBE/internal/auth"
+	"EWSBE/internal/usecase"
 	"strings"
 
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt/v5"
 )
 
-func AuthMiddleware() gin.HandlerFunc {
+// AuthMiddleware accepts access tokens whose session has not been revoked
+func AuthMiddleware(tokens *auth.TokenService, sessions *usecase.SessionUsecase) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		tokenString := c.GetHeader("Authorization")
 		if tokenString == "" {
@@ -20,25 +21,69 @@ func AuthMiddleware() gin.HandlerFunc {
 		// Remove "Bearer " prefix
 		tokenString = strings.TrimPrefix(tokenString, "Bearer ")
 
-		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-			return []byte(os.Getenv("JWT_SECRET")), nil
-		})
-
-		if err != nil || !token.Valid {
+		claims, err := tokens.Parse(tokenString)
+		if err != nil {
 			c.JSON(401, gin.H{"error": "invalid token"})
 			c.Abort()
 			return
 		}
 
-		if claims, ok := token.Claims.(jwt.MapClaims); ok {
-			userID := uint(claims["user_id"].(float64))
-			c.Set("userID", userID)
-		} else {
-			c.JSON(401, gin.H{"error": "invalid token claims"})
+		if !sessions.Active(claims.SessionID) {
+			c.JSON(401, gin.H{"error": "session revoked or expired"})
+			c.Abort()
+			return
+		}
+
+		c.Set("userID", claims.UserID)
+		c.Set("sessionID", claims.SessionID)
+		c.Set("role", claims.Role)
+		c.Set("permissions", claims.Permissions)
+
+		c.Next()
+	}
+}
+
+// RequirePermission lets the request through when the token grants perm;
+// use it after AuthMiddleware
+func RequirePermission(perm string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		permissions, _ := c.Get("permissions")
+		list, _ := permissions.([]string)
+		for _, p := range list {
+			if p == perm {
+				c.Next()
+				return
+			}
+		}
+
+		c.JSON(403, gin.H{"error": "permission denied", "required": perm})
+		c.Abort()
+	}
+}
+
+// DeviceAuthMiddleware accepts a station API key from the X-API-Key header or
+// as a bearer token and sets the station the key belongs to
+func DeviceAuthMiddleware(keys *usecase.DeviceKeyUsecase) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		apiKey := c.GetHeader("X-API-Key")
+		if apiKey == "" {
+			apiKey = strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+		}
+		if apiKey == "" {
+			c.JSON(401, gin.H{"error": "api key required"})
+			c.Abort()
+			return
+		}
+
+		key, err := keys.Authenticate(apiKey)
+		if err != nil {
+			c.JSON(401, gin.H{"error": err.Error()})
 			c.Abort()
 			return
 		}
 
+		c.Set("deviceKeyID", key.ID)
+		c.Set("deviceStation", key.Station)
 		c.Next()
 	}
 }
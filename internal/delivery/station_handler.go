@@ -0,0 +1,95 @@
+package http
+
+import (
+	"EWSBE/internal/entity"
+	"EWSBE/internal/usecase"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type StationHandler struct {
+	stationUc *usecase.StationUsecase
+	monitor   *usecase.StationMonitor
+}
+
+func NewStationHandler(stationUc *usecase.StationUsecase, monitor *usecase.StationMonitor) *StationHandler {
+	return &StationHandler{stationUc: stationUc, monitor: monitor}
+}
+
+func (h *StationHandler) GetAllStations(c *gin.Context) {
+	stations, err := h.stationUc.GetAllStations()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, stations)
+}
+
+func (h *StationHandler) GetStationByCode(c *gin.Context) {
+	station, err := h.stationUc.GetStationByCode(c.Param("code"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "station not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, station)
+}
+
+func (h *StationHandler) CreateStation(c *gin.Context) {
+	var station entity.Station
+	if err := c.ShouldBindJSON(&station); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	station.ID = 0
+	if err := h.stationUc.CreateStation(&station); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, station)
+}
+
+func (h *StationHandler) UpdateStation(c *gin.Context) {
+	var input entity.StationUpdate
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	station, err := h.stationUc.UpdateStation(c.Param("code"), &input)
+	if err != nil {
+		if err.Error() == "station not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, station)
+}
+
+// GetStatuses returns the heartbeat status of every station
+func (h *StationHandler) GetStatuses(c *gin.Context) {
+	statuses, err := h.monitor.Statuses()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, statuses)
+}
+
+func (h *StationHandler) GetStatus(c *gin.Context) {
+	station, err := h.stationUc.GetStationByCode(c.Param("code"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "station not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, h.monitor.Status(station))
+}
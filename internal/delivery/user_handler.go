@@ -0,0 +1,296 @@
+package http
+
+import (
+	"EWSBE/internal/entity"
+	"EWSBE/internal/usecase"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type UserHandler struct {
+	userUc *usecase.UserUsecase
+	guard  *usecase.LoginGuard
+}
+
+func NewUserHandler(userUc *usecase.UserUsecase, guard *usecase.LoginGuard) *UserHandler {
+	return &UserHandler{userUc: userUc, guard: guard}
+}
+
+func userError(c *gin.Context, err error) {
+	switch err.Error() {
+	case "user not found", "invite not found", "lockout not found":
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case "username already taken", "cannot remove the last active admin", "user has authored content, disable the account instead", "invite already used":
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	}
+}
+
+func userID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// actorID is the admin making the request
+func actorID(c *gin.Context) uint {
+	v, _ := c.Get("userID")
+	id, _ := v.(uint)
+	return id
+}
+
+func (h *UserHandler) GetUsers(c *gin.Context) {
+	users, err := h.userUc.GetUsers()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, users)
+}
+
+func (h *UserHandler) GetUserByID(c *gin.Context) {
+	id, ok := userID(c)
+	if !ok {
+		return
+	}
+
+	user, err := h.userUc.GetUserByID(id)
+	if err != nil {
+		userError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"user": user, "permissions": user.Permissions()})
+}
+
+func (h *UserHandler) CreateUser(c *gin.Context) {
+	var req struct {
+		Username string `json:"username" binding:"required"`
+		Password string `json:"password" binding:"required"`
+		Role     string `json:"role"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, err := h.userUc.CreateUser(req.Username, req.Password, req.Role)
+	if err != nil {
+		userError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, user)
+}
+
+// UpdateRole changes a user's role; it takes effect when their access token is next refreshed
+func (h *UserHandler) UpdateRole(c *gin.Context) {
+	id, ok := userID(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Role string `json:"role" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, err := h.userUc.SetRole(id, req.Role)
+	if err != nil {
+		userError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"user": user, "permissions": user.Permissions()})
+}
+
+func (h *UserHandler) DisableUser(c *gin.Context) {
+	id, ok := userID(c)
+	if !ok {
+		return
+	}
+
+	user, err := h.userUc.Disable(id, actorID(c))
+	if err != nil {
+		userError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, user)
+}
+
+func (h *UserHandler) EnableUser(c *gin.Context) {
+	id, ok := userID(c)
+	if !ok {
+		return
+	}
+
+	user, err := h.userUc.Enable(id)
+	if err != nil {
+		userError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, user)
+}
+
+func (h *UserHandler) DeleteUser(c *gin.Context) {
+	id, ok := userID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.userUc.Delete(id, actorID(c)); err != nil {
+		userError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "user deleted"})
+}
+
+func (h *UserHandler) ResetPassword(c *gin.Context) {
+	id, ok := userID(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Password string `json:"password" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.userUc.ResetPassword(id, req.Password); err != nil {
+		userError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "password reset"})
+}
+
+func (h *UserHandler) GetInvites(c *gin.Context) {
+	invites, err := h.userUc.GetInvites()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, invites)
+}
+
+// CreateInvite issues an invite code; expires_in is in hours
+func (h *UserHandler) CreateInvite(c *gin.Context) {
+	var req struct {
+		Role      string `json:"role"`
+		Note      string `json:"note"`
+		ExpiresIn int    `json:"expires_in"`
+	}
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	invite, code, err := h.userUc.CreateInvite(req.Role, req.Note, time.Duration(req.ExpiresIn)*time.Hour, actorID(c))
+	if err != nil {
+		userError(c, err)
+		return
+	}
+
+	// the code is only ever returned here
+	c.JSON(http.StatusCreated, gin.H{"invite": invite, "code": code})
+}
+
+func (h *UserHandler) DeleteInvite(c *gin.Context) {
+	id, ok := userID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.userUc.DeleteInvite(id); err != nil {
+		userError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "invite deleted"})
+}
+
+// GetLoginAttempts lists the login audit trail, newest first (query:
+// username, ip, success, from, to as RFC3339, limit, offset)
+func (h *UserHandler) GetLoginAttempts(c *gin.Context) {
+	filter := entity.LoginAttemptFilter{
+		Username: c.Query("username"),
+		IP:       c.Query("ip"),
+	}
+	if v := c.Query("success"); v != "" {
+		success, err := strconv.ParseBool(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid success"})
+			return
+		}
+		filter.Success = &success
+	}
+	for param, target := range map[string]**time.Time{"from": &filter.From, "to": &filter.To} {
+		if v := c.Query(param); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid " + param})
+				return
+			}
+			*target = &t
+		}
+	}
+	filter.Limit, _ = strconv.Atoi(c.DefaultQuery("limit", "100"))
+	filter.Offset, _ = strconv.Atoi(c.DefaultQuery("offset", "0"))
+
+	attempts, total, err := h.guard.GetAttempts(filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"total": total,
+		"count": len(attempts),
+		"data":  attempts,
+	})
+}
+
+// GetLockouts lists the usernames and IPs currently refused
+func (h *UserHandler) GetLockouts(c *gin.Context) {
+	lockouts, err := h.guard.GetLockouts()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, lockouts)
+}
+
+func (h *UserHandler) ClearLockout(c *gin.Context) {
+	id, ok := userID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.guard.ClearLockout(id); err != nil {
+		userError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "lockout cleared"})
+}
@@ -0,0 +1,173 @@
+package http
+
+import (
+	"EWSBE/internal/entity"
+	"EWSBE/internal/usecase"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type WebhookHandler struct {
+	webhookUc *usecase.WebhookUsecase
+}
+
+func NewWebhookHandler(webhookUc *usecase.WebhookUsecase) *WebhookHandler {
+	return &WebhookHandler{webhookUc: webhookUc}
+}
+
+// request body for create and update; the secret is write-only
+type webhookRequest struct {
+	Name    string   `json:"name"`
+	URL     string   `json:"url"`
+	Events  []string `json:"events"`
+	Secret  string   `json:"secret"`
+	Enabled *bool    `json:"enabled"`
+}
+
+func (h *WebhookHandler) GetAllWebhooks(c *gin.Context) {
+	hooks, err := h.webhookUc.GetAllWebhooks()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, hooks)
+}
+
+func (h *WebhookHandler) CreateWebhook(c *gin.Context) {
+	var req webhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	hook := entity.Webhook{
+		Name:    req.Name,
+		URL:     req.URL,
+		Events:  req.Events,
+		Secret:  req.Secret,
+		Enabled: req.Enabled == nil || *req.Enabled,
+	}
+	if userID, ok := c.Get("userID"); ok {
+		hook.CreatedByID = userID.(uint)
+	}
+
+	if err := h.webhookUc.CreateWebhook(&hook); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// the secret is only ever returned here
+	c.JSON(http.StatusCreated, gin.H{"webhook": hook, "secret": hook.Secret})
+}
+
+func (h *WebhookHandler) UpdateWebhook(c *gin.Context) {
+	id, ok := webhookID(c)
+	if !ok {
+		return
+	}
+
+	var req webhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	current, err := h.webhookUc.GetWebhookByID(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	input := entity.Webhook{
+		Name:    req.Name,
+		URL:     req.URL,
+		Events:  req.Events,
+		Secret:  req.Secret,
+		Enabled: current.Enabled,
+	}
+	if req.Enabled != nil {
+		input.Enabled = *req.Enabled
+	}
+
+	hook, err := h.webhookUc.UpdateWebhook(id, &input)
+	if err != nil {
+		if err.Error() == "webhook not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, hook)
+}
+
+func (h *WebhookHandler) DeleteWebhook(c *gin.Context) {
+	id, ok := webhookID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.webhookUc.DeleteWebhook(id); err != nil {
+		if err.Error() == "webhook not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "webhook deleted successfully"})
+}
+
+func (h *WebhookHandler) GetDeliveries(c *gin.Context) {
+	id, ok := webhookID(c)
+	if !ok {
+		return
+	}
+
+	if _, err := h.webhookUc.GetWebhookByID(id); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	deliveries, err := h.webhookUc.GetDeliveries(id, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, deliveries)
+}
+
+func (h *WebhookHandler) TestWebhook(c *gin.Context) {
+	id, ok := webhookID(c)
+	if !ok {
+		return
+	}
+
+	deliveryID, err := h.webhookUc.Test(id)
+	if err != nil {
+		if err.Error() == "webhook not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"delivery_id": deliveryID})
+}
+
+func webhookID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return 0, false
+	}
+	return uint(id), true
+}
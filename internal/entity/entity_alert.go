@@ -0,0 +1,207 @@
+package entity
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type AlertSeverity string
+
+const (
+	SeverityNormal  AlertSeverity = "normal"
+	SeverityWatch   AlertSeverity = "watch"
+	SeverityWarning AlertSeverity = "warning"
+	SeverityDanger  AlertSeverity = "danger"
+)
+
+// Rank orders severities so they can be compared (normal < watch < warning < danger)
+func (s AlertSeverity) Rank() int {
+	switch s {
+	case SeverityWatch:
+		return 1
+	case SeverityWarning:
+		return 2
+	case SeverityDanger:
+		return 3
+	default:
+		return 0
+	}
+}
+
+const (
+	RuleOperatorAbove = "above" // alert when value rises to or above the threshold
+	RuleOperatorBelow = "below" // alert when value drops to or below the threshold
+)
+
+const (
+	AlertStatusOpen         = "open"
+	AlertStatusAcknowledged = "acknowledged" // still active, an operator has taken ownership
+	AlertStatusResolved     = "resolved"
+)
+
+// threshold rule evaluated against every sensor reading
+type AlertRule struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	Name        string    `json:"name" gorm:"unique;not null"`
+	Description string    `json:"description"`
+	Field       string    `json:"field" gorm:"not null"`    // SensorData field, see SensorData.FieldValue
+	Operator    string    `json:"operator" gorm:"not null"` // above | below
+	Watch       *float64  `json:"watch"`                    // nil disables the level
+	Warning     *float64  `json:"warning"`
+	Danger      *float64  `json:"danger"`
+	Hysteresis  float64   `json:"hysteresis"` // margin the value must clear before stepping down a level
+	Enabled     bool      `json:"enabled"`
+	SeedKey     *string   `json:"seed_key,omitempty" gorm:"uniqueIndex"` // set on built-in rules, survives renames
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// fields of a rule update; omitted fields keep their current value
+type AlertRuleUpdate struct {
+	Name        *string       `json:"name"`
+	Description *string       `json:"description"`
+	Field       *string       `json:"field"`
+	Operator    *string       `json:"operator"`
+	Watch       OptionalFloat `json:"watch"` // null disables the level
+	Warning     OptionalFloat `json:"warning"`
+	Danger      OptionalFloat `json:"danger"`
+	Hysteresis  *float64      `json:"hysteresis"`
+	Enabled     *bool         `json:"enabled"`
+}
+
+// OptionalFloat tells an omitted field (Set false) from an explicit null
+// (Set true, Value nil) in an update body
+type OptionalFloat struct {
+	Set   bool
+	Value *float64
+}
+
+func (o *OptionalFloat) UnmarshalJSON(data []byte) error {
+	o.Set = true
+	if string(data) == "null" {
+		o.Value = nil
+		return nil
+	}
+	var v float64
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	o.Value = &v
+	return nil
+}
+
+// Threshold returns the threshold configured for a severity level
+func (r *AlertRule) Threshold(s AlertSeverity) *float64 {
+	switch s {
+	case SeverityWatch:
+		return r.Watch
+	case SeverityWarning:
+		return r.Warning
+	case SeverityDanger:
+		return r.Danger
+	default:
+		return nil
+	}
+}
+
+func (r *AlertRule) exceeds(value, threshold float64) bool {
+	if r.Operator == RuleOperatorBelow {
+		return value <= threshold
+	}
+	return value >= threshold
+}
+
+func (r *AlertRule) releases(value, threshold float64) bool {
+	if r.Operator == RuleOperatorBelow {
+		return value > threshold+r.Hysteresis
+	}
+	return value < threshold-r.Hysteresis
+}
+
+// Level computes the severity for value given the current severity.
+// Escalation is immediate, but stepping down a level requires the value to
+// clear that level's threshold by the rule's hysteresis margin.
+func (r *AlertRule) Level(value float64, current AlertSeverity) AlertSeverity {
+	levels := []AlertSeverity{SeverityWatch, SeverityWarning, SeverityDanger}
+
+	level := SeverityNormal
+	for _, l := range levels {
+		if t := r.Threshold(l); t != nil && r.exceeds(value, *t) {
+			level = l
+		}
+	}
+
+	if level.Rank() >= current.Rank() {
+		return level
+	}
+
+	// walk down from the current level, stopping at the first one still held
+	for i := current.Rank() - 1; i >= level.Rank(); i-- {
+		l := levels[i]
+		if t := r.Threshold(l); t != nil && !r.releases(value, *t) {
+			return l
+		}
+	}
+	return level
+}
+
+// alert raised by a rule for one station; one record per episode from open to resolved
+type Alert struct {
+	ID           uint          `json:"id" gorm:"primaryKey"`
+	RuleID       uint          `json:"rule_id" gorm:"index;not null"`
+	Rule         AlertRule     `json:"rule" gorm:"foreignKey:RuleID"`
+	StationID    *uint         `json:"station_id" gorm:"index"`
+	Station      *Station      `json:"station,omitempty" gorm:"foreignKey:StationID"`
+	Status       string        `json:"status" gorm:"index;not null"`
+	Severity     AlertSeverity `json:"severity" gorm:"not null"`      // current severity
+	PeakSeverity AlertSeverity `json:"peak_severity" gorm:"not null"` // highest severity reached
+	Value        float64       `json:"value"`                         // value that opened the alert
+	LastValue    float64       `json:"last_value"`                    // most recent evaluated value
+	ReadingID    uint          `json:"reading_id"`                    // reading that opened the alert
+	OpenedAt     time.Time     `json:"opened_at"`
+	ResolvedAt   *time.Time    `json:"resolved_at,omitempty"`
+	CreatedAt    time.Time     `json:"created_at"`
+	UpdatedAt    time.Time     `json:"updated_at"`
+
+	// operator workflow
+	AcknowledgedAt   *time.Time `json:"acknowledged_at,omitempty"`
+	AcknowledgedByID *uint      `json:"acknowledged_by_id,omitempty"`
+	AcknowledgedBy   *User      `json:"acknowledged_by,omitempty" gorm:"foreignKey:AcknowledgedByID"`
+	AckComment       string     `json:"ack_comment,omitempty" gorm:"type:text"`
+	ResolvedByID     *uint      `json:"resolved_by_id,omitempty"` // nil when cleared automatically
+	ResolvedBy       *User      `json:"resolved_by,omitempty" gorm:"foreignKey:ResolvedByID"`
+	ResolveComment   string     `json:"resolve_comment,omitempty" gorm:"type:text"`
+}
+
+// IsActive reports whether the alert has not been resolved yet
+func (a *Alert) IsActive() bool {
+	return a.Status != AlertStatusResolved
+}
+
+// filters for listing alert history; zero values are ignored
+type AlertFilter struct {
+	StationID uint
+	RuleID    uint
+	Status    string
+	Severity  string
+	From      *time.Time // opened at or after
+	To        *time.Time // opened at or before
+	Limit     int
+	Offset    int
+}
+
+const (
+	AlertEventOpened       = "opened"
+	AlertEventEscalated    = "escalated"
+	AlertEventDeescalated  = "deescalated"
+	AlertEventCleared      = "cleared" // value returned to normal
+	AlertEventAcknowledged = "acknowledged"
+	AlertEventResolved     = "resolved" // closed manually by an operator
+)
+
+// state change produced by the alert engine
+type AlertEvent struct {
+	Type             string        `json:"type"`
+	PreviousSeverity AlertSeverity `json:"previousSeverity"`
+	Alert            Alert         `json:"alert"`
+}
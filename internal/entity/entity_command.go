@@ -0,0 +1,67 @@
+package entity
+
+import "time"
+
+// commands a station understands
+const (
+	CommandSetInterval = "set_interval" // params: {"seconds": n}
+	CommandResetRain   = "reset_rain"   // zero the tipping-bucket counter
+	CommandReboot      = "reboot"
+	CommandReadNow     = "read_now"   // publish a reading immediately
+	CommandSyncClock   = "sync_clock" // params filled in on send: {"epoch": unix ms}
+)
+
+var CommandTypes = []string{CommandSetInterval, CommandResetRain, CommandReboot, CommandReadNow, CommandSyncClock}
+
+// command lifecycle
+const (
+	CommandPending  = "pending" // published, waiting for the acknowledgement
+	CommandAcked    = "acked"
+	CommandFailed   = "failed" // rejected by the device or not published
+	CommandTimedOut = "timed_out"
+)
+
+// downlink command sent to one station over MQTT
+type StationCommand struct {
+	ID         uint                   `json:"id" gorm:"primaryKey"`
+	CommandID  string                 `json:"command_id" gorm:"uniqueIndex;not null"` // correlation ID echoed in the ack
+	StationID  uint                   `json:"station_id" gorm:"index;not null"`
+	Station    *Station               `json:"station,omitempty" gorm:"foreignKey:StationID"`
+	Type       string                 `json:"type" gorm:"not null"`
+	Params     map[string]interface{} `json:"params,omitempty" gorm:"type:jsonb;serializer:json"`
+	Topic      string                 `json:"topic"`
+	Status     string                 `json:"status" gorm:"index;not null"`
+	Error      string                 `json:"error,omitempty"`
+	Result     map[string]interface{} `json:"result,omitempty" gorm:"type:jsonb;serializer:json"` // reported by the device
+	IssuedByID uint                   `json:"issued_by_id"`
+	IssuedBy   *User                  `json:"issued_by,omitempty" gorm:"foreignKey:IssuedByID"`
+	SentAt     *time.Time             `json:"sent_at"`
+	AckedAt    *time.Time             `json:"acked_at"`
+	ExpiresAt  time.Time              `json:"expires_at" gorm:"index"`
+	CreatedAt  time.Time              `json:"created_at"`
+	UpdatedAt  time.Time              `json:"updated_at"`
+}
+
+// message published on the station's command topic
+type CommandMessage struct {
+	ID      string                 `json:"id"`
+	Cmd     string                 `json:"cmd"`
+	Params  map[string]interface{} `json:"params,omitempty"`
+	Ts      int64                  `json:"ts"`      // unix ms when sent
+	Expires int64                  `json:"expires"` // unix ms after which the device should ignore it
+}
+
+// acknowledgement the station publishes on its ack topic
+type CommandAck struct {
+	ID      string                 `json:"id"`
+	Status  string                 `json:"status"` // "ok" or "error"
+	Message string                 `json:"message,omitempty"`
+	Result  map[string]interface{} `json:"result,omitempty"`
+}
+
+type CommandFilter struct {
+	StationID uint
+	Status    string
+	Limit     int
+	Offset    int
+}
@@ -6,22 +6,92 @@ import (
 
 // for database storage of sensor readings
 type SensorData struct {
-	ID            uint      `json:"id" gorm:"primaryKey"`
-	CreatedAt     time.Time `json:"createdAt"`
-	UpdatedAt     time.Time `json:"updatedAt"`
-	Timestamp     time.Time `json:"timestamp" gorm:"index"` // when sensor reading was taken
-	Temperature   float64   `json:"temperature"`            // °C (from suhu)
-	Humidity      float64   `json:"humidity"`               // % (from lembap)
-	Pressure      float64   `json:"pressure"`               // hPa (from tekanan)
-	Altitude      float64   `json:"altitude"`               // meters (from ketinggian)
-	Co2           float64   `json:"co2"`                    // ppm (from co2)
-	Distance      float64   `json:"distance"`               // cm (from jarak)
-	WindSpeed     float64   `json:"windSpeed"`              // m/s (from angin)
-	WindDirection float64   `json:"windDirection"`          // degrees 0-360 (from arahAngin)
-	Rainfall      float64   `json:"rainfall"`               // mm (from rain)
-	Voltage       float64   `json:"voltage"`                // V (from voltSensor)
-	BusVoltage    float64   `json:"busVoltage"`             // V (from busVoltage)
-	Current       float64   `json:"current"`                // mA (from current_mA)
+	ID             uint                   `json:"id" gorm:"primaryKey"`
+	CreatedAt      time.Time              `json:"createdAt"`
+	UpdatedAt      time.Time              `json:"updatedAt"`
+	StationID      *uint                  `json:"stationId" gorm:"index"`                            // station that produced the reading
+	Timestamp      time.Time              `json:"timestamp" gorm:"index"`                            // when sensor reading was taken
+	DeviceTime     int64                  `json:"deviceTime"`                                        // raw waktu value reported by the device
+	ReceivedAt     time.Time              `json:"receivedAt"`                                        // when the backend received the reading
+	TimeSource     string                 `json:"timeSource"`                                        // how Timestamp was derived (device, server, uptime)
+	Temperature    float64                `json:"temperature"`                                       // °C (from suhu)
+	Humidity       float64                `json:"humidity"`                                          // % (from lembap)
+	Pressure       float64                `json:"pressure"`                                          // hPa (from tekanan)
+	Altitude       float64                `json:"altitude"`                                          // meters (from ketinggian)
+	Co2            float64                `json:"co2"`                                               // ppm (from co2)
+	Distance       float64                `json:"distance"`                                          // cm (from jarak)
+	WaterLevel     *float64               `json:"waterLevel"`                                        // cm above the station datum, derived from distance
+	WaterLevelRate *float64               `json:"waterLevelRate"`                                    // cm/hour rise (negative when falling)
+	WindSpeed      float64                `json:"windSpeed"`                                         // m/s (from angin)
+	WindDirection  float64                `json:"windDirection"`                                     // degrees 0-360 (from arahAngin)
+	Rainfall       float64                `json:"rainfall"`                                          // mm (from rain)
+	Voltage        float64                `json:"voltage"`                                           // V (from voltSensor)
+	BusVoltage     float64                `json:"busVoltage"`                                        // V (from busVoltage)
+	Current        float64                `json:"current"`                                           // mA (from current_mA)
+	Quality        QualityFlags           `json:"quality" gorm:"type:jsonb;serializer:json"`         // per-field quality from ingestion validation
+	Extra          map[string]interface{} `json:"extra,omitempty" gorm:"type:jsonb;serializer:json"` // payload fields without a column, e.g. soil moisture
+	MessageID      string                 `json:"messageId,omitempty"`                               // optional ID set by the device, identifies redeliveries
+	IngestKey      *string                `json:"-" gorm:"uniqueIndex"`                              // station + message ID or device time, see usecase.ingestKey
+}
+
+type QualityFlag string
+
+const (
+	QualityGood    QualityFlag = "good"
+	QualitySuspect QualityFlag = "suspect" // plausible but unusual: outside normal range, too fast a change, or stuck
+	QualityBad     QualityFlag = "bad"     // physically impossible or a sensor error code; excluded from aggregates
+	QualityMissing QualityFlag = "missing" // not reported by the station's firmware; treated like bad
+)
+
+// quality flag per SensorData field, keyed by JSON field name
+type QualityFlags map[string]QualityFlag
+
+// IsBad reports whether a field was flagged bad or missing; unvalidated
+// fields count as good
+func (q QualityFlags) IsBad(field string) bool {
+	return q[field] == QualityBad || q[field] == QualityMissing
+}
+
+// FieldValue returns the value of a reading field by its JSON name, used by alert rules
+func (d *SensorData) FieldValue(field string) (float64, bool) {
+	switch field {
+	case "temperature":
+		return d.Temperature, true
+	case "humidity":
+		return d.Humidity, true
+	case "pressure":
+		return d.Pressure, true
+	case "altitude":
+		return d.Altitude, true
+	case "co2":
+		return d.Co2, true
+	case "distance":
+		return d.Distance, true
+	case "waterLevel":
+		if d.WaterLevel == nil {
+			return 0, false
+		}
+		return *d.WaterLevel, true
+	case "waterLevelRate":
+		if d.WaterLevelRate == nil {
+			return 0, false
+		}
+		return *d.WaterLevelRate, true
+	case "windSpeed":
+		return d.WindSpeed, true
+	case "windDirection":
+		return d.WindDirection, true
+	case "rainfall":
+		return d.Rainfall, true
+	case "voltage":
+		return d.Voltage, true
+	case "busVoltage":
+		return d.BusVoltage, true
+	case "current":
+		return d.Current, true
+	default:
+		return 0, false
+	}
 }
 
 // grouped/aggregated data for insights
@@ -31,47 +101,186 @@ type AggregatedData struct {
 	Humidity      float64   `json:"humidity"`
 	Pressure      float64   `json:"pressure"`
 	WindSpeed     float64   `json:"windSpeed"`
+	WindGust      float64   `json:"windGust"` // highest wind speed in the bucket
 	Rainfall      float64   `json:"rainfall"`
 	Co2           float64   `json:"co2"`
 	Altitude      float64   `json:"altitude"`
-	WindDirection float64   `json:"windDirection"`
+	WindDirection float64   `json:"windDirection"` // speed-weighted vector mean, 0-360
+	WaterLevel    *float64  `json:"waterLevel"`    // average, nil when the station is uncalibrated
+	MaxWaterLevel *float64  `json:"maxWaterLevel"` // highest level in the bucket
+}
+
+// outcome of a bulk CSV import
+type ImportResult struct {
+	Total        int              `json:"total"`
+	Inserted     int              `json:"inserted"`
+	SkippedLines []int            `json:"skippedLines"` // duplicates of stored readings or earlier rows
+	Rejected     []ImportRejected `json:"rejected"`
+}
+
+type ImportRejected struct {
+	Line  int    `json:"line"`
+	Error string `json:"error"`
+}
+
+// clock drift per time source, drift = receivedAt - timestamp
+type ClockDrift struct {
+	TimeSource      string  `json:"timeSource"`
+	Count           int64   `json:"count"`
+	AvgDriftSeconds float64 `json:"avgDriftSeconds"`
+	MinDriftSeconds float64 `json:"minDriftSeconds"`
+	MaxDriftSeconds float64 `json:"maxDriftSeconds"`
 }
 
 // for analytical insights
 type DataInsights struct {
-	MinTemp        float64 `json:"minTemp"`
-	MaxTemp        float64 `json:"maxTemp"`
-	AvgTemp        float64 `json:"avgTemp"`
-	MinHum         float64 `json:"minHum"`
-	MaxHum         float64 `json:"maxHum"`
-	AvgHum         float64 `json:"avgHum"`
-	PrevMonthDiff  float64 `json:"prevMonthDiff"` // difference in average temp vs previous month
-	PeakHour       int     `json:"peakHour"`      // hour of day with highest average temp
-	PeakHourAvg    float64 `json:"peakHourAvg"`   // avg temp at peak hour
+	MinTemp       float64 `json:"minTemp"`
+	MaxTemp       float64 `json:"maxTemp"`
+	AvgTemp       float64 `json:"avgTemp"`
+	MinHum        float64 `json:"minHum"`
+	MaxHum        float64 `json:"maxHum"`
+	AvgHum        float64 `json:"avgHum"`
+	PrevMonthDiff float64 `json:"prevMonthDiff"` // difference in average temp vs previous month
+	PeakHour      int     `json:"peakHour"`      // hour of day with highest average temp
+	PeakHourAvg   float64 `json:"peakHourAvg"`   // avg temp at peak hour
+}
+
+// timestamp strategies for ingested payloads
+const (
+	TimestampDevice = "device" // trust waktu as Unix epoch milliseconds
+	TimestampServer = "server" // ignore waktu and use the receive time
+	TimestampAuto   = "auto"   // trust plausible epochs, rebase uptime-style values on the receive time
+)
+
+// time sources recorded on each reading
+const (
+	TimeSourceDevice = "device" // device clock (epoch)
+	TimeSourceServer = "server" // backend receive time
+	TimeSourceUptime = "uptime" // millis() since boot, rebased on the receive time
+)
+
+// epochs before this are treated as uptime counters by TimestampAuto (2020-01-01)
+const minDeviceEpochMs = 1577836800000
+
+// ResolveTimestamp derives the reading time from the raw device value
+// according to strategy, returning the time and the source it came from
+func ResolveTimestamp(raw int64, receivedAt time.Time, strategy string) (time.Time, string) {
+	switch strategy {
+	case TimestampDevice:
+		return time.UnixMilli(raw), TimeSourceDevice
+	case TimestampServer:
+		return receivedAt, TimeSourceServer
+	}
+
+	// auto: an epoch must be recent and not ahead of the receive time by more than a day
+	if raw >= minDeviceEpochMs && raw <= receivedAt.Add(24*time.Hour).UnixMilli() {
+		return time.UnixMilli(raw), TimeSourceDevice
+	}
+
+	// uptime since boot: the reading was taken at boot + raw, and boot is
+	// receivedAt - raw for a live message, so the reading time is the receive time
+	return receivedAt, TimeSourceUptime
 }
 
 // from sensor MQTT payload
 type MQTTSensorPayload struct {
-	Waktu      int64   `json:"waktu"`       // unix timestamp milliseconds
-	Suhu       float64 `json:"suhu"`        // temperature
-	Lembap     float64 `json:"lembap"`      // humidity
-	Tekanan    float64 `json:"tekanan"`     // pressure
-	Ketinggian float64 `json:"ketinggian"`  // altitude
-	Co2        float64 `json:"co2"`         // co2
-	Jarak      float64 `json:"jarak"`       // distance
-	Angin      float64 `json:"angin"`       // wind speed
-	ArahAngin  float64 `json:"arahAngin"`   // wind direction
-	BusVoltage float64 `json:"busVoltage"`  // bus voltage
-	CurrentMA  float64 `json:"current_mA"`  // current
-	VoltSensor float64 `json:"voltSensor"`  // voltage sensor
-	Rain       float64 `json:"rain"`        // rainfall
+	Waktu      int64   `json:"waktu"`      // epoch or uptime milliseconds, see ResolveTimestamp
+	Suhu       float64 `json:"suhu"`       // temperature
+	Lembap     float64 `json:"lembap"`     // humidity
+	Tekanan    float64 `json:"tekanan"`    // pressure
+	Ketinggian float64 `json:"ketinggian"` // altitude
+	Co2        float64 `json:"co2"`        // co2
+	Jarak      float64 `json:"jarak"`      // distance
+	Angin      float64 `json:"angin"`      // wind speed
+	ArahAngin  float64 `json:"arahAngin"`  // wind direction
+	BusVoltage float64 `json:"busVoltage"` // bus voltage
+	CurrentMA  float64 `json:"current_mA"` // current
+	VoltSensor float64 `json:"voltSensor"` // voltage sensor
+	Rain       float64 `json:"rain"`       // rainfall
+}
+
+// SD card log header written by the firmware, in column order
+var SDLogColumns = []string{
+	"waktu_ms", "suhu", "lembap", "tekanan", "ketinggian", "co2", "jarak",
+	"angin", "arahAngin", "teganganINA", "arusINA", "teganganSensor", "curahHujan",
+}
+
+// SD card log column -> MQTTSensorPayload JSON field
+var SDLogFieldMapping = map[string]string{
+	"waktu_ms":       "waktu",
+	"suhu":           "suhu",
+	"lembap":         "lembap",
+	"tekanan":        "tekanan",
+	"ketinggian":     "ketinggian",
+	"co2":            "co2",
+	"jarak":          "jarak",
+	"angin":          "angin",
+	"arahAngin":      "arahAngin",
+	"teganganINA":    "busVoltage",
+	"arusINA":        "current_mA",
+	"teganganSensor": "voltSensor",
+	"curahHujan":     "rain",
+}
+
+// MQTTSensorPayload JSON field -> SensorData JSON field, waktu excluded
+var PayloadFieldMapping = map[string]string{
+	"suhu":       "temperature",
+	"lembap":     "humidity",
+	"tekanan":    "pressure",
+	"ketinggian": "altitude",
+	"co2":        "co2",
+	"jarak":      "distance",
+	"angin":      "windSpeed",
+	"arahAngin":  "windDirection",
+	"busVoltage": "busVoltage",
+	"current_mA": "current",
+	"voltSensor": "voltage",
+	"rain":       "rainfall",
+}
+
+// SetField assigns a payload field by its JSON name
+func (m *MQTTSensorPayload) SetField(field string, value float64) bool {
+	switch field {
+	case "waktu":
+		m.Waktu = int64(value)
+	case "suhu":
+		m.Suhu = value
+	case "lembap":
+		m.Lembap = value
+	case "tekanan":
+		m.Tekanan = value
+	case "ketinggian":
+		m.Ketinggian = value
+	case "co2":
+		m.Co2 = value
+	case "jarak":
+		m.Jarak = value
+	case "angin":
+		m.Angin = value
+	case "arahAngin":
+		m.ArahAngin = value
+	case "busVoltage":
+		m.BusVoltage = value
+	case "current_mA":
+		m.CurrentMA = value
+	case "voltSensor":
+		m.VoltSensor = value
+	case "rain":
+		m.Rain = value
+	default:
+		return false
+	}
+	return true
 }
 
-func (m *MQTTSensorPayload) ToSensorData() *SensorData {
-	timestamp := time.Unix(0, m.Waktu*int64(time.Millisecond))
+func (m *MQTTSensorPayload) ToSensorData(strategy string, receivedAt time.Time) *SensorData {
+	timestamp, source := ResolveTimestamp(m.Waktu, receivedAt, strategy)
 
 	return &SensorData{
 		Timestamp:     timestamp,
+		DeviceTime:    m.Waktu,
+		ReceivedAt:    receivedAt,
+		TimeSource:    source,
 		Temperature:   m.Suhu,
 		Humidity:      m.Lembap,
 		Pressure:      m.Tekanan,
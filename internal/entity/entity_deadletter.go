@@ -0,0 +1,57 @@
+package entity
+
+import "time"
+
+// pipeline stage that rejected a message
+const (
+	DeadLetterStageDecode  = "decode"  // payload could not be parsed
+	DeadLetterStageStation = "station" // station could not be resolved
+	DeadLetterStageStore   = "store"   // reading rejected by the database
+)
+
+const (
+	DeadLetterPending   = "pending"
+	DeadLetterReplayed  = "replayed"  // stored after a replay
+	DeadLetterDuplicate = "duplicate" // the reading was already stored
+)
+
+// MQTT message the ingestion pipeline could not store, kept for replay
+type DeadLetter struct {
+	ID           uint       `json:"id" gorm:"primaryKey"`
+	Topic        string     `json:"topic" gorm:"index;not null"`
+	Pattern      string     `json:"pattern"`                             // subscription that received it, selects the station segment
+	Payload      []byte     `json:"payload,omitempty" gorm:"type:bytea"` // raw bytes, base64 in JSON
+	PayloadText  string     `json:"payload_text,omitempty" gorm:"-"`     // payload as text when it is valid UTF-8
+	Size         int        `json:"size"`
+	Stage        string     `json:"stage" gorm:"index;not null"`
+	Decoder      string     `json:"decoder,omitempty"`
+	Error        string     `json:"error" gorm:"type:text"`
+	Status       string     `json:"status" gorm:"index;not null"`
+	ReceivedAt   time.Time  `json:"received_at" gorm:"index"`
+	ReplayCount  int        `json:"replay_count"`
+	LastReplayAt *time.Time `json:"last_replay_at"`
+	ReplayError  string     `json:"replay_error,omitempty" gorm:"type:text"`
+	ReadingID    *uint      `json:"reading_id"` // reading stored by a successful replay, or the stored duplicate
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+type DeadLetterFilter struct {
+	Topic  string
+	Stage  string
+	Status string
+	From   *time.Time
+	To     *time.Time
+	Limit  int
+	Offset int
+}
+
+// outcome of replaying one dead letter
+type DeadLetterReplay struct {
+	ID        uint   `json:"id"`
+	Status    string `json:"status"`
+	Error     string `json:"error,omitempty"`
+	ReadingID *uint  `json:"reading_id,omitempty"`
+	// stored as historical data: no heartbeat, alerts or power tracking
+	Backfilled bool `json:"backfilled"`
+}
@@ -0,0 +1,27 @@
+package entity
+
+import "time"
+
+// API key a station uses to post readings over HTTP
+type DeviceKey struct {
+	ID          uint       `json:"id" gorm:"primaryKey"`
+	StationID   uint       `json:"station_id" gorm:"index;not null"`
+	Station     *Station   `json:"station,omitempty" gorm:"foreignKey:StationID"`
+	Name        string     `json:"name"`
+	Prefix      string     `json:"prefix"`                        // start of the key, to tell keys apart
+	Hash        string     `json:"-" gorm:"uniqueIndex;not null"` // SHA-256 of the key, the key itself is only shown once
+	CreatedByID uint       `json:"created_by_id"`
+	LastUsedAt  *time.Time `json:"last_used_at"`
+	ExpiresAt   *time.Time `json:"expires_at"` // end of the grace period after a rotation
+	RevokedAt   *time.Time `json:"revoked_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// Active reports whether the key is accepted at time now
+func (k *DeviceKey) Active(now time.Time) bool {
+	if k.RevokedAt != nil {
+		return false
+	}
+	return k.ExpiresAt == nil || now.Before(*k.ExpiresAt)
+}
@@ -0,0 +1,59 @@
+package entity
+
+import "time"
+
+// login attempt outcomes
+const (
+	LoginOK          = "ok"
+	LoginBadPassword = "bad_password"
+	LoginUnknownUser = "unknown_user"
+	LoginDisabled    = "disabled"
+)
+
+// audit record of one login attempt
+type LoginAttempt struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	Username  string    `json:"username" gorm:"index"`
+	UserID    *uint     `json:"user_id"`
+	IP        string    `json:"ip" gorm:"index"`
+	UserAgent string    `json:"user_agent"`
+	Success   bool      `json:"success"`
+	Result    string    `json:"result"`
+	CreatedAt time.Time `json:"created_at" gorm:"index"`
+}
+
+type LoginAttemptFilter struct {
+	Username string
+	IP       string
+	Success  *bool
+	From     *time.Time
+	To       *time.Time
+	Limit    int
+	Offset   int
+}
+
+// what a lockout counts failures for
+const (
+	LockoutUsername = "username"
+	LockoutIP       = "ip"
+)
+
+// failed-login counter for one username or IP. Each failure past the free
+// attempts blocks further tries for a growing delay; enough failures lock
+// it out for longer.
+type LoginLockout struct {
+	ID            uint       `json:"id" gorm:"primaryKey"`
+	Kind          string     `json:"kind" gorm:"uniqueIndex:idx_login_lockout;not null"`
+	Key           string     `json:"key" gorm:"uniqueIndex:idx_login_lockout;not null"`
+	Failures      int        `json:"failures"`
+	LastFailureAt time.Time  `json:"last_failure_at" gorm:"index"`
+	BlockedUntil  *time.Time `json:"blocked_until"`
+	Locked        bool       `json:"locked"` // blocked for the lockout period rather than a delay
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+// Blocked reports whether attempts are refused at time now
+func (l *LoginLockout) Blocked(now time.Time) bool {
+	return l.BlockedUntil != nil && now.Before(*l.BlockedUntil)
+}
@@ -0,0 +1,79 @@
+package entity
+
+import "time"
+
+// battery chemistries with a built-in state-of-charge curve
+const (
+	BatteryLiIon    = "liion"
+	BatteryLiFePO4  = "lifepo4"
+	BatteryLeadAcid = "leadacid"
+)
+
+// values derived by the power module and evaluated by alert rules like
+// reading fields
+const (
+	MetricBatterySoc       = "batterySoc"       // % estimated from battery voltage
+	MetricHoursSinceCharge = "hoursSinceCharge" // hours since the battery last took charge
+)
+
+var DerivedAlertFields = []string{MetricBatterySoc, MetricHoursSinceCharge}
+
+// INA219 readings at one point in time; nil when flagged bad.
+// Current is in mA, positive when drawn from the battery and negative
+// while charging.
+type PowerPoint struct {
+	Timestamp  time.Time `json:"timestamp"`
+	Voltage    *float64  `json:"voltage"`
+	BusVoltage *float64  `json:"busVoltage"`
+	Current    *float64  `json:"current"`
+}
+
+// battery charge/discharge states
+const (
+	BatteryCharging    = "charging"
+	BatteryDischarging = "discharging"
+	BatteryIdle        = "idle"
+	BatteryUnknown     = "unknown"
+)
+
+type BatteryState struct {
+	ReadAt     time.Time `json:"readAt"`
+	Voltage    float64   `json:"voltage"`
+	BusVoltage *float64  `json:"busVoltage"`
+	Current    *float64  `json:"current"`
+	Power      *float64  `json:"power"` // W, bus voltage x current
+	Soc        float64   `json:"soc"`   // %, estimated from voltage
+	State      string    `json:"state"`
+}
+
+// least-squares slope of battery voltage over the trailing window
+type PowerTrend struct {
+	WindowHours  float64 `json:"windowHours"`
+	VoltageSlope float64 `json:"voltageSlope"` // V/h
+	Direction    string  `json:"direction"`    // charging, discharging or idle
+	Samples      int     `json:"samples"`
+}
+
+type PowerDaily struct {
+	Date         string   `json:"date"`       // YYYY-MM-DD in server local time
+	ConsumedWh   float64  `json:"consumedWh"` // energy drawn from the battery
+	ChargedWh    float64  `json:"chargedWh"`  // energy put into the battery
+	NetWh        float64  `json:"netWh"`      // charged - consumed
+	MinVoltage   *float64 `json:"minVoltage"`
+	MaxVoltage   *float64 `json:"maxVoltage"`
+	CoveredHours float64  `json:"coveredHours"` // time span actually integrated
+}
+
+type PowerSummary struct {
+	AsOf             time.Time     `json:"asOf"`
+	Chemistry        string        `json:"chemistry"`
+	Cells            int           `json:"cells"`
+	Battery          *BatteryState `json:"battery"` // nil without a valid reading
+	Trend            *PowerTrend   `json:"trend"`   // nil with fewer than two samples
+	Daily            []PowerDaily  `json:"daily"`
+	LastChargeAt     *time.Time    `json:"lastChargeAt"`
+	HoursSinceCharge *float64      `json:"hoursSinceCharge"`
+	LowBattery       bool          `json:"lowBattery"`
+	ChargingFailure  bool          `json:"chargingFailure"`
+	Alerts           []Alert       `json:"alerts"` // active power alerts for the station
+}
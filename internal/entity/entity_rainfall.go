@@ -0,0 +1,31 @@
+package entity
+
+import "time"
+
+// rainfall amount at one reading (tipping-bucket delta)
+type RainfallPoint struct {
+	Timestamp time.Time `json:"timestamp"`
+	Rainfall  float64   `json:"rainfall"`
+}
+
+type RainfallDaily struct {
+	Date  string  `json:"date"` // YYYY-MM-DD in server local time
+	Total float64 `json:"total"`
+}
+
+// contiguous period of rain separated from the next by a dry gap
+type RainEvent struct {
+	Start           time.Time `json:"start"`
+	End             time.Time `json:"end"`
+	DurationMinutes float64   `json:"durationMinutes"`
+	Total           float64   `json:"total"`         // mm
+	PeakIntensity   float64   `json:"peakIntensity"` // mm/h, highest rolling 1-hour total
+	Ongoing         bool      `json:"ongoing"`
+}
+
+type RainfallSummary struct {
+	AsOf          time.Time          `json:"asOf"`
+	Accumulations map[string]float64 `json:"accumulations"` // "1h", "3h", "6h", "24h" -> mm
+	Daily         []RainfallDaily    `json:"daily"`
+	Events        []RainEvent        `json:"events"`
+}
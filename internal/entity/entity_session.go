@@ -0,0 +1,40 @@
+package entity
+
+import "time"
+
+// login session: one refresh token family. Access tokens carry the session ID
+// so revoking the session cuts them off too.
+type Session struct {
+	ID            uint       `json:"id" gorm:"primaryKey"`
+	FamilyID      string     `json:"family_id" gorm:"uniqueIndex;not null"` // "sid" claim of its access tokens
+	UserID        uint       `json:"user_id" gorm:"index;not null"`
+	UserAgent     string     `json:"user_agent"`
+	IP            string     `json:"ip"`
+	LastRefreshAt *time.Time `json:"last_refresh_at"`
+	RevokedAt     *time.Time `json:"revoked_at"`
+	RevokedReason string     `json:"revoked_reason,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+// single-use refresh token; refreshing marks it used and issues the next one
+// in the same session
+type RefreshToken struct {
+	ID        uint       `json:"id" gorm:"primaryKey"`
+	SessionID uint       `json:"session_id" gorm:"index;not null"`
+	Session   *Session   `json:"session,omitempty" gorm:"foreignKey:SessionID"`
+	TokenHash string     `json:"-" gorm:"uniqueIndex;not null"` // SHA-256 of the token
+	ExpiresAt time.Time  `json:"expires_at" gorm:"index"`
+	UsedAt    *time.Time `json:"used_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// why a session was revoked
+const (
+	RevokedLogout   = "logout"
+	RevokedReuse    = "refresh token reuse"
+	RevokedDisabled = "account disabled"
+	RevokedPassword = "password reset"
+	RevokedDeleted  = "account deleted"
+	RevokedRole     = "role changed"
+)
@@ -0,0 +1,78 @@
+package entity
+
+import "time"
+
+// field station (ESP32 node) producing sensor readings
+type Station struct {
+	ID          uint       `json:"id" gorm:"primaryKey"`
+	Code        string     `json:"code" gorm:"unique;not null"` // matched against the MQTT topic segment
+	Name        string     `json:"name" gorm:"not null"`
+	Latitude    float64    `json:"latitude"`
+	Longitude   float64    `json:"longitude"`
+	Elevation   float64    `json:"elevation"` // meters above sea level
+	InstalledAt *time.Time `json:"installed_at,omitempty"`
+
+	// water level calibration for the ultrasonic sensor:
+	// level (cm above datum) = SensorHeight - distance + LevelOffset
+	SensorHeight *float64 `json:"sensor_height"` // cm from datum to the sensor face; nil = uncalibrated
+	DatumName    string   `json:"datum_name"`    // what level 0 refers to, e.g. "riverbed" or "gauge zero"
+	LevelOffset  float64  `json:"level_offset"`  // cm correction from comparison with a staff gauge
+
+	// heartbeat, maintained by the station monitor
+	ExpectedInterval int        `json:"expected_interval"` // seconds between reports; 0 = monitor default
+	LastSeenAt       *time.Time `json:"last_seen_at"`
+	Status           string     `json:"status" gorm:"default:unknown"`
+	StatusChangedAt  *time.Time `json:"status_changed_at"`
+	GapCount         int        `json:"gap_count"` // times reporting lapsed past the stale threshold
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// fields of a station update; omitted fields keep their current value
+type StationUpdate struct {
+	Name             *string       `json:"name"`
+	Latitude         *float64      `json:"latitude"`
+	Longitude        *float64      `json:"longitude"`
+	Elevation        *float64      `json:"elevation"`
+	InstalledAt      *time.Time    `json:"installed_at"`
+	SensorHeight     OptionalFloat `json:"sensor_height"` // null marks the station uncalibrated
+	DatumName        *string       `json:"datum_name"`
+	LevelOffset      *float64      `json:"level_offset"`
+	ExpectedInterval *int          `json:"expected_interval"`
+}
+
+// station heartbeat states
+const (
+	StationUnknown = "unknown" // never reported
+	StationOnline  = "online"
+	StationStale   = "stale"
+	StationOffline = "offline"
+)
+
+// heartbeat snapshot for one station
+type StationStatus struct {
+	StationID        uint       `json:"station_id"`
+	Code             string     `json:"code"`
+	Name             string     `json:"name"`
+	Status           string     `json:"status"`
+	LastSeenAt       *time.Time `json:"last_seen_at"`
+	SecondsSinceSeen *float64   `json:"seconds_since_seen"`
+	ExpectedInterval int        `json:"expected_interval"` // seconds, after applying the default
+	StatusChangedAt  *time.Time `json:"status_changed_at"`
+	GapCount         int        `json:"gap_count"`
+}
+
+// emitted when a station changes heartbeat state
+type StationStatusEvent struct {
+	PreviousStatus string        `json:"previous_status"`
+	Station        StationStatus `json:"station"`
+}
+
+// WaterLevel converts a raw ultrasonic distance into water level above the datum
+func (s *Station) WaterLevel(distance float64) (float64, bool) {
+	if s.SensorHeight == nil {
+		return 0, false
+	}
+	return *s.SensorHeight - distance + s.LevelOffset, true
+}
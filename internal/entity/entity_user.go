@@ -3,9 +3,75 @@ package entity
 import "time"
 
 type User struct {
-	ID        uint      `json:"id" gorm:"primaryKey"`
-	Username  string    `json:"username" gorm:"unique;not null"`
-	Password  string    `json:"-" gorm:"not null"` // - means not serialized
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID         uint       `json:"id" gorm:"primaryKey"`
+	Username   string     `json:"username" gorm:"unique;not null"`
+	Password   string     `json:"-" gorm:"not null"` // - means not serialized
+	Role       string     `json:"role" gorm:"not null;default:viewer"`
+	DisabledAt *time.Time `json:"disabled_at"` // disabled accounts cannot log in
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
+
+// single-use code that lets someone register while registration is invite-only
+type Invite struct {
+	ID          uint       `json:"id" gorm:"primaryKey"`
+	Prefix      string     `json:"prefix"`                        // start of the code, to tell invites apart
+	CodeHash    string     `json:"-" gorm:"uniqueIndex;not null"` // SHA-256 of the code, shown once on creation
+	Role        string     `json:"role" gorm:"not null"`          // role the new account gets
+	Note        string     `json:"note"`
+	CreatedByID uint       `json:"created_by_id"`
+	ExpiresAt   time.Time  `json:"expires_at"`
+	UsedAt      *time.Time `json:"used_at"`
+	UsedByID    *uint      `json:"used_by_id"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// user roles
+const (
+	RoleAdmin    = "admin"    // everything, including users and integrations
+	RoleEditor   = "editor"   // publishes news
+	RoleOperator = "operator" // handles alerts and sends station commands
+	RoleViewer   = "viewer"   // read-only access to protected data
+)
+
+var Roles = []string{RoleAdmin, RoleEditor, RoleOperator, RoleViewer}
+
+// permissions checked on protected routes
+const (
+	PermNewsWrite         = "news:write"
+	PermAlertsRead        = "alerts:read"
+	PermAlertsHandle      = "alerts:handle" // acknowledge and resolve
+	PermAlertRulesWrite   = "alerts:rules"
+	PermStationsWrite     = "stations:write" // stations and their API keys
+	PermCommandsRead      = "commands:read"
+	PermCommandsSend      = "commands:send"
+	PermDataImport        = "data:import"
+	PermWebhooksManage    = "webhooks:manage"
+	PermDeadLettersManage = "deadletters:manage"
+	PermUsersManage       = "users:manage"
+)
+
+var viewerPermissions = []string{PermAlertsRead, PermCommandsRead}
+
+// role -> granted permissions
+var RolePermissions = map[string][]string{
+	RoleViewer:   viewerPermissions,
+	RoleEditor:   append([]string{PermNewsWrite}, viewerPermissions...),
+	RoleOperator: append([]string{PermAlertsHandle, PermCommandsSend, PermDataImport}, viewerPermissions...),
+	RoleAdmin: {
+		PermNewsWrite, PermAlertsRead, PermAlertsHandle, PermAlertRulesWrite,
+		PermStationsWrite, PermCommandsRead, PermCommandsSend, PermDataImport,
+		PermWebhooksManage, PermDeadLettersManage, PermUsersManage,
+	},
+}
+
+// ValidRole reports whether role is one of Roles
+func ValidRole(role string) bool {
+	_, ok := RolePermissions[role]
+	return ok
+}
+
+// Permissions returns what the user's role grants
+func (u *User) Permissions() []string {
+	return RolePermissions[u.Role]
 }
@@ -0,0 +1,97 @@
+package entity
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// webhook event types
+const (
+	WebhookEventAlertOpened       = "alert.opened"
+	WebhookEventAlertEscalated    = "alert.escalated"
+	WebhookEventAlertDeescalated  = "alert.deescalated"
+	WebhookEventAlertAcknowledged = "alert.acknowledged"
+	WebhookEventAlertCleared      = "alert.cleared" // back to normal or resolved by an operator
+	WebhookEventNewsPublished     = "news.published"
+	WebhookEventStationOffline    = "station.offline"
+	WebhookEventStationOnline     = "station.online"
+	WebhookEventPing              = "ping" // sent by the test endpoint
+	WebhookEventAll               = "*"
+)
+
+var WebhookEvents = []string{
+	WebhookEventAlertOpened,
+	WebhookEventAlertEscalated,
+	WebhookEventAlertDeescalated,
+	WebhookEventAlertAcknowledged,
+	WebhookEventAlertCleared,
+	WebhookEventNewsPublished,
+	WebhookEventStationOffline,
+	WebhookEventStationOnline,
+}
+
+// WebhookEventForAlert maps an alert engine event to its webhook event type
+func WebhookEventForAlert(alertEvent string) string {
+	switch alertEvent {
+	case AlertEventOpened:
+		return WebhookEventAlertOpened
+	case AlertEventEscalated:
+		return WebhookEventAlertEscalated
+	case AlertEventDeescalated:
+		return WebhookEventAlertDeescalated
+	case AlertEventAcknowledged:
+		return WebhookEventAlertAcknowledged
+	default:
+		return WebhookEventAlertCleared
+	}
+}
+
+// external endpoint notified of selected events
+type Webhook struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	Name        string    `json:"name" gorm:"not null"`
+	URL         string    `json:"url" gorm:"not null"`
+	Events      []string  `json:"events" gorm:"type:jsonb;serializer:json"` // event types, or "*" for all
+	Secret      string    `json:"-" gorm:"not null"`                        // HMAC signing key, only shown on create
+	Enabled     bool      `json:"enabled"`
+	CreatedByID uint      `json:"created_by_id"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// Wants reports whether the webhook subscribes to an event type
+func (w *Webhook) Wants(event string) bool {
+	if event == WebhookEventPing {
+		return true
+	}
+	for _, e := range w.Events {
+		if e == event || e == WebhookEventAll {
+			return true
+		}
+	}
+	return false
+}
+
+// body POSTed to webhook targets
+type WebhookEnvelope struct {
+	ID        string          `json:"id"` // delivery ID, constant across retries
+	Event     string          `json:"event"`
+	CreatedAt time.Time       `json:"created_at"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// one delivery attempt
+type WebhookDelivery struct {
+	ID           uint      `json:"id" gorm:"primaryKey"`
+	WebhookID    uint      `json:"webhook_id" gorm:"index;not null"`
+	DeliveryID   string    `json:"delivery_id" gorm:"index;not null"`
+	Event        string    `json:"event" gorm:"not null"`
+	Attempt      int       `json:"attempt"`
+	Payload      string    `json:"payload" gorm:"type:text"`
+	StatusCode   int       `json:"status_code"`
+	Success      bool      `json:"success"`
+	Error        string    `json:"error,omitempty"`
+	ResponseBody string    `json:"response_body,omitempty" gorm:"type:text"` // truncated
+	DurationMs   int64     `json:"duration_ms"`
+	CreatedAt    time.Time `json:"created_at"`
+}
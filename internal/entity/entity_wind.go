@@ -0,0 +1,37 @@
+package entity
+
+import "time"
+
+// 16-point compass sectors, each 22.5° wide and centred on its heading
+var CompassSectors = []string{
+	"N", "NNE", "NE", "ENE", "E", "ESE", "SE", "SSE",
+	"S", "SSW", "SW", "WSW", "W", "WNW", "NW", "NNW",
+}
+
+// upper edges (m/s) of the wind rose speed bins; below the first edge is calm
+var WindRoseSpeedEdges = []float64{0.5, 2, 4, 6, 8, 11}
+
+// reading count for one sector and speed bin, as grouped by the database
+type WindRoseCell struct {
+	Sector int   `json:"sector"` // index into CompassSectors
+	Bin    int   `json:"bin"`    // 0 = calm, i = between WindRoseSpeedEdges[i-1] and [i], last = above
+	Count  int64 `json:"count"`
+}
+
+type WindRoseSector struct {
+	Direction   string    `json:"direction"`
+	Angle       float64   `json:"angle"`       // sector centre in degrees
+	Counts      []int64   `json:"counts"`      // per speed bin, calm excluded
+	Frequencies []float64 `json:"frequencies"` // percent of all readings, per speed bin
+	Frequency   float64   `json:"frequency"`   // percent of all readings in this sector
+}
+
+type WindRose struct {
+	Start         time.Time        `json:"start"`
+	End           time.Time        `json:"end"`
+	Total         int64            `json:"total"`
+	Calm          int64            `json:"calm"`
+	CalmFrequency float64          `json:"calmFrequency"` // percent
+	SpeedBins     []string         `json:"speedBins"`     // labels in m/s
+	Sectors       []WindRoseSector `json:"sectors"`
+}
@@ -0,0 +1,101 @@
+package model
+
+import (
+	"EWSBE/internal/entity"
+	"EWSBE/internal/repository"
+
+	"gorm.io/gorm"
+)
+
+type alertModel struct {
+	db *gorm.DB
+}
+
+func NewAlertRepo(db *gorm.DB) repository.AlertRepository {
+	return &alertModel{db: db}
+}
+
+func (r *alertModel) GetRules() ([]entity.AlertRule, error) {
+	var rules []entity.AlertRule
+	if err := r.db.Order("id asc").Find(&rules).Error; err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+func (r *alertModel) GetRuleByID(id uint) (*entity.AlertRule, error) {
+	var rule entity.AlertRule
+	if err := r.db.First(&rule, id).Error; err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+func (r *alertModel) CreateRule(rule *entity.AlertRule) error {
+	return r.db.Create(rule).Error
+}
+
+func (r *alertModel) UpdateRule(rule *entity.AlertRule) error {
+	return r.db.Save(rule).Error
+}
+
+func (r *alertModel) GetOpenAlerts() ([]entity.Alert, error) {
+	var alerts []entity.Alert
+	if err := r.db.Preload("Rule").Preload("Station").Where("status <> ?", entity.AlertStatusResolved).Find(&alerts).Error; err != nil {
+		return nil, err
+	}
+	return alerts, nil
+}
+
+func (r *alertModel) GetAlerts(filter entity.AlertFilter) ([]entity.Alert, int64, error) {
+	query := r.db.Model(&entity.Alert{})
+	if filter.StationID != 0 {
+		query = query.Where("station_id = ?", filter.StationID)
+	}
+	if filter.RuleID != 0 {
+		query = query.Where("rule_id = ?", filter.RuleID)
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.Severity != "" {
+		query = query.Where("peak_severity = ?", filter.Severity)
+	}
+	if filter.From != nil {
+		query = query.Where("opened_at >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where("opened_at <= ?", *filter.To)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var alerts []entity.Alert
+	if err := query.Preload("Rule").Preload("Station").Preload("AcknowledgedBy").Preload("ResolvedBy").
+		Order("opened_at desc").
+		Limit(filter.Limit).
+		Offset(filter.Offset).
+		Find(&alerts).Error; err != nil {
+		return nil, 0, err
+	}
+	return alerts, total, nil
+}
+
+func (r *alertModel) GetAlertByID(id uint) (*entity.Alert, error) {
+	var alert entity.Alert
+	if err := r.db.Preload("Rule").Preload("Station").Preload("AcknowledgedBy").Preload("ResolvedBy").First(&alert, id).Error; err != nil {
+		return nil, err
+	}
+	return &alert, nil
+}
+
+func (r *alertModel) CreateAlert(alert *entity.Alert) error {
+	return r.db.Omit("Rule", "Station", "AcknowledgedBy", "ResolvedBy").Create(alert).Error
+}
+
+func (r *alertModel) UpdateAlert(alert *entity.Alert) error {
+	return r.db.Omit("Rule", "Station", "AcknowledgedBy", "ResolvedBy").Save(alert).Error
+}
@@ -0,0 +1,77 @@
+package model
+
+import (
+	"EWSBE/internal/entity"
+	"EWSBE/internal/repository"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type commandModel struct {
+	db *gorm.DB
+}
+
+func NewCommandRepo(db *gorm.DB) repository.CommandRepository {
+	return &commandModel{db: db}
+}
+
+func (r *commandModel) CreateCommand(cmd *entity.StationCommand) error {
+	return r.db.Create(cmd).Error
+}
+
+func (r *commandModel) UpdateCommand(cmd *entity.StationCommand) error {
+	return r.db.Omit("Station", "IssuedBy").Save(cmd).Error
+}
+
+func (r *commandModel) GetCommandByID(id uint) (*entity.StationCommand, error) {
+	var cmd entity.StationCommand
+	if err := r.db.Preload("Station").Preload("IssuedBy").First(&cmd, id).Error; err != nil {
+		return nil, err
+	}
+	return &cmd, nil
+}
+
+func (r *commandModel) GetCommandByCommandID(commandID string) (*entity.StationCommand, error) {
+	var cmd entity.StationCommand
+	if err := r.db.Where("command_id = ?", commandID).First(&cmd).Error; err != nil {
+		return nil, err
+	}
+	return &cmd, nil
+}
+
+func (r *commandModel) GetCommands(filter entity.CommandFilter) ([]entity.StationCommand, int64, error) {
+	query := r.db.Model(&entity.StationCommand{})
+	if filter.StationID != 0 {
+		query = query.Where("station_id = ?", filter.StationID)
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var cmds []entity.StationCommand
+	if err := query.Preload("Station").Preload("IssuedBy").
+		Order("created_at desc").
+		Limit(filter.Limit).
+		Offset(filter.Offset).
+		Find(&cmds).Error; err != nil {
+		return nil, 0, err
+	}
+	return cmds, total, nil
+}
+
+// GetExpiredCommands returns pending commands whose acknowledgement is overdue
+func (r *commandModel) GetExpiredCommands(now time.Time) ([]entity.StationCommand, error) {
+	var cmds []entity.StationCommand
+	if err := r.db.Where("status = ? AND expires_at < ?", entity.CommandPending, now).
+		Order("expires_at asc").
+		Find(&cmds).Error; err != nil {
+		return nil, err
+	}
+	return cmds, nil
+}
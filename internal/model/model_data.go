@@ -4,6 +4,7 @@ import (
 	"EWSBE/internal/entity"
 	"EWSBE/internal/repository"
 	"fmt"
+	"strings"
 	"time"
 
 	"gorm.io/gorm"
@@ -21,6 +22,41 @@ func (r *dataModel) CreateData(u *entity.SensorData) error {
 	return r.db.Create(u).Error
 }
 
+func (r *dataModel) CreateDataBatch(data []entity.SensorData, batchSize int) error {
+	return r.db.CreateInBatches(data, batchSize).Error
+}
+
+func (r *dataModel) GetTimestamps(stationID uint, start, end time.Time) ([]time.Time, error) {
+	var timestamps []time.Time
+	if err := r.db.Model(&entity.SensorData{}).
+		Scopes(scopeStation(stationID)).
+		Where("timestamp >= ? AND timestamp <= ?", start, end).
+		Pluck("timestamp", &timestamps).Error; err != nil {
+		return nil, err
+	}
+	return timestamps, nil
+}
+
+// GetExistingIngestKeys returns the given keys that are already stored
+func (r *dataModel) GetExistingIngestKeys(keys []string) ([]string, error) {
+	var existing []string
+	if err := r.db.Model(&entity.SensorData{}).
+		Where("ingest_key IN ?", keys).
+		Pluck("ingest_key", &existing).Error; err != nil {
+		return nil, err
+	}
+	return existing, nil
+}
+
+// GetDataIDByIngestKey returns the ID of the reading stored under key
+func (r *dataModel) GetDataIDByIngestKey(key string) (uint, error) {
+	var data entity.SensorData
+	if err := r.db.Select("id").Where("ingest_key = ?", key).First(&data).Error; err != nil {
+		return 0, err
+	}
+	return data.ID, nil
+}
+
 func (r *dataModel) GetAllData() ([]entity.SensorData, error) {
 	var u []entity.SensorData
 	if err := r.db.Order("timestamp desc").Find(&u).Error; err != nil {
@@ -30,9 +66,19 @@ func (r *dataModel) GetAllData() ([]entity.SensorData, error) {
 	return u, nil
 }
 
-func (r *dataModel) GetLatestData() (*entity.SensorData, error) {
+// scopeStation limits a query to one station; 0 keeps all stations
+func scopeStation(stationID uint) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if stationID == 0 {
+			return db
+		}
+		return db.Where("station_id = ?", stationID)
+	}
+}
+
+func (r *dataModel) GetLatestData(stationID uint) (*entity.SensorData, error) {
 	var data entity.SensorData
-	if err := r.db.Order("timestamp desc").First(&data).Error; err != nil {
+	if err := r.db.Scopes(scopeStation(stationID)).Order("timestamp desc").First(&data).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return nil, nil
 		}
@@ -41,9 +87,9 @@ func (r *dataModel) GetLatestData() (*entity.SensorData, error) {
 	return &data, nil
 }
 
-func (r *dataModel) GetDataByTimeRange(start, end time.Time) ([]entity.SensorData, error) {
+func (r *dataModel) GetDataByTimeRange(stationID uint, start, end time.Time) ([]entity.SensorData, error) {
 	var data []entity.SensorData
-	if err := r.db.Where("timestamp >= ? AND timestamp <= ?", start, end).
+	if err := r.db.Scopes(scopeStation(stationID)).Where("timestamp >= ? AND timestamp <= ?", start, end).
 		Order("timestamp desc").
 		Find(&data).Error; err != nil {
 		return nil, err
@@ -51,19 +97,100 @@ func (r *dataModel) GetDataByTimeRange(start, end time.Time) ([]entity.SensorDat
 	return data, nil
 }
 
-func (r *dataModel) GetDataByLimit(limit int) ([]entity.SensorData, error) {
+func (r *dataModel) GetDataByLimit(stationID uint, limit int) ([]entity.SensorData, error) {
 	var data []entity.SensorData
-	if err := r.db.Order("timestamp desc").Limit(limit).Find(&data).Error; err != nil {
+	if err := r.db.Scopes(scopeStation(stationID)).Order("timestamp desc").Limit(limit).Find(&data).Error; err != nil {
 		return nil, err
 	}
 	return data, nil
 }
 
+func (r *dataModel) GetClockDrift(stationID uint, since time.Time) ([]entity.ClockDrift, error) {
+	var results []entity.ClockDrift
+
+	err := r.db.Model(&entity.SensorData{}).
+		Scopes(scopeStation(stationID)).
+		Select("time_source, COUNT(*) as count, " +
+			"AVG(EXTRACT(EPOCH FROM received_at - timestamp)) as avg_drift_seconds, " +
+			"MIN(EXTRACT(EPOCH FROM received_at - timestamp)) as min_drift_seconds, " +
+			"MAX(EXTRACT(EPOCH FROM received_at - timestamp)) as max_drift_seconds").
+		Where("received_at >= ? AND time_source <> ''", since).
+		Group("time_source").
+		Scan(&results).Error
+
+	return results, err
+}
+
+// GetRainfall returns the rainfall series in ascending order, skipping bad values
+func (r *dataModel) GetRainfall(stationID uint, start, end time.Time) ([]entity.RainfallPoint, error) {
+	var points []entity.RainfallPoint
+	if err := r.db.Model(&entity.SensorData{}).
+		Scopes(scopeStation(stationID)).
+		Select("timestamp, rainfall").
+		Where("timestamp >= ? AND timestamp <= ?", start, end).
+		Where(qualityColumn("rainfall", "rainfall", false) + " IS NOT NULL").
+		Order("timestamp asc").
+		Scan(&points).Error; err != nil {
+		return nil, err
+	}
+	return points, nil
+}
+
+// GetPower returns the INA219 series in ascending order; values flagged bad
+// come back as NULL
+func (r *dataModel) GetPower(stationID uint, start, end time.Time) ([]entity.PowerPoint, error) {
+	var points []entity.PowerPoint
+	if err := r.db.Model(&entity.SensorData{}).
+		Scopes(scopeStation(stationID)).
+		Select("timestamp, " +
+			qualityColumn("voltage", "voltage", false) + " AS voltage, " +
+			qualityColumn("bus_voltage", "busVoltage", false) + " AS bus_voltage, " +
+			qualityColumn("current", "current", false) + " AS current").
+		Where("timestamp >= ? AND timestamp <= ?", start, end).
+		Order("timestamp asc").
+		Scan(&points).Error; err != nil {
+		return nil, err
+	}
+	return points, nil
+}
+
+// GetWindRose counts readings per compass sector and speed bin
+func (r *dataModel) GetWindRose(stationID uint, start, end time.Time) ([]entity.WindRoseCell, error) {
+	var cells []entity.WindRoseCell
+
+	edges := make([]string, len(entity.WindRoseSpeedEdges))
+	for i, e := range entity.WindRoseSpeedEdges {
+		edges[i] = fmt.Sprintf("%g", e)
+	}
+	sector := "CAST(FLOOR(MOD(CAST(wind_direction + 11.25 AS numeric), 360) / 22.5) AS integer)"
+	bin := fmt.Sprintf("WIDTH_BUCKET(wind_speed, ARRAY[%s]::float8[])", strings.Join(edges, ","))
+
+	err := r.db.Model(&entity.SensorData{}).
+		Scopes(scopeStation(stationID)).
+		Select(fmt.Sprintf("%s as sector, %s as bin, COUNT(*) as count", sector, bin)).
+		Where("timestamp >= ? AND timestamp <= ?", start, end).
+		Where(qualityColumn("wind_speed", "windSpeed", false) + " IS NOT NULL").
+		Where(qualityColumn("wind_direction", "windDirection", false) + " IS NOT NULL").
+		Group("sector, bin").
+		Scan(&cells).Error
+
+	return cells, err
+}
+
 // ======================================================== //
 // =================== For Insight Page =================== //
 // ======================================================== //
 
-func (r *dataModel) GetAggregatedData(interval string, start, end time.Time) ([]entity.AggregatedData, error) {
+// qualityColumn yields NULL instead of column when the field was flagged bad
+// or missing, so aggregate functions skip it
+func qualityColumn(column, field string, includeBad bool) string {
+	if includeBad {
+		return column
+	}
+	return fmt.Sprintf("(CASE WHEN quality->>'%s' IN ('bad', 'missing') THEN NULL ELSE %s END)", field, column)
+}
+
+func (r *dataModel) GetAggregatedData(stationID uint, interval string, start, end time.Time, includeBad bool) ([]entity.AggregatedData, error) {
 	var results []entity.AggregatedData
 
 	// map interval to PostgreSQL date_trunc parameter
@@ -81,21 +208,42 @@ func (r *dataModel) GetAggregatedData(interval string, start, end time.Time) ([]
 		period = "hour"
 	}
 
-	querySelect := fmt.Sprintf("date_trunc('%s', timestamp) as timestamp, AVG(temperature) as temperature, AVG(humidity) as humidity, AVG(pressure) as pressure, AVG(wind_speed) as wind_speed, AVG(rainfall) as rainfall, AVG(co2) as co2, AVG(altitude) as altitude, AVG(wind_direction) as wind_direction", period)
+	q := func(column, field string) string { return qualityColumn(column, field, includeBad) }
+	speed, dir := q("wind_speed", "windSpeed"), q("wind_direction", "windDirection")
+
+	// rainfall is a per-reading delta, so it is summed rather than averaged
+	querySelect := fmt.Sprintf("date_trunc('%s', timestamp) as timestamp, AVG(%s) as temperature, AVG(%s) as humidity, AVG(%s) as pressure, AVG(%s) as wind_speed, COALESCE(SUM(%s), 0) as rainfall, AVG(%s) as co2, AVG(%s) as altitude",
+		period,
+		q("temperature", "temperature"), q("humidity", "humidity"), q("pressure", "pressure"), speed,
+		q("rainfall", "rainfall"), q("co2", "co2"), q("altitude", "altitude"))
+	// wind direction is circular (350° and 10° average to 0°, not 180°), so sum
+	// the speed-weighted unit vectors and take the angle of the result
+	querySelect += fmt.Sprintf(", COALESCE(DEGREES(ATAN2(SUM(%[1]s * SIN(RADIANS(%[2]s))), SUM(%[1]s * COS(RADIANS(%[2]s))))), 0) as wind_direction, COALESCE(MAX(%[1]s), 0) as wind_gust", speed, dir)
+	querySelect += fmt.Sprintf(", AVG(%[1]s) as water_level, MAX(%[1]s) as max_water_level", q("water_level", "waterLevel"))
 	queryGroup := fmt.Sprintf("date_trunc('%s', timestamp)", period)
 
 	err := r.db.Model(&entity.SensorData{}).
+		Scopes(scopeStation(stationID)).
 		Select(querySelect).
 		Where("timestamp >= ? AND timestamp <= ?", start, end).
 		Group(queryGroup).
 		Order("timestamp desc").
 		Scan(&results).Error
 
+	// ATAN2 yields -180..180
+	for i := range results {
+		if results[i].WindDirection < 0 {
+			results[i].WindDirection += 360
+		}
+	}
+
 	return results, err
 }
 
-func (r *dataModel) GetDataInsights() (*entity.DataInsights, error) {
+func (r *dataModel) GetDataInsights(stationID uint, includeBad bool) (*entity.DataInsights, error) {
 	var insights entity.DataInsights
+	temp := qualityColumn("temperature", "temperature", includeBad)
+	hum := qualityColumn("humidity", "humidity", includeBad)
 	now := time.Now()
 	startOfMonth := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
 	startOfPrevMonth := startOfMonth.AddDate(0, -1, 0)
@@ -113,7 +261,10 @@ func (r *dataModel) GetDataInsights() (*entity.DataInsights, error) {
 	var currentStats Stats
 
 	err := r.db.Model(&entity.SensorData{}).
-		Select("MIN(temperature) as min_temp, MAX(temperature) as max_temp, AVG(temperature) as avg_temp, MIN(humidity) as min_hum, MAX(humidity) as max_hum, AVG(humidity) as avg_hum").
+		Scopes(scopeStation(stationID)).
+		// every value filtered out leaves NULL aggregates, reported as 0
+		Select(fmt.Sprintf("COALESCE(MIN(%[1]s), 0) as min_temp, COALESCE(MAX(%[1]s), 0) as max_temp, COALESCE(AVG(%[1]s), 0) as avg_temp, "+
+			"COALESCE(MIN(%[2]s), 0) as min_hum, COALESCE(MAX(%[2]s), 0) as max_hum, COALESCE(AVG(%[2]s), 0) as avg_hum", temp, hum)).
 		Where("timestamp >= ?", startOfMonth).
 		Scan(&currentStats).Error
 
@@ -131,7 +282,8 @@ func (r *dataModel) GetDataInsights() (*entity.DataInsights, error) {
 	// previous month avg temp for comparison
 	var prevMonthAvgTemp float64
 	err = r.db.Model(&entity.SensorData{}).
-		Select("COALESCE(AVG(temperature), 0)").
+		Scopes(scopeStation(stationID)).
+		Select(fmt.Sprintf("COALESCE(AVG(%s), 0)", temp)).
 		Where("timestamp >= ? AND timestamp <= ?", startOfPrevMonth, endOfPrevMonth).
 		Scan(&prevMonthAvgTemp).Error
 	
@@ -146,10 +298,13 @@ func (r *dataModel) GetDataInsights() (*entity.DataInsights, error) {
 	var peakHour PeakHourStat
 	
 	err = r.db.Model(&entity.SensorData{}).
-		Select("EXTRACT(HOUR FROM timestamp) as hour, AVG(temperature) as avg_temp").
+		Scopes(scopeStation(stationID)).
+		Select(fmt.Sprintf("EXTRACT(HOUR FROM timestamp) as hour, AVG(%s) as avg_temp", temp)).
 		Where("timestamp >= ?", startOfMonth).
 		Group("EXTRACT(HOUR FROM timestamp)").
-		Order("avg_temp DESC").
+		// skip hours whose temperatures were all flagged bad
+		Having(fmt.Sprintf("AVG(%s) IS NOT NULL", temp)).
+		Order("avg_temp DESC NULLS LAST").
 		Limit(1).
 		Scan(&peakHour).Error
 
@@ -0,0 +1,78 @@
+package model
+
+import (
+	"EWSBE/internal/entity"
+	"EWSBE/internal/repository"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type deadLetterModel struct {
+	db *gorm.DB
+}
+
+func NewDeadLetterRepo(db *gorm.DB) repository.DeadLetterRepository {
+	return &deadLetterModel{db: db}
+}
+
+func (r *deadLetterModel) CreateDeadLetter(dl *entity.DeadLetter) error {
+	return r.db.Create(dl).Error
+}
+
+// GetDeadLetters lists dead letters newest first, without their payloads
+func (r *deadLetterModel) GetDeadLetters(filter entity.DeadLetterFilter) ([]entity.DeadLetter, int64, error) {
+	query := r.db.Model(&entity.DeadLetter{})
+	if filter.Topic != "" {
+		query = query.Where("topic = ?", filter.Topic)
+	}
+	if filter.Stage != "" {
+		query = query.Where("stage = ?", filter.Stage)
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.From != nil {
+		query = query.Where("received_at >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where("received_at <= ?", *filter.To)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var letters []entity.DeadLetter
+	if err := query.Omit("payload").
+		Order("received_at desc").
+		Limit(filter.Limit).
+		Offset(filter.Offset).
+		Find(&letters).Error; err != nil {
+		return nil, 0, err
+	}
+	return letters, total, nil
+}
+
+func (r *deadLetterModel) GetDeadLetterByID(id uint) (*entity.DeadLetter, error) {
+	var dl entity.DeadLetter
+	if err := r.db.First(&dl, id).Error; err != nil {
+		return nil, err
+	}
+	return &dl, nil
+}
+
+func (r *deadLetterModel) UpdateDeadLetter(dl *entity.DeadLetter) error {
+	return r.db.Save(dl).Error
+}
+
+func (r *deadLetterModel) DeleteDeadLetter(id uint) error {
+	return r.db.Delete(&entity.DeadLetter{}, id).Error
+}
+
+// DeleteDeadLetters removes dead letters received before the cutoff
+func (r *deadLetterModel) DeleteDeadLetters(before time.Time) (int64, error) {
+	res := r.db.Where("received_at < ?", before).Delete(&entity.DeadLetter{})
+	return res.RowsAffected, res.Error
+}
@@ -0,0 +1,54 @@
+package model
+
+import (
+	"EWSBE/internal/entity"
+	"EWSBE/internal/repository"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type deviceKeyModel struct {
+	db *gorm.DB
+}
+
+func NewDeviceKeyRepo(db *gorm.DB) repository.DeviceKeyRepository {
+	return &deviceKeyModel{db: db}
+}
+
+func (r *deviceKeyModel) CreateDeviceKey(key *entity.DeviceKey) error {
+	return r.db.Omit("Station").Create(key).Error
+}
+
+func (r *deviceKeyModel) GetDeviceKeys(stationID uint) ([]entity.DeviceKey, error) {
+	var keys []entity.DeviceKey
+	if err := r.db.Where("station_id = ?", stationID).Order("created_at desc").Find(&keys).Error; err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+func (r *deviceKeyModel) GetDeviceKeyByID(id uint) (*entity.DeviceKey, error) {
+	var key entity.DeviceKey
+	if err := r.db.First(&key, id).Error; err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+func (r *deviceKeyModel) GetDeviceKeyByHash(hash string) (*entity.DeviceKey, error) {
+	var key entity.DeviceKey
+	if err := r.db.Preload("Station").Where("hash = ?", hash).First(&key).Error; err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+func (r *deviceKeyModel) UpdateDeviceKey(key *entity.DeviceKey) error {
+	return r.db.Omit("Station").Save(key).Error
+}
+
+// TouchDeviceKey records a use of the key without touching other columns
+func (r *deviceKeyModel) TouchDeviceKey(id uint, at time.Time) error {
+	return r.db.Model(&entity.DeviceKey{}).Where("id = ?", id).Update("last_used_at", at).Error
+}
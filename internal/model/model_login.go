@@ -0,0 +1,100 @@
+package model
+
+import (
+	"EWSBE/internal/entity"
+	"EWSBE/internal/repository"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type loginModel struct {
+	db *gorm.DB
+}
+
+func NewLoginRepo(db *gorm.DB) repository.LoginRepository {
+	return &loginModel{db: db}
+}
+
+func (r *loginModel) CreateLoginAttempt(attempt *entity.LoginAttempt) error {
+	return r.db.Create(attempt).Error
+}
+
+func (r *loginModel) GetLoginAttempts(filter entity.LoginAttemptFilter) ([]entity.LoginAttempt, int64, error) {
+	query := r.db.Model(&entity.LoginAttempt{})
+	if filter.Username != "" {
+		query = query.Where("username = ?", filter.Username)
+	}
+	if filter.IP != "" {
+		query = query.Where("ip = ?", filter.IP)
+	}
+	if filter.Success != nil {
+		query = query.Where("success = ?", *filter.Success)
+	}
+	if filter.From != nil {
+		query = query.Where("created_at >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where("created_at <= ?", *filter.To)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var attempts []entity.LoginAttempt
+	if err := query.Order("created_at desc").
+		Limit(filter.Limit).
+		Offset(filter.Offset).
+		Find(&attempts).Error; err != nil {
+		return nil, 0, err
+	}
+	return attempts, total, nil
+}
+
+func (r *loginModel) DeleteLoginAttempts(before time.Time) (int64, error) {
+	res := r.db.Where("created_at < ?", before).Delete(&entity.LoginAttempt{})
+	return res.RowsAffected, res.Error
+}
+
+func (r *loginModel) GetLockout(kind, key string) (*entity.LoginLockout, error) {
+	var lockout entity.LoginLockout
+	if err := r.db.Where("kind = ? AND key = ?", kind, key).First(&lockout).Error; err != nil {
+		return nil, err
+	}
+	return &lockout, nil
+}
+
+func (r *loginModel) GetLockoutByID(id uint) (*entity.LoginLockout, error) {
+	var lockout entity.LoginLockout
+	if err := r.db.First(&lockout, id).Error; err != nil {
+		return nil, err
+	}
+	return &lockout, nil
+}
+
+// GetBlockedLockouts returns usernames and IPs that are refused at time now
+func (r *loginModel) GetBlockedLockouts(now time.Time) ([]entity.LoginLockout, error) {
+	var lockouts []entity.LoginLockout
+	if err := r.db.Where("blocked_until > ?", now).Order("blocked_until desc").Find(&lockouts).Error; err != nil {
+		return nil, err
+	}
+	return lockouts, nil
+}
+
+func (r *loginModel) SaveLockout(lockout *entity.LoginLockout) error {
+	return r.db.Save(lockout).Error
+}
+
+func (r *loginModel) DeleteLockout(id uint) error {
+	return r.db.Delete(&entity.LoginLockout{}, id).Error
+}
+
+// DeleteStaleLockouts removes counters with no failure since before that no
+// longer block at time now
+func (r *loginModel) DeleteStaleLockouts(before, now time.Time) (int64, error) {
+	res := r.db.Where("last_failure_at < ? AND (blocked_until IS NULL OR blocked_until < ?)", before, now).
+		Delete(&entity.LoginLockout{})
+	return res.RowsAffected, res.Error
+}
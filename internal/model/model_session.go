@@ -0,0 +1,80 @@
+package model
+
+import (
+	"EWSBE/internal/entity"
+	"EWSBE/internal/repository"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type sessionModel struct {
+	db *gorm.DB
+}
+
+func NewSessionRepo(db *gorm.DB) repository.SessionRepository {
+	return &sessionModel{db: db}
+}
+
+func (r *sessionModel) CreateSession(session *entity.Session) error {
+	return r.db.Create(session).Error
+}
+
+func (r *sessionModel) GetSessionByFamilyID(familyID string) (*entity.Session, error) {
+	var session entity.Session
+	if err := r.db.Where("family_id = ?", familyID).First(&session).Error; err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+func (r *sessionModel) UpdateSession(session *entity.Session) error {
+	return r.db.Save(session).Error
+}
+
+// RevokeUserSessions revokes every session of a user that is still active
+func (r *sessionModel) RevokeUserSessions(userID uint, at time.Time, reason string) error {
+	return r.db.Model(&entity.Session{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Updates(map[string]interface{}{"revoked_at": at, "revoked_reason": reason}).Error
+}
+
+func (r *sessionModel) CreateRefreshToken(token *entity.RefreshToken) error {
+	return r.db.Omit("Session").Create(token).Error
+}
+
+func (r *sessionModel) GetRefreshTokenByHash(hash string) (*entity.RefreshToken, error) {
+	var token entity.RefreshToken
+	if err := r.db.Preload("Session").Where("token_hash = ?", hash).First(&token).Error; err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// UseRefreshToken marks an unused token as used and reports whether this call did
+func (r *sessionModel) UseRefreshToken(id uint, at time.Time) (bool, error) {
+	res := r.db.Model(&entity.RefreshToken{}).Where("id = ? AND used_at IS NULL", id).Update("used_at", at)
+	return res.RowsAffected == 1, res.Error
+}
+
+func (r *sessionModel) DeleteExpiredRefreshTokens(before time.Time) (int64, error) {
+	res := r.db.Where("expires_at < ?", before).Delete(&entity.RefreshToken{})
+	return res.RowsAffected, res.Error
+}
+
+// DeleteStaleSessions deletes sessions revoked before the cutoff or not
+// refreshed since, together with their remaining refresh tokens
+func (r *sessionModel) DeleteStaleSessions(before time.Time) (int64, error) {
+	var deleted int64
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		stale := tx.Model(&entity.Session{}).Select("id").
+			Where("revoked_at < ? OR COALESCE(last_refresh_at, created_at) < ?", before, before)
+		if err := tx.Where("session_id IN (?)", stale).Delete(&entity.RefreshToken{}).Error; err != nil {
+			return err
+		}
+		res := tx.Where("revoked_at < ? OR COALESCE(last_refresh_at, created_at) < ?", before, before).Delete(&entity.Session{})
+		deleted = res.RowsAffected
+		return res.Error
+	})
+	return deleted, err
+}
@@ -0,0 +1,56 @@
+package model
+
+import (
+	"EWSBE/internal/entity"
+	"EWSBE/internal/repository"
+
+	"gorm.io/gorm"
+)
+
+type stationModel struct {
+	db *gorm.DB
+}
+
+func NewStationRepo(db *gorm.DB) repository.StationRepository {
+	return &stationModel{db: db}
+}
+
+func (r *stationModel) CreateStation(station *entity.Station) error {
+	return r.db.Create(station).Error
+}
+
+func (r *stationModel) GetAllStations() ([]entity.Station, error) {
+	var stations []entity.Station
+	if err := r.db.Order("code asc").Find(&stations).Error; err != nil {
+		return nil, err
+	}
+	return stations, nil
+}
+
+func (r *stationModel) GetStationByID(id uint) (*entity.Station, error) {
+	var station entity.Station
+	if err := r.db.First(&station, id).Error; err != nil {
+		return nil, err
+	}
+	return &station, nil
+}
+
+func (r *stationModel) GetStationByCode(code string) (*entity.Station, error) {
+	var station entity.Station
+	if err := r.db.Where("code = ?", code).First(&station).Error; err != nil {
+		return nil, err
+	}
+	return &station, nil
+}
+
+// heartbeat columns are owned by the station monitor
+var heartbeatColumns = []string{"last_seen_at", "status", "status_changed_at", "gap_count"}
+
+func (r *stationModel) UpdateStation(station *entity.Station) error {
+	return r.db.Omit(heartbeatColumns...).Save(station).Error
+}
+
+func (r *stationModel) UpdateHeartbeat(station *entity.Station) error {
+	return r.db.Model(&entity.Station{}).Where("id = ?", station.ID).
+		Select(heartbeatColumns).Updates(station).Error
+}
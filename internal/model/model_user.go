@@ -3,6 +3,7 @@ package model
 import (
 	"EWSBE/internal/entity"
 	"EWSBE/internal/repository"
+	"time"
 
 	"gorm.io/gorm"
 )
@@ -26,3 +27,82 @@ func (r *userModel) GetUserByUsername(username string) (*entity.User, error) {
 	}
 	return &user, nil
 }
+
+func (r *userModel) GetUserByID(id uint) (*entity.User, error) {
+	var user entity.User
+	if err := r.db.First(&user, id).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (r *userModel) GetAllUsers() ([]entity.User, error) {
+	var users []entity.User
+	if err := r.db.Order("username").Find(&users).Error; err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+func (r *userModel) UpdateUser(user *entity.User) error {
+	return r.db.Save(user).Error
+}
+
+func (r *userModel) DeleteUser(id uint) error {
+	return r.db.Delete(&entity.User{}, id).Error
+}
+
+func (r *userModel) CountUsers() (int64, error) {
+	var count int64
+	err := r.db.Model(&entity.User{}).Count(&count).Error
+	return count, err
+}
+
+// CountActiveUsersByRole counts users with the role that are not disabled
+func (r *userModel) CountActiveUsersByRole(role string) (int64, error) {
+	var count int64
+	err := r.db.Model(&entity.User{}).Where("role = ? AND disabled_at IS NULL", role).Count(&count).Error
+	return count, err
+}
+
+func (r *userModel) CreateInvite(invite *entity.Invite) error {
+	return r.db.Create(invite).Error
+}
+
+func (r *userModel) GetInvites() ([]entity.Invite, error) {
+	var invites []entity.Invite
+	if err := r.db.Order("created_at desc").Find(&invites).Error; err != nil {
+		return nil, err
+	}
+	return invites, nil
+}
+
+func (r *userModel) GetInviteByID(id uint) (*entity.Invite, error) {
+	var invite entity.Invite
+	if err := r.db.First(&invite, id).Error; err != nil {
+		return nil, err
+	}
+	return &invite, nil
+}
+
+func (r *userModel) GetInviteByHash(hash string) (*entity.Invite, error) {
+	var invite entity.Invite
+	if err := r.db.Where("code_hash = ?", hash).First(&invite).Error; err != nil {
+		return nil, err
+	}
+	return &invite, nil
+}
+
+// ClaimInvite marks an unused invite as used and reports whether this call did
+func (r *userModel) ClaimInvite(id uint, at time.Time) (bool, error) {
+	res := r.db.Model(&entity.Invite{}).Where("id = ? AND used_at IS NULL", id).Update("used_at", at)
+	return res.RowsAffected == 1, res.Error
+}
+
+func (r *userModel) UpdateInvite(invite *entity.Invite) error {
+	return r.db.Save(invite).Error
+}
+
+func (r *userModel) DeleteInvite(id uint) error {
+	return r.db.Delete(&entity.Invite{}, id).Error
+}
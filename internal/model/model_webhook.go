@@ -0,0 +1,61 @@
+package model
+
+import (
+	"EWSBE/internal/entity"
+	"EWSBE/internal/repository"
+
+	"gorm.io/gorm"
+)
+
+type webhookModel struct {
+	db *gorm.DB
+}
+
+func NewWebhookRepo(db *gorm.DB) repository.WebhookRepository {
+	return &webhookModel{db: db}
+}
+
+func (r *webhookModel) CreateWebhook(hook *entity.Webhook) error {
+	return r.db.Create(hook).Error
+}
+
+func (r *webhookModel) GetAllWebhooks() ([]entity.Webhook, error) {
+	var hooks []entity.Webhook
+	if err := r.db.Order("id asc").Find(&hooks).Error; err != nil {
+		return nil, err
+	}
+	return hooks, nil
+}
+
+func (r *webhookModel) GetWebhookByID(id uint) (*entity.Webhook, error) {
+	var hook entity.Webhook
+	if err := r.db.First(&hook, id).Error; err != nil {
+		return nil, err
+	}
+	return &hook, nil
+}
+
+func (r *webhookModel) UpdateWebhook(hook *entity.Webhook) error {
+	return r.db.Save(hook).Error
+}
+
+func (r *webhookModel) DeleteWebhook(id uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("webhook_id = ?", id).Delete(&entity.WebhookDelivery{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&entity.Webhook{}, id).Error
+	})
+}
+
+func (r *webhookModel) CreateDelivery(delivery *entity.WebhookDelivery) error {
+	return r.db.Create(delivery).Error
+}
+
+func (r *webhookModel) GetDeliveries(webhookID uint, limit int) ([]entity.WebhookDelivery, error) {
+	var deliveries []entity.WebhookDelivery
+	if err := r.db.Where("webhook_id = ?", webhookID).Order("id desc").Limit(limit).Find(&deliveries).Error; err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
@@ -0,0 +1,294 @@
+package mqtt
+
+import (
+	"EWSBE/internal/config"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	paho "github.com/eclipse/paho.mqtt.golang"
+)
+
+// connection states reported by Status
+const (
+	StateConnecting   = "connecting"
+	StateConnected    = "connected"
+	StateReconnecting = "reconnecting"
+	StateDisconnected = "disconnected"
+)
+
+type ConnectionStatus struct {
+	State            string     `json:"state"`
+	Broker           string     `json:"broker"`
+	ClientID         string     `json:"clientId"`
+	TLS              bool       `json:"tls"`
+	CleanSession     bool       `json:"cleanSession"`
+	QoS              byte       `json:"qos"`
+	ConnectedSince   *time.Time `json:"connectedSince"`
+	LastDisconnectAt *time.Time `json:"lastDisconnectAt"`
+	LastError        string     `json:"lastError,omitempty"`
+	Reconnects       int        `json:"reconnects"`
+	Subscriptions    []string   `json:"subscriptions"`
+}
+
+type subscription struct {
+	qos     byte
+	handler paho.MessageHandler
+}
+
+// Connection wraps the paho client: it keeps retrying the first connect
+// with exponential backoff, lets paho reconnect after a drop and restores
+// all subscriptions on every (re)connect
+type Connection struct {
+	client paho.Client
+	cfg    config.MQTTConfig
+
+	mu     sync.Mutex
+	subs   map[string]subscription
+	status ConnectionStatus
+	closed chan struct{}
+}
+
+// Connect starts connecting in the background and returns immediately;
+// subscriptions made before the connection is up are applied once it is.
+// It only fails on invalid TLS settings.
+func Connect(cfg config.MQTTConfig) (*Connection, error) {
+	c := &Connection{
+		cfg:    cfg,
+		subs:   make(map[string]subscription),
+		closed: make(chan struct{}),
+		status: ConnectionStatus{
+			State:        StateConnecting,
+			Broker:       cfg.Broker,
+			ClientID:     cfg.ClientID,
+			CleanSession: cfg.CleanSession,
+			QoS:          cfg.QoS,
+		},
+	}
+
+	if !cfg.CleanSession && cfg.ClientID == "" {
+		log.Println("mqtt: persistent session requested without MQTT_CLIENT_ID, the broker cannot resume it")
+	}
+
+	opts := paho.NewClientOptions()
+	opts.AddBroker(cfg.Broker)
+	opts.SetClientID(cfg.ClientID)
+	if cfg.Username != "" {
+		opts.SetUsername(cfg.Username)
+		opts.SetPassword(cfg.Password)
+	}
+
+	secure := usesTLS(cfg.Broker)
+	if secure {
+		tlsCfg, err := newTLSConfig(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("mqtt tls: %w", err)
+		}
+		opts.SetTLSConfig(tlsCfg)
+		c.status.TLS = true
+	} else {
+		if cfg.CAFile != "" || cfg.CertFile != "" {
+			log.Printf("mqtt: TLS files are set but %s is not an ssl:// broker, connecting without TLS", cfg.Broker)
+		}
+		if cfg.Password != "" {
+			log.Printf("mqtt: sending credentials to %s without TLS", cfg.Broker)
+		}
+	}
+	opts.SetCleanSession(cfg.CleanSession)
+	opts.SetResumeSubs(!cfg.CleanSession)
+	opts.SetKeepAlive(cfg.KeepAlive)
+	opts.SetConnectTimeout(cfg.ConnectTimeout)
+	opts.SetAutoReconnect(true) // paho backs off exponentially up to MaxReconnectInterval
+	opts.SetMaxReconnectInterval(cfg.MaxReconnectInterval)
+	opts.SetOnConnectHandler(c.onConnect)
+	opts.SetConnectionLostHandler(c.onConnectionLost)
+	opts.SetReconnectingHandler(func(paho.Client, *paho.ClientOptions) {
+		c.mu.Lock()
+		c.status.State = StateReconnecting
+		c.mu.Unlock()
+	})
+
+	c.client = paho.NewClient(opts)
+	go c.connectLoop()
+	return c, nil
+}
+
+// connectLoop retries the initial connection until it succeeds or the
+// connection is closed
+func (c *Connection) connectLoop() {
+	delay := c.cfg.InitialRetryDelay
+	if delay <= 0 {
+		delay = time.Second
+	}
+
+	for {
+		token := c.client.Connect()
+		token.Wait()
+		if token.Error() == nil {
+			return
+		}
+
+		c.mu.Lock()
+		c.status.LastError = token.Error().Error()
+		c.mu.Unlock()
+		log.Printf("mqtt: connect to %s failed: %v (retrying in %s)", c.cfg.Broker, token.Error(), delay)
+
+		select {
+		case <-c.closed:
+			return
+		case <-time.After(delay):
+		}
+
+		delay *= 2
+		if c.cfg.MaxReconnectInterval > 0 && delay > c.cfg.MaxReconnectInterval {
+			delay = c.cfg.MaxReconnectInterval
+		}
+	}
+}
+
+func (c *Connection) onConnect(client paho.Client) {
+	now := time.Now()
+
+	c.mu.Lock()
+	if c.status.ConnectedSince != nil || c.status.LastDisconnectAt != nil {
+		c.status.Reconnects++
+	}
+	c.status.State = StateConnected
+	c.status.ConnectedSince = &now
+	c.status.LastError = ""
+	subs := make(map[string]subscription, len(c.subs))
+	for topic, s := range c.subs {
+		subs[topic] = s
+	}
+	c.mu.Unlock()
+
+	log.Printf("mqtt: connected to %s", c.cfg.Broker)
+
+	// the broker may have lost our session, so always subscribe again
+	for topic, s := range subs {
+		c.subscribe(client, topic, s)
+	}
+}
+
+func (c *Connection) onConnectionLost(_ paho.Client, err error) {
+	now := time.Now()
+
+	c.mu.Lock()
+	c.status.State = StateReconnecting
+	c.status.ConnectedSince = nil
+	c.status.LastDisconnectAt = &now
+	if err != nil {
+		c.status.LastError = err.Error()
+	}
+	c.mu.Unlock()
+
+	log.Printf("mqtt: connection to %s lost: %v", c.cfg.Broker, err)
+}
+
+// subscribe runs in paho callbacks, so it must not wait on the token
+func (c *Connection) subscribe(client paho.Client, topic string, s subscription) {
+	token := client.Subscribe(topic, s.qos, s.handler)
+	go func() {
+		if !token.WaitTimeout(c.cfg.ConnectTimeout) {
+			log.Printf("mqtt: subscribe to %s timed out", topic)
+			return
+		}
+		if err := token.Error(); err != nil {
+			log.Printf("mqtt subscribe error: %s: %v", topic, err)
+			return
+		}
+		log.Printf("mqtt subscribed to topic: %s (qos %d)", topic, s.qos)
+	}()
+}
+
+// Subscribe registers a handler for topic. It is applied now if connected
+// and again after every reconnect.
+func (c *Connection) Subscribe(topic string, qos byte, handler paho.MessageHandler) error {
+	if qos > 2 {
+		return errors.New("qos must be 0, 1 or 2")
+	}
+
+	s := subscription{qos: qos, handler: handler}
+	c.mu.Lock()
+	c.subs[topic] = s
+	c.mu.Unlock()
+
+	// route messages the broker replays from a persistent session before
+	// the subscription is renewed
+	c.client.AddRoute(topic, handler)
+
+	if c.client.IsConnectionOpen() {
+		c.subscribe(c.client, topic, s)
+	}
+	return nil
+}
+
+// Publish sends a message, failing fast while disconnected
+func (c *Connection) Publish(topic string, qos byte, retained bool, payload interface{}) error {
+	if !c.client.IsConnectionOpen() {
+		return errors.New("mqtt client not connected")
+	}
+
+	token := c.client.Publish(topic, qos, retained, payload)
+	if !token.WaitTimeout(c.cfg.ConnectTimeout) {
+		return errors.New("mqtt publish timed out")
+	}
+	return token.Error()
+}
+
+// Unsubscribe stops delivery for all registered topics. A persistent
+// session keeps its subscriptions on the broker so messages published while
+// we are down are queued for the next start; call Disconnect before stopping
+// the ingestor so nothing arriving in between is acked and then dropped.
+func (c *Connection) Unsubscribe(timeout time.Duration) {
+	c.mu.Lock()
+	topics := make([]string, 0, len(c.subs))
+	for topic := range c.subs {
+		topics = append(topics, topic)
+	}
+	c.subs = make(map[string]subscription)
+	c.mu.Unlock()
+
+	if len(topics) == 0 || !c.cfg.CleanSession || !c.client.IsConnectionOpen() {
+		return
+	}
+	c.client.Unsubscribe(topics...).WaitTimeout(timeout)
+}
+
+// Disconnect closes the connection and stops any pending connect attempts
+func (c *Connection) Disconnect(quiesce uint) {
+	c.mu.Lock()
+	select {
+	case <-c.closed:
+	default:
+		close(c.closed)
+	}
+	c.status.State = StateDisconnected
+	c.status.ConnectedSince = nil
+	c.mu.Unlock()
+
+	if c.client.IsConnected() {
+		c.client.Disconnect(quiesce)
+		log.Println("MQTT client disconnected")
+	}
+}
+
+func (c *Connection) IsConnected() bool {
+	return c.client.IsConnectionOpen()
+}
+
+func (c *Connection) Status() ConnectionStatus {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	status := c.status
+	status.Subscriptions = make([]string, 0, len(c.subs))
+	for topic := range c.subs {
+		status.Subscriptions = append(status.Subscriptions, topic)
+	}
+	sort.Strings(status.Subscriptions)
+	return status
+}
@@ -0,0 +1,39 @@
+package mqtt
+
+import (
+	"EWSBE/internal/entity"
+	"EWSBE/internal/usecase"
+	"encoding/json"
+	"errors"
+	"log"
+	"strings"
+
+	paho "github.com/eclipse/paho.mqtt.golang"
+)
+
+// SubscribeCommandAcks subscribes to the acknowledgement topic of every
+// station. ackTopic uses the "{station}" placeholder, e.g.
+// "ewsbe/{station}/ack"; the segment is matched by a '+' wildcard and must
+// be the station the command was sent to.
+func SubscribeCommandAcks(conn *Connection, ackTopic string, qos byte, commands *usecase.CommandUsecase) error {
+	if conn == nil {
+		return errors.New("mqtt client not configured")
+	}
+	if !strings.Contains(ackTopic, "{station}") {
+		return errors.New("ack topic needs a {station} segment")
+	}
+	pattern := strings.ReplaceAll(ackTopic, "{station}", "+")
+
+	return conn.Subscribe(pattern, qos, func(_ paho.Client, msg paho.Message) {
+		var ack entity.CommandAck
+		if err := json.Unmarshal(msg.Payload(), &ack); err != nil {
+			log.Printf("mqtt: unmarshal ack error: %v", err)
+			return
+		}
+
+		code := StationCodeFromTopic(pattern, msg.Topic())
+		if err := commands.HandleAck(code, ack); err != nil {
+			log.Printf("mqtt: ack on %s: %v", msg.Topic(), err)
+		}
+	})
+}
@@ -0,0 +1,285 @@
+package mqtt
+
+import (
+	"EWSBE/internal/entity"
+	"EWSBE/internal/usecase"
+	ws "EWSBE/internal/websocket"
+	"context"
+	"encoding/binary"
+	"errors"
+	"hash/fnv"
+	"log"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type IngestConfig struct {
+	QueueSize     int           // total across the worker queues
+	Workers       int           // each worker owns the readings of a subset of stations
+	BatchSize     int           // readings per insert
+	FlushInterval time.Duration // a partial batch is written after this long
+	EnqueueWait   time.Duration // how long a full queue may block the MQTT callback before dropping
+}
+
+// counters reported by the health endpoint
+type IngestStats struct {
+	Received      uint64 `json:"received"`
+	Stored        uint64 `json:"stored"`
+	Dropped       uint64 `json:"dropped"`    // queue full or shutting down
+	Failed        uint64 `json:"failed"`     // rejected by the database, kept as dead letters
+	Duplicates    uint64 `json:"duplicates"` // redeliveries of stored readings, not inserted again
+	Batches       uint64 `json:"batches"`
+	QueueLength   int    `json:"queueLength"`
+	QueueCapacity int    `json:"queueCapacity"`
+}
+
+// Ingestor decouples MQTT delivery from the database: messages are queued
+// and a worker pool writes them in batches, broadcasting after commit. Every
+// worker has its own queue and a station always maps to the same one, so
+// readings of one station are committed in the order they arrived.
+type Ingestor struct {
+	uc       *usecase.DataUsecase
+	hub      *ws.Hub
+	pipeline *Pipeline // dead-letters readings the database rejects
+	cfg      IngestConfig
+
+	queues  []chan ingestItem // one per worker, chosen by station
+	workers sync.WaitGroup
+
+	mu      sync.RWMutex // guards stopped against sends on the closed queues
+	stopped bool
+
+	received, stored, dropped, failed, duplicates, batches atomic.Uint64
+	lastDropLog                                            atomic.Int64
+}
+
+// queued reading with the message it was decoded from
+type ingestItem struct {
+	data entity.SensorData
+	msg  *Message
+}
+
+func NewIngestor(uc *usecase.DataUsecase, hub *ws.Hub, pipeline *Pipeline, cfg IngestConfig) *Ingestor {
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = 1000
+	}
+	if cfg.Workers <= 0 {
+		cfg.Workers = 2
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 100
+	}
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = time.Second
+	}
+	if cfg.EnqueueWait < 0 {
+		cfg.EnqueueWait = 0
+	}
+
+	size := (cfg.QueueSize + cfg.Workers - 1) / cfg.Workers
+	queues := make([]chan ingestItem, cfg.Workers)
+	for i := range queues {
+		queues[i] = make(chan ingestItem, size)
+	}
+
+	return &Ingestor{
+		uc:       uc,
+		hub:      hub,
+		pipeline: pipeline,
+		cfg:      cfg,
+		queues:   queues,
+	}
+}
+
+func (in *Ingestor) Start() {
+	for _, queue := range in.queues {
+		in.workers.Add(1)
+		go in.work(queue)
+	}
+}
+
+// queueFor picks the worker queue of a reading's station; readings without
+// a station share the first queue
+func (in *Ingestor) queueFor(d *entity.SensorData) chan ingestItem {
+	if d.StationID == nil {
+		return in.queues[0]
+	}
+	h := fnv.New32a()
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], uint64(*d.StationID))
+	h.Write(buf[:])
+	return in.queues[h.Sum32()%uint32(len(in.queues))]
+}
+
+// Submit queues a reading and the message it came from, if any. When the
+// queue is full it blocks for up to EnqueueWait, which slows the MQTT client
+// down, and then drops the reading.
+func (in *Ingestor) Submit(d entity.SensorData, msg *Message) bool {
+	item := ingestItem{data: d, msg: msg}
+	in.received.Add(1)
+
+	in.mu.RLock()
+	defer in.mu.RUnlock()
+	if in.stopped {
+		in.drop("shutting down")
+		return false
+	}
+
+	queue := in.queueFor(&d)
+	select {
+	case queue <- item:
+		return true
+	default:
+	}
+
+	if in.cfg.EnqueueWait > 0 {
+		timer := time.NewTimer(in.cfg.EnqueueWait)
+		defer timer.Stop()
+		select {
+		case queue <- item:
+			return true
+		case <-timer.C:
+		}
+	}
+
+	in.drop("queue full")
+	return false
+}
+
+// Stop refuses new readings and waits until the queue has been written or
+// ctx expires
+func (in *Ingestor) Stop(ctx context.Context) {
+	in.mu.Lock()
+	if in.stopped {
+		in.mu.Unlock()
+		return
+	}
+	in.stopped = true
+	for _, queue := range in.queues {
+		close(queue)
+	}
+	in.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		in.workers.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		log.Printf("mqtt: ingestion drained (%d stored, %d dropped)", in.stored.Load(), in.dropped.Load())
+	case <-ctx.Done():
+		log.Printf("mqtt: shutdown with %d readings still queued", in.queued())
+	}
+}
+
+func (in *Ingestor) Stats() IngestStats {
+	return IngestStats{
+		Received:      in.received.Load(),
+		Stored:        in.stored.Load(),
+		Dropped:       in.dropped.Load(),
+		Failed:        in.failed.Load(),
+		Duplicates:    in.duplicates.Load(),
+		Batches:       in.batches.Load(),
+		QueueLength:   in.queued(),
+		QueueCapacity: cap(in.queues[0]) * len(in.queues),
+	}
+}
+
+func (in *Ingestor) queued() int {
+	n := 0
+	for _, queue := range in.queues {
+		n += len(queue)
+	}
+	return n
+}
+
+func (in *Ingestor) work(queue <-chan ingestItem) {
+	defer in.workers.Done()
+
+	batch := make([]ingestItem, 0, in.cfg.BatchSize)
+	ticker := time.NewTicker(in.cfg.FlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case d, ok := <-queue:
+			if !ok {
+				in.flush(batch)
+				return
+			}
+			batch = append(batch, d)
+			if len(batch) >= in.cfg.BatchSize {
+				in.flush(batch)
+				batch = make([]ingestItem, 0, in.cfg.BatchSize)
+			}
+		case <-ticker.C:
+			if len(batch) > 0 {
+				in.flush(batch)
+				batch = make([]ingestItem, 0, in.cfg.BatchSize)
+			}
+		}
+	}
+}
+
+func (in *Ingestor) flush(batch []ingestItem) {
+	if len(batch) == 0 {
+		return
+	}
+
+	// CreateBatch sorts by timestamp; sorting first keeps errs aligned with batch
+	sort.SliceStable(batch, func(i, j int) bool { return batch[i].data.Timestamp.Before(batch[j].data.Timestamp) })
+	data := make([]entity.SensorData, len(batch))
+	for i := range batch {
+		data[i] = batch[i].data
+	}
+
+	stored, errs := in.uc.CreateBatch(data, in.cfg.BatchSize)
+	in.batches.Add(1)
+	in.stored.Add(uint64(len(stored)))
+
+	var failed, duplicates int
+	for i, err := range errs {
+		switch {
+		case err == nil:
+		case errors.Is(err, usecase.ErrDuplicateReading):
+			duplicates++
+		default:
+			failed++
+			if batch[i].msg != nil && in.pipeline != nil {
+				in.pipeline.Reject(*batch[i].msg, entity.DeadLetterStageStore, err)
+			}
+		}
+	}
+	in.duplicates.Add(uint64(duplicates))
+	in.failed.Add(uint64(failed))
+	if failed > 0 {
+		log.Printf("mqtt: %d of %d readings not saved", failed, len(batch))
+	}
+	if len(stored) == 0 {
+		return
+	}
+	log.Printf("mqtt: saved %d readings", len(stored))
+
+	// broadcast to WebSocket clients only once the rows are committed
+	if in.hub != nil {
+		for i := range stored {
+			if err := in.hub.BroadcastSensorData(&stored[i]); err != nil {
+				log.Printf("mqtt: failed to broadcast sensor data: %v", err)
+			}
+		}
+	}
+}
+
+// drop counts a lost reading, logging at most every 10 seconds
+func (in *Ingestor) drop(reason string) {
+	n := in.dropped.Add(1)
+	now := time.Now().Unix()
+	last := in.lastDropLog.Load()
+	if now-last >= 10 && in.lastDropLog.CompareAndSwap(last, now) {
+		log.Printf("mqtt: dropping readings (%s), %d dropped so far", reason, n)
+	}
+}
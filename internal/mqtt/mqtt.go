@@ -3,62 +3,149 @@ package mqtt
 import (
 	"EWSBE/internal/entity"
 	"EWSBE/internal/usecase"
-	ws "EWSBE/internal/websocket"
-	"encoding/json"
 	"errors"
+	"fmt"
 	"log"
+	"strings"
 	"time"
 
 	paho "github.com/eclipse/paho.mqtt.golang"
 )
 
-func Connect(broker, clientID string) (paho.Client, error) {
-	opts := paho.NewClientOptions()
-	opts.AddBroker(broker)
-	opts.SetClientID(clientID)
-	opts.SetCleanSession(true)
-	opts.SetConnectTimeout(30 * time.Second)
+// StationCodeFromTopic returns the topic segment matched by the first '+'
+// wildcard of pattern (or the first segment under '#'), e.g. pattern
+// "sensors/ewsbe/+" and topic "sensors/ewsbe/mantap2" yield "mantap2".
+// An empty string means the pattern carries no station segment.
+func StationCodeFromTopic(pattern, topic string) string {
+	patternParts := strings.Split(pattern, "/")
+	topicParts := strings.Split(topic, "/")
 
-	client := paho.NewClient(opts)
-	if token := client.Connect(); token.Wait() && token.Error() != nil {
-		return nil, token.Error()
+	for i, p := range patternParts {
+		if i >= len(topicParts) {
+			return ""
+		}
+		if p == "+" || p == "#" {
+			return topicParts[i]
+		}
+	}
+	return ""
+}
+
+// ingestion settings for sensor topics
+type SensorTopicConfig struct {
+	DefaultStation    string                   // station code for topics without a wildcard segment
+	TimestampStrategy string                   // entity.TimestampDevice, TimestampServer or TimestampAuto
+	Decoders          *usecase.DecoderRegistry // nil uses the built-in decoders
+	AutoRegister      bool                     // create stations for unknown topic codes instead of rejecting them
+}
+
+// Message is a raw sensor message, kept with its reading until it is stored
+// so a rejected one can go to the dead-letter store
+type Message struct {
+	Topic      string
+	Pattern    string // subscription that received it
+	Payload    []byte
+	Decoder    string
+	ReceivedAt time.Time
+}
+
+// Pipeline turns raw sensor messages into readings: the payload is decoded
+// and the reading attributed to the station whose code matches the wildcard
+// segment of the topic, or to cfg.DefaultStation when the pattern has no
+// wildcard. Unknown codes other than the default station are rejected unless
+// cfg.AutoRegister is set, so an admin can create the station and replay.
+// Rejected messages are kept in the dead-letter store.
+type Pipeline struct {
+	data        *usecase.DataUsecase
+	stations    *usecase.StationUsecase
+	deadLetters *usecase.DeadLetterUsecase // nil only logs rejected messages
+	decoders    *usecase.DecoderRegistry
+	cfg         SensorTopicConfig
+}
+
+func NewPipeline(data *usecase.DataUsecase, stations *usecase.StationUsecase, deadLetters *usecase.DeadLetterUsecase, cfg SensorTopicConfig) *Pipeline {
+	decoders := cfg.Decoders
+	if decoders == nil {
+		decoders = usecase.NewDecoderRegistry()
 	}
-	return client, nil
+	return &Pipeline{data: data, stations: stations, deadLetters: deadLetters, decoders: decoders, cfg: cfg}
 }
 
-func SubscribeSensorTopic(client paho.Client, topic string, qos byte, uc *usecase.DataUsecase, hub *ws.Hub) error {
-	if client == nil || !client.IsConnected() {
-		return errors.New("mqtt client not connected")
+// reading decodes msg and resolves its station, returning the stage that
+// failed on error
+func (p *Pipeline) reading(msg *Message) (*entity.SensorData, string, error) {
+	// parse MQTT payload with the decoder for its topic, version or format
+	reading, decoder, err := p.decoders.Decode(msg.Topic, msg.Payload)
+	msg.Decoder = decoder
+	if err != nil {
+		return nil, entity.DeadLetterStageDecode, fmt.Errorf("%s decoder: %w", decoder, err)
 	}
 
-	token := client.Subscribe(topic, qos, func(_ paho.Client, msg paho.Message) {
-		// parse MQTT payload
-		var mqttPayload entity.MQTTSensorPayload
-		if err := json.Unmarshal(msg.Payload(), &mqttPayload); err != nil {
-			log.Printf("mqtt: unmarshal payload error: %v", err)
-			return
+	// convert to SensorData
+	sensorData := reading.ToSensorData(p.cfg.TimestampStrategy, msg.ReceivedAt)
+
+	// route to station
+	code := StationCodeFromTopic(msg.Pattern, msg.Topic)
+	if code == "" {
+		code = p.cfg.DefaultStation
+	}
+	if code != "" {
+		station, err := p.stations.Resolve(code, p.cfg.AutoRegister || code == p.cfg.DefaultStation)
+		if err != nil {
+			return nil, entity.DeadLetterStageStation, fmt.Errorf("resolve station %q: %w", code, err)
 		}
+		sensorData.StationID = &station.ID
+	}
+	return sensorData, "", nil
+}
 
-		// convert to SensorData
-		sensorData := mqttPayload.ToSensorData()
+// Reject logs a message the pipeline could not store and keeps it for replay
+func (p *Pipeline) Reject(msg Message, stage string, cause error) {
+	log.Printf("mqtt: rejected message on %s (%s): %v", msg.Topic, stage, cause)
+	if p.deadLetters == nil {
+		return
+	}
+	if err := p.deadLetters.Record(msg.Topic, msg.Pattern, msg.Payload, stage, msg.Decoder, cause, msg.ReceivedAt); err != nil {
+		log.Printf("mqtt: failed to store dead letter: %v", err)
+	}
+}
 
-		// save to database
-		if err := uc.Create(sensorData); err != nil {
-			log.Printf("mqtt: failed to save sensor data: %v", err)
-			return
+// Replay runs a dead letter through the pipeline again and backfills the
+// reading directly, without the ingestion queue. Like a CSV import it does
+// not count as a heartbeat or trigger alerts.
+func (p *Pipeline) Replay(dl entity.DeadLetter) (uint, error) {
+	msg := Message{Topic: dl.Topic, Pattern: dl.Pattern, Payload: dl.Payload, ReceivedAt: dl.ReceivedAt}
+	d, _, err := p.reading(&msg)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := p.data.Backfill(d); err != nil {
+		if errors.Is(err, usecase.ErrDuplicateReading) {
+			return d.ID, err
 		}
-		log.Printf("mqtt: saved sensor data from topic %s (temp: %.1f°C, hum: %.1f%%)", 
-			msg.Topic(), sensorData.Temperature, sensorData.Humidity)
-
-		// broadcast to WebSocket clients in real-time
-		if hub != nil {
-			if err := hub.BroadcastSensorData(sensorData); err != nil {
-				log.Printf("mqtt: failed to broadcast sensor data: %v", err)
-			} else {
-				log.Printf("mqtt: broadcasted to %d WebSocket clients", hub.ClientCount())
-			}
+		return 0, err
+	}
+	return d.ID, nil
+}
+
+// SubscribeSensorTopic subscribes to a sensor topic pattern. Readings go
+// through the pipeline and are handed to the ingestor for storage.
+func SubscribeSensorTopic(conn *Connection, topic string, qos byte, ingest *Ingestor, pipeline *Pipeline) error {
+	if conn == nil {
+		return errors.New("mqtt client not configured")
+	}
+
+	return conn.Subscribe(topic, qos, func(_ paho.Client, m paho.Message) {
+		msg := &Message{Topic: m.Topic(), Pattern: topic, Payload: m.Payload(), ReceivedAt: time.Now()}
+
+		sensorData, stage, err := pipeline.reading(msg)
+		if err != nil {
+			pipeline.Reject(*msg, stage, err)
+			return
 		}
+
+		// stored and broadcast by the ingestion workers
+		ingest.Submit(*sensorData, msg)
 	})
-	token.Wait()
-	return token.Error()
-}
\ No newline at end of file
+}
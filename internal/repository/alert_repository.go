@@ -0,0 +1,15 @@
+package repository
+
+import "EWSBE/internal/entity"
+
+type AlertRepository interface {
+	GetRules() ([]entity.AlertRule, error)
+	GetRuleByID(id uint) (*entity.AlertRule, error)
+	CreateRule(rule *entity.AlertRule) error
+	UpdateRule(rule *entity.AlertRule) error
+	GetOpenAlerts() ([]entity.Alert, error)
+	GetAlerts(filter entity.AlertFilter) ([]entity.Alert, int64, error)
+	GetAlertByID(id uint) (*entity.Alert, error)
+	CreateAlert(alert *entity.Alert) error
+	UpdateAlert(alert *entity.Alert) error
+}
@@ -0,0 +1,15 @@
+package repository
+
+import (
+	"EWSBE/internal/entity"
+	"time"
+)
+
+type CommandRepository interface {
+	CreateCommand(cmd *entity.StationCommand) error
+	UpdateCommand(cmd *entity.StationCommand) error
+	GetCommandByID(id uint) (*entity.StationCommand, error)
+	GetCommandByCommandID(commandID string) (*entity.StationCommand, error)
+	GetCommands(filter entity.CommandFilter) ([]entity.StationCommand, int64, error)
+	GetExpiredCommands(now time.Time) ([]entity.StationCommand, error)
+}
@@ -7,10 +7,18 @@ import (
 
 type DataRepository interface {
 	CreateData(u *entity.SensorData) error
+	CreateDataBatch(data []entity.SensorData, batchSize int) error
+	GetTimestamps(stationID uint, start, end time.Time) ([]time.Time, error)
+	GetExistingIngestKeys(keys []string) ([]string, error)
+	GetDataIDByIngestKey(key string) (uint, error)
 	GetAllData() ([]entity.SensorData, error)
-	GetLatestData() (*entity.SensorData, error)
-	GetDataByTimeRange(start, end time.Time) ([]entity.SensorData, error)
-	GetDataByLimit(limit int) ([]entity.SensorData, error)
-	GetAggregatedData(interval string, start, end time.Time) ([]entity.AggregatedData, error)
-	GetDataInsights() (*entity.DataInsights, error)
+	GetLatestData(stationID uint) (*entity.SensorData, error)
+	GetDataByTimeRange(stationID uint, start, end time.Time) ([]entity.SensorData, error)
+	GetDataByLimit(stationID uint, limit int) ([]entity.SensorData, error)
+	GetAggregatedData(stationID uint, interval string, start, end time.Time, includeBad bool) ([]entity.AggregatedData, error)
+	GetDataInsights(stationID uint, includeBad bool) (*entity.DataInsights, error)
+	GetClockDrift(stationID uint, since time.Time) ([]entity.ClockDrift, error)
+	GetRainfall(stationID uint, start, end time.Time) ([]entity.RainfallPoint, error)
+	GetWindRose(stationID uint, start, end time.Time) ([]entity.WindRoseCell, error)
+	GetPower(stationID uint, start, end time.Time) ([]entity.PowerPoint, error)
 }
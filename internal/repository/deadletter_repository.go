@@ -0,0 +1,15 @@
+package repository
+
+import (
+	"EWSBE/internal/entity"
+	"time"
+)
+
+type DeadLetterRepository interface {
+	CreateDeadLetter(dl *entity.DeadLetter) error
+	GetDeadLetters(filter entity.DeadLetterFilter) ([]entity.DeadLetter, int64, error)
+	GetDeadLetterByID(id uint) (*entity.DeadLetter, error)
+	UpdateDeadLetter(dl *entity.DeadLetter) error
+	DeleteDeadLetter(id uint) error
+	DeleteDeadLetters(before time.Time) (int64, error)
+}
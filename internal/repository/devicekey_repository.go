@@ -0,0 +1,15 @@
+package repository
+
+import (
+	"EWSBE/internal/entity"
+	"time"
+)
+
+type DeviceKeyRepository interface {
+	CreateDeviceKey(key *entity.DeviceKey) error
+	GetDeviceKeys(stationID uint) ([]entity.DeviceKey, error)
+	GetDeviceKeyByID(id uint) (*entity.DeviceKey, error)
+	GetDeviceKeyByHash(hash string) (*entity.DeviceKey, error)
+	UpdateDeviceKey(key *entity.DeviceKey) error
+	TouchDeviceKey(id uint, at time.Time) error
+}
@@ -0,0 +1,19 @@
+package repository
+
+import (
+	"EWSBE/internal/entity"
+	"time"
+)
+
+type LoginRepository interface {
+	CreateLoginAttempt(attempt *entity.LoginAttempt) error
+	GetLoginAttempts(filter entity.LoginAttemptFilter) ([]entity.LoginAttempt, int64, error)
+	DeleteLoginAttempts(before time.Time) (int64, error)
+
+	GetLockout(kind, key string) (*entity.LoginLockout, error)
+	GetLockoutByID(id uint) (*entity.LoginLockout, error)
+	GetBlockedLockouts(now time.Time) ([]entity.LoginLockout, error)
+	SaveLockout(lockout *entity.LoginLockout) error
+	DeleteLockout(id uint) error
+	DeleteStaleLockouts(before, now time.Time) (int64, error)
+}
@@ -0,0 +1,19 @@
+package repository
+
+import (
+	"EWSBE/internal/entity"
+	"time"
+)
+
+type SessionRepository interface {
+	CreateSession(session *entity.Session) error
+	GetSessionByFamilyID(familyID string) (*entity.Session, error)
+	UpdateSession(session *entity.Session) error
+	RevokeUserSessions(userID uint, at time.Time, reason string) error
+
+	CreateRefreshToken(token *entity.RefreshToken) error
+	GetRefreshTokenByHash(hash string) (*entity.RefreshToken, error)
+	UseRefreshToken(id uint, at time.Time) (bool, error)
+	DeleteExpiredRefreshTokens(before time.Time) (int64, error)
+	DeleteStaleSessions(before time.Time) (int64, error)
+}
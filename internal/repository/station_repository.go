@@ -0,0 +1,12 @@
+package repository
+
+import "EWSBE/internal/entity"
+
+type StationRepository interface {
+	CreateStation(station *entity.Station) error
+	GetAllStations() ([]entity.Station, error)
+	GetStationByID(id uint) (*entity.Station, error)
+	GetStationByCode(code string) (*entity.Station, error)
+	UpdateStation(station *entity.Station) error
+	UpdateHeartbeat(station *entity.Station) error
+}
@@ -1,8 +1,25 @@
 package repository
 
-import "EWSBE/internal/entity"
+import (
+	"EWSBE/internal/entity"
+	"time"
+)
 
 type UserRepository interface {
 	CreateUser(user *entity.User) error
 	GetUserByUsername(username string) (*entity.User, error)
+	GetUserByID(id uint) (*entity.User, error)
+	GetAllUsers() ([]entity.User, error)
+	UpdateUser(user *entity.User) error
+	DeleteUser(id uint) error
+	CountUsers() (int64, error)
+	CountActiveUsersByRole(role string) (int64, error)
+
+	CreateInvite(invite *entity.Invite) error
+	GetInvites() ([]entity.Invite, error)
+	GetInviteByID(id uint) (*entity.Invite, error)
+	GetInviteByHash(hash string) (*entity.Invite, error)
+	ClaimInvite(id uint, at time.Time) (bool, error)
+	UpdateInvite(invite *entity.Invite) error
+	DeleteInvite(id uint) error
 }
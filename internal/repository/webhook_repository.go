@@ -0,0 +1,13 @@
+package repository
+
+import "EWSBE/internal/entity"
+
+type WebhookRepository interface {
+	CreateWebhook(hook *entity.Webhook) error
+	GetAllWebhooks() ([]entity.Webhook, error)
+	GetWebhookByID(id uint) (*entity.Webhook, error)
+	UpdateWebhook(hook *entity.Webhook) error
+	DeleteWebhook(id uint) error
+	CreateDelivery(delivery *entity.WebhookDelivery) error
+	GetDeliveries(webhookID uint, limit int) ([]entity.WebhookDelivery, error)
+}
@@ -0,0 +1,256 @@
+package usecase
+
+import (
+	"EWSBE/internal/entity"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const importBatchSize = 500
+
+// options for ImportCSV
+type ImportOptions struct {
+	StationID uint
+	// CSV header -> MQTTSensorPayload JSON field; nil uses the firmware SD log layout
+	Mapping map[string]string
+	// entity.TimestampDevice or entity.TimestampAuto; uptime values need BootTime
+	TimestampStrategy string
+	// wall-clock time the device booted, used to rebase uptime (millis()) values
+	BootTime *time.Time
+}
+
+// ImportCSV parses an SD card log and inserts its readings in batches, skipping
+// rows whose timestamp is already stored for the station
+func (uc *DataUsecase) ImportCSV(r io.Reader, opts ImportOptions) (*entity.ImportResult, error) {
+	if opts.StationID == 0 {
+		return nil, errors.New("station is required")
+	}
+	if opts.TimestampStrategy == entity.TimestampServer {
+		return nil, errors.New("server timestamps cannot be used for imported logs")
+	}
+
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	result := &entity.ImportResult{
+		SkippedLines: []int{},
+		Rejected:     []entity.ImportRejected{},
+	}
+
+	columns, err := importColumns(reader, opts.Mapping)
+	if err != nil {
+		return nil, err
+	}
+
+	type parsedRow struct {
+		line int
+		data *entity.SensorData
+	}
+	var rows []parsedRow
+	var lastUptime int64 = -1
+	now := time.Now()
+
+	// fresh trackers so historical rows don't disturb the live rate and stuck-value state
+	v := newValidator()
+	levels := newLevelTracker()
+	station, err := uc.stations.GetStationByID(opts.StationID)
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				result.Total++
+				result.Rejected = append(result.Rejected, entity.ImportRejected{Line: parseErr.Line, Error: parseErr.Err.Error()})
+				continue
+			}
+			return nil, err
+		}
+		line, _ := reader.FieldPos(0)
+
+		// the firmware rewrites the header after an SD card swap
+		if len(record) > 0 && strings.TrimSpace(record[0]) == entity.SDLogColumns[0] {
+			continue
+		}
+		if len(record) == 1 && strings.TrimSpace(record[0]) == "" {
+			continue
+		}
+		result.Total++
+
+		payload, err := parseImportRecord(record, columns)
+		if err != nil {
+			result.Rejected = append(result.Rejected, entity.ImportRejected{Line: line, Error: err.Error()})
+			continue
+		}
+
+		d := payload.ToSensorData(opts.TimestampStrategy, now)
+		if d.TimeSource == entity.TimeSourceUptime {
+			if opts.BootTime == nil {
+				result.Rejected = append(result.Rejected, entity.ImportRejected{Line: line, Error: "uptime timestamp requires bootTime"})
+				continue
+			}
+			if payload.Waktu < lastUptime {
+				result.Rejected = append(result.Rejected, entity.ImportRejected{Line: line, Error: "uptime counter reset (device rebooted); import this segment separately with its own bootTime"})
+				continue
+			}
+			lastUptime = payload.Waktu
+			d.Timestamp = opts.BootTime.Add(time.Duration(payload.Waktu) * time.Millisecond)
+		}
+
+		stationID := opts.StationID
+		d.StationID = &stationID
+		d.IngestKey = ingestKey(d)
+		v.Validate(d)
+		levels.Apply(d, station)
+		rows = append(rows, parsedRow{line: line, data: d})
+	}
+
+	if len(rows) == 0 {
+		return result, nil
+	}
+
+	// load stored timestamps covering the file to de-duplicate against
+	start, end := rows[0].data.Timestamp, rows[0].data.Timestamp
+	for _, row := range rows {
+		if row.data.Timestamp.Before(start) {
+			start = row.data.Timestamp
+		}
+		if row.data.Timestamp.After(end) {
+			end = row.data.Timestamp
+		}
+	}
+
+	stored, err := uc.repo.GetTimestamps(opts.StationID, start, end)
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[int64]bool, len(stored)+len(rows))
+	for _, ts := range stored {
+		seen[ts.UnixMilli()] = true
+	}
+
+	batch := make([]entity.SensorData, 0, len(rows))
+	for _, row := range rows {
+		key := row.data.Timestamp.UnixMilli()
+		if seen[key] {
+			result.SkippedLines = append(result.SkippedLines, row.line)
+			continue
+		}
+		seen[key] = true
+		batch = append(batch, *row.data)
+	}
+
+	if len(batch) > 0 {
+		if err := uc.repo.CreateDataBatch(batch, importBatchSize); err != nil {
+			return nil, err
+		}
+	}
+	result.Inserted = len(batch)
+
+	return result, nil
+}
+
+// Backfill stores one historical reading the way ImportCSV does: validated
+// with fresh trackers and without the heartbeat, alert rules or power
+// observations of the live path. A stored duplicate returns
+// ErrDuplicateReading with u.ID set to the stored reading when it is found.
+func (uc *DataUsecase) Backfill(u *entity.SensorData) error {
+	uc.identify(u)
+	if u.IngestKey != nil && uc.recent.Contains(*u.IngestKey, time.Now()) {
+		return uc.duplicate(u)
+	}
+
+	newValidator().Validate(u)
+	newLevelTracker().Apply(u, uc.station(u))
+
+	if err := uc.repo.CreateData(u); err != nil {
+		if u.IngestKey != nil && errors.Is(err, gorm.ErrDuplicatedKey) {
+			return uc.duplicate(u)
+		}
+		return err
+	}
+
+	uc.remember(u)
+	return nil
+}
+
+// duplicate points u at the reading already stored under its ingest key
+func (uc *DataUsecase) duplicate(u *entity.SensorData) error {
+	u.ID = 0
+	if id, err := uc.repo.GetDataIDByIngestKey(*u.IngestKey); err == nil {
+		u.ID = id
+	}
+	return ErrDuplicateReading
+}
+
+// importColumns maps each CSV column index to a payload field
+func importColumns(reader *csv.Reader, mapping map[string]string) ([]string, error) {
+	if mapping == nil {
+		// firmware layout, with or without its header line
+		columns := make([]string, len(entity.SDLogColumns))
+		for i, name := range entity.SDLogColumns {
+			columns[i] = entity.SDLogFieldMapping[name]
+		}
+		return columns, nil
+	}
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, errors.New("a header row is required when a column mapping is given")
+	}
+
+	probe := &entity.MQTTSensorPayload{}
+	columns := make([]string, len(header))
+	found := map[string]bool{}
+	for i, name := range header {
+		field, ok := mapping[strings.TrimSpace(name)]
+		if !ok {
+			continue
+		}
+		if !probe.SetField(field, 0) {
+			return nil, fmt.Errorf("unknown payload field %q in mapping", field)
+		}
+		columns[i] = field
+		found[field] = true
+	}
+
+	if !found["waktu"] {
+		return nil, errors.New("mapping must include a column for waktu")
+	}
+	return columns, nil
+}
+
+func parseImportRecord(record []string, columns []string) (*entity.MQTTSensorPayload, error) {
+	if len(record) < len(columns) {
+		return nil, fmt.Errorf("expected %d columns, got %d", len(columns), len(record))
+	}
+
+	payload := &entity.MQTTSensorPayload{}
+	for i, field := range columns {
+		if field == "" {
+			continue
+		}
+		raw := strings.TrimSpace(record[i])
+		// NaN from a failed sensor read parses and is flagged bad by validation
+		value, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value %q for %s", raw, field)
+		}
+		payload.SetField(field, value)
+	}
+	return payload, nil
+}
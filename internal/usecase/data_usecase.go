@@ -2,51 +2,270 @@ package usecase
 
 import (
 	"EWSBE/internal/entity"
+	"errors"
+	"log"
+	"sort"
 	"time"
+
+	"gorm.io/gorm"
 )
 
 type DataRepository interface {
 	CreateData(u *entity.SensorData) error
+	CreateDataBatch(data []entity.SensorData, batchSize int) error
+	GetTimestamps(stationID uint, start, end time.Time) ([]time.Time, error)
+	GetExistingIngestKeys(keys []string) ([]string, error)
+	GetDataIDByIngestKey(key string) (uint, error)
 	GetAllData() ([]entity.SensorData, error)
-	GetLatestData() (*entity.SensorData, error)
-	GetDataByTimeRange(start, end time.Time) ([]entity.SensorData, error)
-	GetDataByLimit(limit int) ([]entity.SensorData, error)
-	GetAggregatedData(interval string, start, end time.Time) ([]entity.AggregatedData, error)
-	GetDataInsights() (*entity.DataInsights, error)
+	GetLatestData(stationID uint) (*entity.SensorData, error)
+	GetDataByTimeRange(stationID uint, start, end time.Time) ([]entity.SensorData, error)
+	GetDataByLimit(stationID uint, limit int) ([]entity.SensorData, error)
+	GetAggregatedData(stationID uint, interval string, start, end time.Time, includeBad bool) ([]entity.AggregatedData, error)
+	GetDataInsights(stationID uint, includeBad bool) (*entity.DataInsights, error)
+	GetClockDrift(stationID uint, since time.Time) ([]entity.ClockDrift, error)
+	GetRainfall(stationID uint, start, end time.Time) ([]entity.RainfallPoint, error)
+	GetWindRose(stationID uint, start, end time.Time) ([]entity.WindRoseCell, error)
+	GetPower(stationID uint, start, end time.Time) ([]entity.PowerPoint, error)
 }
 
 type DataUsecase struct {
-	repo DataRepository
+	repo   DataRepository
+	alerts *AlertUsecase
+
+	stations  *StationUsecase
+	monitor   *StationMonitor
+	validator *validator
+	levels    *levelTracker
+	power     *powerTracker
+	recent    *recentKeys
+}
+
+func NewDataUsecase(r DataRepository, alerts *AlertUsecase, stations *StationUsecase, monitor *StationMonitor, power PowerConfig, dedup DedupConfig) *DataUsecase {
+	return &DataUsecase{
+		repo:      r,
+		alerts:    alerts,
+		stations:  stations,
+		monitor:   monitor,
+		validator: newValidator(),
+		levels:    newLevelTracker(),
+		power:     newPowerTracker(power, r.GetPower),
+		recent:    newRecentKeys(dedup),
+	}
 }
 
-func NewDataUsecase(r DataRepository) *DataUsecase {
-	return &DataUsecase{repo: r}
+// station returns the reading's station, or nil for unattributed readings
+func (uc *DataUsecase) station(d *entity.SensorData) *entity.Station {
+	if d.StationID == nil || uc.stations == nil {
+		return nil
+	}
+	station, err := uc.stations.GetStationByID(*d.StationID)
+	if err != nil {
+		log.Printf("data: station %d lookup failed: %v", *d.StationID, err)
+		return nil
+	}
+	return station
 }
 
+// Create stores one reading; a redelivered reading returns ErrDuplicateReading
 func (uc *DataUsecase) Create(u *entity.SensorData) error {
-	return uc.repo.CreateData(u)
+	uc.identify(u)
+	if u.IngestKey != nil && uc.recent.Contains(*u.IngestKey, time.Now()) {
+		return ErrDuplicateReading
+	}
+	uc.prepare(u)
+
+	if err := uc.repo.CreateData(u); err != nil {
+		if u.IngestKey != nil && errors.Is(err, gorm.ErrDuplicatedKey) {
+			return ErrDuplicateReading
+		}
+		return err
+	}
+
+	uc.remember(u)
+	uc.afterCommit(u)
+	return nil
+}
+
+// CreateBatch stores live readings in one batch insert. Redeliveries of
+// stored readings are skipped. If the batch fails the readings are retried
+// one by one so a single bad row does not drop the rest; the returned slice
+// holds the readings that were stored. data is sorted by timestamp in place
+// and errs, nil when everything was stored, holds the error of each reading
+// in that order: ErrDuplicateReading for a redelivery, else the insert error.
+func (uc *DataUsecase) CreateBatch(data []entity.SensorData, batchSize int) (stored []entity.SensorData, errs []error) {
+	if len(data) == 0 {
+		return nil, nil
+	}
+
+	// the validator and level tracker expect readings in time order
+	sort.SliceStable(data, func(i, j int) bool { return data[i].Timestamp.Before(data[j].Timestamp) })
+	for i := range data {
+		uc.identify(&data[i])
+	}
+
+	fail := func(i int, err error) {
+		if errs == nil {
+			errs = make([]error, len(data))
+		}
+		errs[i] = err
+	}
+
+	// drop redeliveries: recently stored, already in the database or
+	// repeated within the batch
+	existing := uc.storedKeys(data)
+	now := time.Now()
+	inBatch := make(map[string]bool)
+	fresh := make([]int, 0, len(data))
+	for i := range data {
+		if key := data[i].IngestKey; key != nil {
+			if uc.recent.Contains(*key, now) || existing[*key] || inBatch[*key] {
+				fail(i, ErrDuplicateReading)
+				continue
+			}
+			inBatch[*key] = true
+		}
+		fresh = append(fresh, i)
+	}
+	if len(fresh) == 0 {
+		return nil, errs
+	}
+
+	rows := make([]entity.SensorData, len(fresh))
+	for j, i := range fresh {
+		rows[j] = data[i]
+		uc.prepare(&rows[j])
+	}
+
+	stored = rows
+	if err := uc.repo.CreateDataBatch(rows, batchSize); err != nil {
+		log.Printf("data: batch insert of %d readings failed, retrying one by one: %v", len(rows), err)
+
+		stored = make([]entity.SensorData, 0, len(rows))
+		for j := range rows {
+			rows[j].ID = 0
+			if err := uc.repo.CreateData(&rows[j]); err != nil {
+				// stored concurrently by another worker
+				if rows[j].IngestKey != nil && errors.Is(err, gorm.ErrDuplicatedKey) {
+					err = ErrDuplicateReading
+				}
+				fail(fresh[j], err)
+				continue
+			}
+			stored = append(stored, rows[j])
+		}
+	}
+
+	for j, i := range fresh {
+		data[i] = rows[j]
+	}
+	for i := range stored {
+		uc.remember(&stored[i])
+		uc.afterCommit(&stored[i])
+	}
+	return stored, errs
+}
+
+// identify fills timestamp defaults and the ingest key used to detect
+// redeliveries
+func (uc *DataUsecase) identify(u *entity.SensorData) {
+	if u.ReceivedAt.IsZero() {
+		u.ReceivedAt = time.Now()
+	}
+	if u.Timestamp.IsZero() {
+		u.Timestamp = u.ReceivedAt
+		u.TimeSource = entity.TimeSourceServer
+	} else if u.TimeSource == "" {
+		u.TimeSource = entity.TimeSourceDevice
+	}
+	u.IngestKey = ingestKey(u)
+}
+
+// storedKeys returns which ingest keys of data are already in the database;
+// on error the unique index still rejects the duplicates
+func (uc *DataUsecase) storedKeys(data []entity.SensorData) map[string]bool {
+	keys := make([]string, 0, len(data))
+	for i := range data {
+		if data[i].IngestKey != nil {
+			keys = append(keys, *data[i].IngestKey)
+		}
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+
+	stored, err := uc.repo.GetExistingIngestKeys(keys)
+	if err != nil {
+		log.Printf("data: ingest key lookup failed: %v", err)
+		return nil
+	}
+	existing := make(map[string]bool, len(stored))
+	for _, key := range stored {
+		existing[key] = true
+	}
+	return existing
+}
+
+func (uc *DataUsecase) remember(u *entity.SensorData) {
+	if u.IngestKey != nil {
+		uc.recent.Add(*u.IngestKey, time.Now())
+	}
+}
+
+// prepare flags questionable fields and derives water level before a
+// reading is stored
+func (uc *DataUsecase) prepare(u *entity.SensorData) {
+	// flag questionable fields instead of rejecting the whole reading
+	uc.validator.Validate(u)
+	uc.levels.Apply(u, uc.station(u))
+}
+
+// afterCommit records the heartbeat and runs alert rules for a stored reading
+func (uc *DataUsecase) afterCommit(u *entity.SensorData) {
+	// live readings count as a heartbeat; imports go through ImportCSV
+	if uc.monitor != nil && u.StationID != nil {
+		uc.monitor.Seen(*u.StationID, u.ReceivedAt)
+	}
+
+	// a failed alert evaluation must not drop the reading
+	if uc.alerts != nil {
+		if err := uc.alerts.Evaluate(u); err != nil {
+			log.Printf("alert: evaluation failed for reading %d: %v", u.ID, err)
+		}
+		if metrics := uc.power.Observe(u); metrics != nil {
+			if err := uc.alerts.EvaluateMetrics(u.StationID, u.ID, metrics); err != nil {
+				log.Printf("alert: power evaluation failed for reading %d: %v", u.ID, err)
+			}
+		}
+	}
 }
 
 func (uc *DataUsecase) GetAllData() ([]entity.SensorData, error) {
 	return uc.repo.GetAllData()
 }
 
-func (uc *DataUsecase) GetLatestData() (*entity.SensorData, error) {
-	return uc.repo.GetLatestData()
+// stationID 0 means readings from all stations
+func (uc *DataUsecase) GetLatestData(stationID uint) (*entity.SensorData, error) {
+	return uc.repo.GetLatestData(stationID)
+}
+
+func (uc *DataUsecase) GetDataByTimeRange(stationID uint, start, end time.Time) ([]entity.SensorData, error) {
+	return uc.repo.GetDataByTimeRange(stationID, start, end)
 }
 
-func (uc *DataUsecase) GetDataByTimeRange(start, end time.Time) ([]entity.SensorData, error) {
-	return uc.repo.GetDataByTimeRange(start, end)
+func (uc *DataUsecase) GetDataByLimit(stationID uint, limit int) ([]entity.SensorData, error) {
+	return uc.repo.GetDataByLimit(stationID, limit)
 }
 
-func (uc *DataUsecase) GetDataByLimit(limit int) ([]entity.SensorData, error) {
-	return uc.repo.GetDataByLimit(limit)
+// values flagged bad are skipped unless includeBad is set
+func (uc *DataUsecase) GetAggregatedData(stationID uint, interval string, start, end time.Time, includeBad bool) ([]entity.AggregatedData, error) {
+	return uc.repo.GetAggregatedData(stationID, interval, start, end, includeBad)
 }
 
-func (uc *DataUsecase) GetAggregatedData(interval string, start, end time.Time) ([]entity.AggregatedData, error) {
-	return uc.repo.GetAggregatedData(interval, start, end)
+// values flagged bad are skipped unless includeBad is set
+func (uc *DataUsecase) GetDataInsights(stationID uint, includeBad bool) (*entity.DataInsights, error) {
+	return uc.repo.GetDataInsights(stationID, includeBad)
 }
 
-func (uc *DataUsecase) GetDataInsights() (*entity.DataInsights, error) {
-	return uc.repo.GetDataInsights()
+func (uc *DataUsecase) GetClockDrift(stationID uint, since time.Time) ([]entity.ClockDrift, error) {
+	return uc.repo.GetClockDrift(stationID, since)
 }
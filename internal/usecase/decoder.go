@@ -0,0 +1,452 @@
+package usecase
+
+import (
+	"EWSBE/internal/entity"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fxamacker/cbor/v2"
+)
+
+// built-in decoder names
+const (
+	DecoderJSON = "json" // firmware JSON object, see entity.MQTTSensorPayload
+	DecoderCSV  = "csv"  // one SD log line, see entity.SDLogColumns
+	DecoderCBOR = "cbor" // CBOR map with the JSON keys, or array in SD log column order
+)
+
+// payload keys that carry the format version rather than a reading
+var versionKeys = []string{"v", "version"}
+
+// payload keys that carry the device's message ID, used to drop redeliveries
+var messageIDKeys = []string{"msgId", "messageId"}
+
+// Reading is what a decoder extracts from one message: values keyed by
+// MQTTSensorPayload JSON field, and every field it does not know
+type Reading struct {
+	Values    map[string]float64
+	Extra     map[string]interface{}
+	MessageID string
+}
+
+func newReading() *Reading {
+	return &Reading{Values: make(map[string]float64), Extra: make(map[string]interface{})}
+}
+
+// set stores a value for a payload field; null becomes NaN so validation
+// flags the field bad, as the firmware sends null for a failed sensor read
+func (r *Reading) set(field string, v interface{}) error {
+	if v == nil {
+		r.Values[field] = math.NaN()
+		return nil
+	}
+	f, ok := toFloat(v)
+	if !ok {
+		return fmt.Errorf("field %s: %v is not a number", field, v)
+	}
+	r.Values[field] = f
+	return nil
+}
+
+// ToSensorData converts the reading. Fields the payload did not carry are
+// flagged missing, and without a usable waktu (absent, null or NaN) the
+// receive time is used.
+func (r *Reading) ToSensorData(strategy string, receivedAt time.Time) *entity.SensorData {
+	waktu, hasTime := r.Values["waktu"]
+	hasTime = hasTime && !math.IsNaN(waktu) && !math.IsInf(waktu, 0)
+	if !hasTime {
+		strategy = entity.TimestampServer
+	}
+
+	var payload entity.MQTTSensorPayload
+	for field, v := range r.Values {
+		if field == "waktu" && !hasTime {
+			continue
+		}
+		payload.SetField(field, v)
+	}
+
+	d := payload.ToSensorData(strategy, receivedAt)
+	d.Quality = make(entity.QualityFlags)
+	for field, column := range entity.PayloadFieldMapping {
+		if _, ok := r.Values[field]; !ok {
+			d.Quality[column] = entity.QualityMissing
+		}
+	}
+	if len(r.Extra) > 0 {
+		d.Extra = r.Extra
+	}
+	d.MessageID = r.MessageID
+	return d
+}
+
+// Decoder parses one sensor payload, an MQTT message or an HTTP body, into
+// a reading
+type Decoder interface {
+	Decode(payload []byte) (*Reading, error)
+}
+
+// JSONDecoder reads a JSON object. Keys are firmware names ("suhu") or
+// SensorData names ("temperature"); Fields adds aliases for other firmware.
+type JSONDecoder struct {
+	Fields map[string]string // payload key -> MQTTSensorPayload field
+}
+
+func (d JSONDecoder) Decode(payload []byte) (*Reading, error) {
+	var m map[string]interface{}
+	if err := json.Unmarshal(payload, &m); err != nil {
+		return nil, err
+	}
+	return fromMap(m, d.Fields)
+}
+
+// CSVDecoder reads one comma-separated line. Empty values are missing and
+// columns beyond Columns are kept as extra "colN" fields.
+type CSVDecoder struct {
+	Columns []string // MQTTSensorPayload field per column, "" skips; nil uses the SD log layout
+}
+
+func (d CSVDecoder) Decode(payload []byte) (*Reading, error) {
+	var line string
+	for _, l := range strings.Split(string(payload), "\n") {
+		l = strings.TrimSpace(l)
+		if l == "" || strings.HasPrefix(l, entity.SDLogColumns[0]+",") {
+			continue // blank or the SD log header
+		}
+		if line != "" {
+			return nil, errors.New("expected one line per message")
+		}
+		line = l
+	}
+	if line == "" {
+		return nil, errors.New("empty payload")
+	}
+
+	columns := d.Columns
+	if columns == nil {
+		columns = sdLogFields()
+	}
+
+	r := newReading()
+	for i, raw := range strings.Split(line, ",") {
+		raw = strings.TrimSpace(raw)
+		if raw == "" {
+			continue
+		}
+		if i >= len(columns) {
+			if f, err := strconv.ParseFloat(raw, 64); err == nil {
+				r.Extra[fmt.Sprintf("col%d", i+1)] = f
+			} else {
+				r.Extra[fmt.Sprintf("col%d", i+1)] = raw
+			}
+			continue
+		}
+		if columns[i] == "" {
+			continue
+		}
+		// "nan" from a failed sensor read parses and is flagged bad
+		f, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value %q for %s", raw, columns[i])
+		}
+		r.Values[columns[i]] = f
+	}
+	return r, nil
+}
+
+var cborDecMode, _ = cbor.DecOptions{
+	DefaultMapType: reflect.TypeOf(map[string]interface{}(nil)),
+}.DecMode()
+
+// CBORDecoder reads a CBOR map with the same keys as JSONDecoder, or an
+// array of values in Columns order for compact payloads
+type CBORDecoder struct {
+	Fields  map[string]string // payload key -> MQTTSensorPayload field
+	Columns []string          // for arrays; nil uses the SD log layout
+}
+
+func (d CBORDecoder) Decode(payload []byte) (*Reading, error) {
+	var v interface{}
+	if err := cborDecMode.Unmarshal(payload, &v); err != nil {
+		return nil, err
+	}
+
+	switch v := v.(type) {
+	case map[string]interface{}:
+		return fromMap(v, d.Fields)
+	case []interface{}:
+		columns := d.Columns
+		if columns == nil {
+			columns = sdLogFields()
+		}
+		r := newReading()
+		for i, value := range v {
+			if i >= len(columns) {
+				r.Extra[fmt.Sprintf("col%d", i+1)] = value
+				continue
+			}
+			if columns[i] == "" {
+				continue
+			}
+			if err := r.set(columns[i], value); err != nil {
+				return nil, err
+			}
+		}
+		return r, nil
+	}
+	return nil, fmt.Errorf("expected a CBOR map or array, got %T", v)
+}
+
+// fromMap splits a decoded object into known fields and extras
+func fromMap(m map[string]interface{}, aliases map[string]string) (*Reading, error) {
+	r := newReading()
+	for key, value := range m {
+		if isVersionKey(key) {
+			continue
+		}
+		if isMessageIDKey(key) {
+			if id, ok := messageID(value); ok {
+				r.MessageID = id
+				continue
+			}
+		}
+		field, ok := aliases[key]
+		if !ok {
+			field = payloadField(key)
+		}
+		if field == "" {
+			r.Extra[key] = value
+			continue
+		}
+		if err := r.set(field, value); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// payloadField maps a firmware or SensorData key to its MQTTSensorPayload field
+func payloadField(key string) string {
+	if key == "waktu" || key == "deviceTime" {
+		return "waktu"
+	}
+	if _, ok := entity.PayloadFieldMapping[key]; ok {
+		return key
+	}
+	for field, column := range entity.PayloadFieldMapping {
+		if column == key {
+			return field
+		}
+	}
+	return ""
+}
+
+func sdLogFields() []string {
+	columns := make([]string, len(entity.SDLogColumns))
+	for i, name := range entity.SDLogColumns {
+		columns[i] = entity.SDLogFieldMapping[name]
+	}
+	return columns
+}
+
+func isVersionKey(key string) bool {
+	for _, k := range versionKeys {
+		if key == k {
+			return true
+		}
+	}
+	return false
+}
+
+func isMessageIDKey(key string) bool {
+	for _, k := range messageIDKeys {
+		if key == k {
+			return true
+		}
+	}
+	return false
+}
+
+// messageID accepts string and integer IDs
+func messageID(v interface{}) (string, bool) {
+	switch id := v.(type) {
+	case string:
+		return id, id != ""
+	case float64:
+		if id == math.Trunc(id) {
+			return strconv.FormatFloat(id, 'f', -1, 64), true
+		}
+	case uint64:
+		return strconv.FormatUint(id, 10), true
+	case int64:
+		return strconv.FormatInt(id, 10), true
+	}
+	return "", false
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	}
+	return 0, false
+}
+
+type decoderRoute struct {
+	match   string // topic pattern or version value
+	decoder string
+}
+
+// DecoderRegistry picks the decoder for a message: a matching topic route
+// first, then the payload's "v"/"version" field, then the payload format
+// (JSON object, CBOR map or array, otherwise a CSV line). Configure it
+// before subscribing; Decode is safe for concurrent use afterwards.
+type DecoderRegistry struct {
+	decoders map[string]Decoder
+	topics   []decoderRoute
+	versions map[string]string
+}
+
+// NewDecoderRegistry returns a registry with the built-in decoders
+func NewDecoderRegistry() *DecoderRegistry {
+	r := &DecoderRegistry{
+		decoders: make(map[string]Decoder),
+		versions: make(map[string]string),
+	}
+	r.Register(DecoderJSON, JSONDecoder{})
+	r.Register(DecoderCSV, CSVDecoder{})
+	r.Register(DecoderCBOR, CBORDecoder{})
+	return r
+}
+
+// Register adds or replaces a named decoder
+func (r *DecoderRegistry) Register(name string, d Decoder) {
+	r.decoders[name] = d
+}
+
+// Route sends messages on topics matching pattern to a decoder; routes are
+// checked in the order they were added
+func (r *DecoderRegistry) Route(pattern, name string) error {
+	if _, ok := r.decoders[name]; !ok {
+		return fmt.Errorf("unknown decoder %q", name)
+	}
+	r.topics = append(r.topics, decoderRoute{match: pattern, decoder: name})
+	return nil
+}
+
+// MapVersion sends payloads whose version field equals version to a decoder
+func (r *DecoderRegistry) MapVersion(version, name string) error {
+	if _, ok := r.decoders[name]; !ok {
+		return fmt.Errorf("unknown decoder %q", name)
+	}
+	r.versions[version] = name
+	return nil
+}
+
+// Select returns the name of the decoder for a message
+func (r *DecoderRegistry) Select(topic string, payload []byte) string {
+	for _, route := range r.topics {
+		if topicMatches(route.match, topic) {
+			return route.decoder
+		}
+	}
+
+	format := sniffFormat(payload)
+	if len(r.versions) > 0 {
+		if name, ok := r.versions[payloadVersion(format, payload)]; ok {
+			return name
+		}
+	}
+	return format
+}
+
+// Decode parses a message with the selected decoder and returns its name
+func (r *DecoderRegistry) Decode(topic string, payload []byte) (*Reading, string, error) {
+	name := r.Select(topic, payload)
+	d, ok := r.decoders[name]
+	if !ok {
+		return nil, name, fmt.Errorf("unknown decoder %q", name)
+	}
+	reading, err := d.Decode(payload)
+	return reading, name, err
+}
+
+// sniffFormat guesses the built-in decoder from the first byte
+func sniffFormat(payload []byte) string {
+	trimmed := bytes.TrimSpace(payload)
+	if len(trimmed) > 0 && trimmed[0] == '{' {
+		return DecoderJSON
+	}
+	if len(payload) > 0 {
+		// CBOR array (major type 4), map (5) or the self-describe tag 55799
+		switch b := payload[0]; {
+		case b >= 0x80 && b <= 0xbf, bytes.HasPrefix(payload, []byte{0xd9, 0xd9, 0xf7}):
+			return DecoderCBOR
+		}
+	}
+	return DecoderCSV
+}
+
+// payloadVersion reads the version field of a JSON or CBOR object
+func payloadVersion(format string, payload []byte) string {
+	var m map[string]interface{}
+	switch format {
+	case DecoderJSON:
+		if json.Unmarshal(payload, &m) != nil {
+			return ""
+		}
+	case DecoderCBOR:
+		var v interface{}
+		if cborDecMode.Unmarshal(payload, &v) != nil {
+			return ""
+		}
+		m, _ = v.(map[string]interface{})
+	}
+
+	for _, key := range versionKeys {
+		switch v := m[key].(type) {
+		case string:
+			return v
+		case nil:
+		default:
+			if f, ok := toFloat(v); ok {
+				return strconv.FormatFloat(f, 'f', -1, 64)
+			}
+		}
+	}
+	return ""
+}
+
+// topicMatches reports whether topic matches an MQTT subscription pattern
+func topicMatches(pattern, topic string) bool {
+	patternParts := strings.Split(pattern, "/")
+	topicParts := strings.Split(topic, "/")
+
+	for i, p := range patternParts {
+		if p == "#" {
+			return true
+		}
+		if i >= len(topicParts) {
+			return false
+		}
+		if p != "+" && p != topicParts[i] {
+			return false
+		}
+	}
+	return len(patternParts) == len(topicParts)
+}
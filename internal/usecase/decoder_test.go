@@ -0,0 +1,193 @@
+package usecase
+
+import (
+	"EWSBE/internal/entity"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/fxamacker/cbor/v2"
+)
+
+func TestJSONDecoder(t *testing.T) {
+	payload := []byte(`{"v":2,"msgId":42,"waktu":1735689600000,"suhu":27.5,"humidity":80,"co2":null,"firmware":"1.4.0","mm":3}`)
+
+	r, err := JSONDecoder{Fields: map[string]string{"mm": "rain"}}.Decode(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.MessageID != "42" {
+		t.Errorf("message ID = %q, want 42", r.MessageID)
+	}
+	// firmware, SensorData and alias keys all map to payload fields
+	if r.Values["waktu"] != 1735689600000 || r.Values["suhu"] != 27.5 || r.Values["lembap"] != 80 || r.Values["rain"] != 3 {
+		t.Errorf("values = %v", r.Values)
+	}
+	// null from a failed sensor read is kept as NaN so validation flags it
+	if !math.IsNaN(r.Values["co2"]) {
+		t.Errorf("null co2 decoded as %v, want NaN", r.Values["co2"])
+	}
+	if _, ok := r.Values["v"]; ok {
+		t.Error("version key decoded as a value")
+	}
+	if r.Extra["firmware"] != "1.4.0" || len(r.Extra) != 1 {
+		t.Errorf("extra = %v", r.Extra)
+	}
+}
+
+func TestJSONDecoderErrors(t *testing.T) {
+	for _, payload := range []string{`not json`, `[1,2]`, `{"suhu":"warm"}`} {
+		if _, err := (JSONDecoder{}).Decode([]byte(payload)); err == nil {
+			t.Errorf("%s decoded without error", payload)
+		}
+	}
+}
+
+func TestCSVDecoder(t *testing.T) {
+	payload := []byte("waktu_ms,suhu,lembap\n1735689600000,27.5,,1010,,,,,,,,,,99,note\n")
+
+	r, err := CSVDecoder{}.Decode(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Values["waktu"] != 1735689600000 || r.Values["suhu"] != 27.5 || r.Values["tekanan"] != 1010 {
+		t.Errorf("values = %v", r.Values)
+	}
+	if _, ok := r.Values["lembap"]; ok {
+		t.Error("empty column decoded as a value")
+	}
+	if r.Extra["col14"] != 99.0 || r.Extra["col15"] != "note" {
+		t.Errorf("extra = %v", r.Extra)
+	}
+
+	r, err = CSVDecoder{Columns: []string{"waktu", "", "suhu"}}.Decode([]byte("1000,skipped,nan"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !math.IsNaN(r.Values["suhu"]) || len(r.Values) != 2 {
+		t.Errorf("custom columns = %v", r.Values)
+	}
+}
+
+func TestCSVDecoderErrors(t *testing.T) {
+	for _, payload := range []string{"", "\n", "1,2\n3,4", "1000,warm"} {
+		if _, err := (CSVDecoder{}).Decode([]byte(payload)); err == nil {
+			t.Errorf("%q decoded without error", payload)
+		}
+	}
+}
+
+func TestCBORDecoder(t *testing.T) {
+	m, err := cbor.Marshal(map[string]interface{}{"waktu": 1735689600000, "suhu": 27.5, "messageId": "abc", "lembap": nil})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := CBORDecoder{}.Decode(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Values["waktu"] != 1735689600000 || r.Values["suhu"] != 27.5 || r.MessageID != "abc" || !math.IsNaN(r.Values["lembap"]) {
+		t.Errorf("map: values = %v, message ID %q", r.Values, r.MessageID)
+	}
+
+	a, err := cbor.Marshal([]interface{}{1735689600000, 27.5, 80})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err = CBORDecoder{}.Decode(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Values["waktu"] != 1735689600000 || r.Values["suhu"] != 27.5 || r.Values["lembap"] != 80 {
+		t.Errorf("array: values = %v", r.Values)
+	}
+
+	s, _ := cbor.Marshal("text")
+	if _, err := (CBORDecoder{}).Decode(s); err == nil {
+		t.Error("CBOR string decoded without error")
+	}
+}
+
+func TestReadingToSensorData(t *testing.T) {
+	receivedAt := time.Date(2025, 1, 1, 0, 0, 5, 0, time.UTC)
+	deviceTime := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name       string
+		values     map[string]float64
+		wantTime   time.Time
+		wantSource string
+	}{
+		{"device epoch", map[string]float64{"waktu": float64(deviceTime.UnixMilli()), "suhu": 20}, deviceTime, entity.TimeSourceDevice},
+		{"uptime", map[string]float64{"waktu": 60000, "suhu": 20}, receivedAt, entity.TimeSourceUptime},
+		{"missing waktu", map[string]float64{"suhu": 20}, receivedAt, entity.TimeSourceServer},
+		{"null waktu", map[string]float64{"waktu": math.NaN(), "suhu": 20}, receivedAt, entity.TimeSourceServer},
+		{"infinite waktu", map[string]float64{"waktu": math.Inf(1), "suhu": 20}, receivedAt, entity.TimeSourceServer},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &Reading{Values: tt.values, MessageID: "m1"}
+			d := r.ToSensorData(entity.TimestampAuto, receivedAt)
+			if !d.Timestamp.Equal(tt.wantTime) || d.TimeSource != tt.wantSource {
+				t.Errorf("timestamp %v (%s), want %v (%s)", d.Timestamp, d.TimeSource, tt.wantTime, tt.wantSource)
+			}
+			if tt.wantSource == entity.TimeSourceServer && d.DeviceTime != 0 {
+				t.Errorf("device time = %d, want 0", d.DeviceTime)
+			}
+			if d.Temperature != 20 || d.MessageID != "m1" {
+				t.Errorf("temperature %v, message ID %q", d.Temperature, d.MessageID)
+			}
+			if d.Quality["temperature"] != "" || d.Quality["humidity"] != entity.QualityMissing {
+				t.Errorf("quality = %v", d.Quality)
+			}
+		})
+	}
+}
+
+func TestDecoderRegistrySelect(t *testing.T) {
+	r := NewDecoderRegistry()
+	if err := r.Route("legacy/+/csv", DecoderCSV); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.MapVersion("3", DecoderCSV); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Route("x/#", "protobuf"); err == nil {
+		t.Error("route to an unknown decoder accepted")
+	}
+
+	cborMap, _ := cbor.Marshal(map[string]interface{}{"suhu": 1})
+	tests := []struct {
+		topic, payload, want string
+	}{
+		{"legacy/st1/csv", `{"suhu":1}`, DecoderCSV},
+		{"ews/st1/data", `{"suhu":1}`, DecoderJSON},
+		{"ews/st1/data", ` {"v":3,"suhu":1}`, DecoderCSV},
+		{"ews/st1/data", `{"version":"2","suhu":1}`, DecoderJSON},
+		{"ews/st1/data", string(cborMap), DecoderCBOR},
+		{"ews/st1/data", "1000,20.5", DecoderCSV},
+	}
+	for _, tt := range tests {
+		if got := r.Select(tt.topic, []byte(tt.payload)); got != tt.want {
+			t.Errorf("Select(%s, %q) = %s, want %s", tt.topic, tt.payload, got, tt.want)
+		}
+	}
+}
+
+func TestTopicMatches(t *testing.T) {
+	tests := []struct {
+		pattern, topic string
+		want           bool
+	}{
+		{"ews/+/data", "ews/st1/data", true},
+		{"ews/+/data", "ews/st1/status", false},
+		{"ews/#", "ews/st1/data", true},
+		{"ews/st1", "ews/st1/data", false},
+		{"ews/st1/data", "ews/st1", false},
+	}
+	for _, tt := range tests {
+		if got := topicMatches(tt.pattern, tt.topic); got != tt.want {
+			t.Errorf("topicMatches(%s, %s) = %v", tt.pattern, tt.topic, got)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package usecase
+
+import (
+	"EWSBE/internal/entity"
+	"container/list"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrDuplicateReading is returned for a reading that was already stored
+var ErrDuplicateReading = errors.New("duplicate reading")
+
+type DedupConfig struct {
+	CacheSize int           // recent keys kept in memory
+	CacheTTL  time.Duration // how long a key is remembered
+}
+
+// ingestKey identifies a reading across redeliveries: the station plus the
+// device's message ID, or plus the device timestamp when the time came from
+// the device clock. Uptime and server times repeat or differ between
+// deliveries, so such readings without a message ID have no key.
+func ingestKey(d *entity.SensorData) *string {
+	var stationID uint
+	if d.StationID != nil {
+		stationID = *d.StationID
+	}
+
+	var key string
+	switch {
+	case d.MessageID != "":
+		key = fmt.Sprintf("%d:m:%s", stationID, d.MessageID)
+	case d.TimeSource == entity.TimeSourceDevice && !d.Timestamp.IsZero():
+		key = fmt.Sprintf("%d:t:%d", stationID, d.Timestamp.UnixMilli())
+	default:
+		return nil
+	}
+	return &key
+}
+
+// recentKeys remembers the ingest keys of recently stored readings so most
+// redeliveries are dropped without a database round trip; the unique index
+// on the key catches the rest
+type recentKeys struct {
+	mu    sync.Mutex
+	size  int
+	ttl   time.Duration
+	order *list.List // oldest first
+	keys  map[string]*list.Element
+}
+
+type recentKey struct {
+	key      string
+	storedAt time.Time
+}
+
+func newRecentKeys(cfg DedupConfig) *recentKeys {
+	if cfg.CacheSize <= 0 {
+		cfg.CacheSize = 10000
+	}
+	if cfg.CacheTTL <= 0 {
+		cfg.CacheTTL = 24 * time.Hour
+	}
+	return &recentKeys{
+		size:  cfg.CacheSize,
+		ttl:   cfg.CacheTTL,
+		order: list.New(),
+		keys:  make(map[string]*list.Element),
+	}
+}
+
+func (c *recentKeys) Contains(key string, now time.Time) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.keys[key]
+	return ok && now.Sub(el.Value.(recentKey).storedAt) < c.ttl
+}
+
+func (c *recentKeys) Add(key string, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.keys[key]; ok {
+		c.order.Remove(el)
+	}
+	c.keys[key] = c.order.PushBack(recentKey{key: key, storedAt: now})
+
+	// evict expired keys and the oldest beyond the size limit
+	for el := c.order.Front(); el != nil; el = c.order.Front() {
+		k := el.Value.(recentKey)
+		if c.order.Len() <= c.size && now.Sub(k.storedAt) < c.ttl {
+			break
+		}
+		c.order.Remove(el)
+		delete(c.keys, k.key)
+	}
+}
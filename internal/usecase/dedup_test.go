@@ -0,0 +1,107 @@
+package usecase
+
+import (
+	"EWSBE/internal/entity"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestIngestKey(t *testing.T) {
+	station := uint(7)
+	deviceTime := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		data entity.SensorData
+		want string // empty for no key
+	}{
+		{"message ID", entity.SensorData{StationID: &station, MessageID: "42", TimeSource: entity.TimeSourceDevice, Timestamp: deviceTime}, "7:m:42"},
+		{"device time", entity.SensorData{StationID: &station, TimeSource: entity.TimeSourceDevice, Timestamp: deviceTime}, fmt.Sprintf("7:t:%d", deviceTime.UnixMilli())},
+		{"no station", entity.SensorData{MessageID: "42"}, "0:m:42"},
+		{"uptime", entity.SensorData{StationID: &station, TimeSource: entity.TimeSourceUptime, Timestamp: deviceTime}, ""},
+		{"server time", entity.SensorData{StationID: &station, TimeSource: entity.TimeSourceServer, Timestamp: deviceTime}, ""},
+		{"zero device time", entity.SensorData{StationID: &station, TimeSource: entity.TimeSourceDevice}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ingestKey(&tt.data)
+			switch {
+			case tt.want == "" && got != nil:
+				t.Errorf("key = %q, want none", *got)
+			case tt.want != "" && (got == nil || *got != tt.want):
+				t.Errorf("key = %v, want %q", got, tt.want)
+			}
+		})
+	}
+
+	// the same message from another station is a different reading
+	other := uint(8)
+	a := ingestKey(&entity.SensorData{StationID: &station, MessageID: "42"})
+	b := ingestKey(&entity.SensorData{StationID: &other, MessageID: "42"})
+	if *a == *b {
+		t.Errorf("stations 7 and 8 share key %q", *a)
+	}
+}
+
+func TestRecentKeysExpire(t *testing.T) {
+	c := newRecentKeys(DedupConfig{CacheSize: 10, CacheTTL: time.Minute})
+	now := time.Now()
+
+	c.Add("a", now)
+	if !c.Contains("a", now.Add(59*time.Second)) {
+		t.Error("key forgotten before the TTL")
+	}
+	if c.Contains("a", now.Add(time.Minute)) {
+		t.Error("key remembered after the TTL")
+	}
+	if c.Contains("b", now) {
+		t.Error("unknown key reported as recent")
+	}
+
+	// adding again restarts the TTL
+	c.Add("a", now.Add(30*time.Second))
+	if !c.Contains("a", now.Add(80*time.Second)) {
+		t.Error("re-added key kept its old TTL")
+	}
+
+	// a later Add evicts expired keys
+	c.Add("b", now.Add(2*time.Minute))
+	if _, ok := c.keys["a"]; ok || c.order.Len() != 1 {
+		t.Errorf("expired key not evicted, %d keys kept", c.order.Len())
+	}
+}
+
+func TestRecentKeysEvictOldest(t *testing.T) {
+	c := newRecentKeys(DedupConfig{CacheSize: 3, CacheTTL: time.Hour})
+	now := time.Now()
+
+	for i, key := range []string{"a", "b", "c", "d"} {
+		c.Add(key, now.Add(time.Duration(i)*time.Second))
+	}
+	if c.Contains("a", now) {
+		t.Error("oldest key kept beyond the size limit")
+	}
+	for _, key := range []string{"b", "c", "d"} {
+		if !c.Contains(key, now) {
+			t.Errorf("key %s evicted", key)
+		}
+	}
+
+	// re-adding moves a key to the back of the queue
+	c.Add("b", now.Add(5*time.Second))
+	c.Add("e", now.Add(6*time.Second))
+	if c.Contains("c", now) || !c.Contains("b", now) {
+		t.Error("re-added key evicted before an older one")
+	}
+	if len(c.keys) != 3 || c.order.Len() != 3 {
+		t.Errorf("%d keys and %d list entries, want 3", len(c.keys), c.order.Len())
+	}
+}
+
+func TestRecentKeysDefaults(t *testing.T) {
+	c := newRecentKeys(DedupConfig{})
+	if c.size != 10000 || c.ttl != 24*time.Hour {
+		t.Errorf("defaults: size %d, ttl %v", c.size, c.ttl)
+	}
+}
@@ -0,0 +1,440 @@
+package usecase
+
+import (
+	"EWSBE/internal/entity"
+	"log"
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+type socPoint struct {
+	volts float64 // per cell, at rest
+	soc   float64 // %
+}
+
+type batteryProfile struct {
+	curve       []socPoint // ascending voltage
+	chargeVolts float64    // per cell; at or above this the battery is being charged
+}
+
+// open-circuit voltage curves; readings under load or charge skew the
+// estimate, so treat the state of charge as approximate
+var batteryProfiles = map[string]batteryProfile{
+	entity.BatteryLiIon: {
+		curve: []socPoint{
+			{3.00, 0}, {3.30, 5}, {3.45, 10}, {3.60, 20}, {3.70, 40}, {3.75, 50},
+			{3.80, 60}, {3.90, 75}, {4.00, 85}, {4.10, 95}, {4.20, 100},
+		},
+		chargeVolts: 4.10,
+	},
+	entity.BatteryLiFePO4: {
+		curve: []socPoint{
+			{2.50, 0}, {3.00, 10}, {3.20, 20}, {3.25, 40}, {3.30, 70}, {3.35, 90}, {3.40, 100},
+		},
+		chargeVolts: 3.45,
+	},
+	entity.BatteryLeadAcid: {
+		curve: []socPoint{
+			{1.750, 0}, {1.885, 10}, {1.930, 20}, {1.958, 30}, {1.983, 40}, {2.010, 50},
+			{2.033, 60}, {2.053, 70}, {2.070, 80}, {2.083, 90}, {2.117, 100},
+		},
+		chargeVolts: 2.30,
+	},
+}
+
+const (
+	// consecutive samples further apart than this are not integrated
+	powerMaxGap = 15 * time.Minute
+	// voltage slope per cell below which the battery counts as idle
+	trendDeadband = 0.01 // V/h
+)
+
+type PowerConfig struct {
+	Chemistry     string
+	Cells         int           // in series
+	ChargeCurrent float64       // mA; currents beyond this count as charging or discharging
+	TrendWindow   time.Duration // window for the voltage trend
+	SeedWindow    time.Duration // stored readings searched for the last charge after startup
+}
+
+func (c PowerConfig) withDefaults() PowerConfig {
+	if _, ok := batteryProfiles[c.Chemistry]; !ok {
+		c.Chemistry = entity.BatteryLiIon
+	}
+	if c.Cells <= 0 {
+		c.Cells = 1
+	}
+	if c.ChargeCurrent <= 0 {
+		c.ChargeCurrent = 10
+	}
+	if c.TrendWindow <= 0 {
+		c.TrendWindow = 6 * time.Hour
+	}
+	if c.SeedWindow <= 0 {
+		c.SeedWindow = 72 * time.Hour
+	}
+	return c
+}
+
+func (c PowerConfig) profile() batteryProfile {
+	return batteryProfiles[c.Chemistry]
+}
+
+// soc estimates the state of charge from pack voltage
+func (c PowerConfig) soc(voltage float64) float64 {
+	curve := c.profile().curve
+	v := voltage / float64(c.Cells)
+	if v <= curve[0].volts {
+		return 0
+	}
+	for i := 1; i < len(curve); i++ {
+		if v <= curve[i].volts {
+			lo, hi := curve[i-1], curve[i]
+			return lo.soc + (v-lo.volts)/(hi.volts-lo.volts)*(hi.soc-lo.soc)
+		}
+	}
+	return 100
+}
+
+// charging reports whether a reading shows the battery taking charge,
+// either by reverse current or by reaching the charge voltage
+func (c PowerConfig) charging(voltage, current *float64) bool {
+	if current != nil && *current <= -c.ChargeCurrent {
+		return true
+	}
+	return voltage != nil && *voltage >= c.profile().chargeVolts*float64(c.Cells)
+}
+
+func (c PowerConfig) state(current *float64) string {
+	switch {
+	case current == nil:
+		return entity.BatteryUnknown
+	case *current <= -c.ChargeCurrent:
+		return entity.BatteryCharging
+	case *current >= c.ChargeCurrent:
+		return entity.BatteryDischarging
+	default:
+		return entity.BatteryIdle
+	}
+}
+
+// powerHistory loads a station's stored power readings, oldest first
+type powerHistory func(stationID uint, start, end time.Time) ([]entity.PowerPoint, error)
+
+// powerTracker remembers when each station's battery last took charge.
+// A station's first reading after startup seeds the tracker from the
+// readings stored within the seed window, so a restart neither resets the
+// clock nor clears open charging failure alerts. Until a charge is seen the
+// clock runs from the oldest of those readings, so a charging failure is
+// reported late rather than falsely.
+type powerTracker struct {
+	cfg     PowerConfig
+	history powerHistory
+
+	mu         sync.Mutex
+	lastCharge map[uint]time.Time
+	firstSeen  map[uint]time.Time
+}
+
+func newPowerTracker(cfg PowerConfig, history powerHistory) *powerTracker {
+	return &powerTracker{
+		cfg:        cfg.withDefaults(),
+		history:    history,
+		lastCharge: make(map[uint]time.Time),
+		firstSeen:  make(map[uint]time.Time),
+	}
+}
+
+// seed looks up the last charge of a station seen for the first time; it
+// reports false when the stored readings could not be loaded
+func (t *powerTracker) seed(stationID uint, at time.Time) bool {
+	t.mu.Lock()
+	_, seeded := t.firstSeen[stationID]
+	t.mu.Unlock()
+	if seeded {
+		return true
+	}
+
+	first := at
+	var last time.Time
+	if t.history != nil {
+		points, err := t.history(stationID, at.Add(-t.cfg.SeedWindow), at)
+		if err != nil {
+			log.Printf("power: loading readings of station %d failed: %v", stationID, err)
+			return false
+		}
+		for _, p := range points {
+			if p.Timestamp.Before(first) {
+				first = p.Timestamp
+			}
+			if t.cfg.charging(p.Voltage, p.Current) && p.Timestamp.After(last) {
+				last = p.Timestamp
+			}
+		}
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if _, ok := t.firstSeen[stationID]; !ok {
+		t.firstSeen[stationID] = first
+		if !last.IsZero() && last.After(t.lastCharge[stationID]) {
+			t.lastCharge[stationID] = last
+		}
+	}
+	return true
+}
+
+// Observe returns the derived power metrics for a reading
+func (t *powerTracker) Observe(d *entity.SensorData) map[string]float64 {
+	var voltage, current *float64
+	if !d.Quality.IsBad("voltage") && d.Voltage > 0 {
+		voltage = &d.Voltage
+	}
+	if !d.Quality.IsBad("current") {
+		current = &d.Current
+	}
+	if voltage == nil && current == nil {
+		return nil
+	}
+
+	var stationID uint
+	if d.StationID != nil {
+		stationID = *d.StationID
+	}
+
+	metrics := make(map[string]float64, 2)
+	if voltage != nil {
+		metrics[entity.MetricBatterySoc] = t.cfg.soc(*voltage)
+	}
+	// without the stored history the clock would restart at this reading
+	if !t.seed(stationID, d.Timestamp) {
+		return metrics
+	}
+
+	t.mu.Lock()
+	if t.cfg.charging(voltage, current) && d.Timestamp.After(t.lastCharge[stationID]) {
+		t.lastCharge[stationID] = d.Timestamp
+	}
+	since, ok := t.lastCharge[stationID]
+	if !ok {
+		since = t.firstSeen[stationID]
+	}
+	t.mu.Unlock()
+
+	metrics[entity.MetricHoursSinceCharge] = math.Max(d.Timestamp.Sub(since).Hours(), 0)
+	return metrics
+}
+
+// LastCharge returns when the station's battery was last seen taking charge
+func (t *powerTracker) LastCharge(stationID uint) (time.Time, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	last, ok := t.lastCharge[stationID]
+	return last, ok
+}
+
+// GetPowerSummary returns battery state, voltage trend, daily energy over
+// the last days and active power alerts for one station
+func (uc *DataUsecase) GetPowerSummary(stationID uint, days int) (*entity.PowerSummary, error) {
+	cfg := uc.power.cfg
+	now := time.Now()
+	startOfToday := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	start := startOfToday.AddDate(0, 0, -(days - 1))
+	if w := now.Add(-cfg.TrendWindow); w.Before(start) {
+		start = w
+	}
+
+	points, err := uc.repo.GetPower(stationID, start, now)
+	if err != nil {
+		return nil, err
+	}
+
+	summary := &entity.PowerSummary{
+		AsOf:      now,
+		Chemistry: cfg.Chemistry,
+		Cells:     cfg.Cells,
+		Battery:   batteryState(cfg, points),
+		Trend:     voltageTrend(cfg, points, now),
+		Daily:     dailyEnergy(points, startOfToday.AddDate(0, 0, -(days-1)), startOfToday),
+		Alerts:    []entity.Alert{},
+	}
+
+	for i := len(points) - 1; i >= 0; i-- {
+		if cfg.charging(points[i].Voltage, points[i].Current) {
+			at := points[i].Timestamp
+			summary.LastChargeAt = &at
+			break
+		}
+	}
+	// the live tracker may remember a charge from before the window
+	if last, ok := uc.power.LastCharge(stationID); ok && (summary.LastChargeAt == nil || last.After(*summary.LastChargeAt)) {
+		summary.LastChargeAt = &last
+	}
+	if summary.LastChargeAt != nil {
+		hours := now.Sub(*summary.LastChargeAt).Hours()
+		summary.HoursSinceCharge = &hours
+	}
+
+	if uc.alerts != nil {
+		for _, a := range uc.alerts.GetActiveAlerts() {
+			if a.StationID == nil || *a.StationID != stationID {
+				continue
+			}
+			switch a.Rule.Field {
+			case entity.MetricBatterySoc, "voltage":
+				summary.LowBattery = true
+			case entity.MetricHoursSinceCharge:
+				summary.ChargingFailure = true
+			default:
+				continue
+			}
+			summary.Alerts = append(summary.Alerts, a)
+		}
+		sort.Slice(summary.Alerts, func(i, j int) bool { return summary.Alerts[i].OpenedAt.Before(summary.Alerts[j].OpenedAt) })
+	}
+
+	return summary, nil
+}
+
+// batteryState describes the latest reading with a valid battery voltage
+func batteryState(cfg PowerConfig, points []entity.PowerPoint) *entity.BatteryState {
+	for i := len(points) - 1; i >= 0; i-- {
+		p := points[i]
+		if p.Voltage == nil || *p.Voltage <= 0 {
+			continue
+		}
+
+		state := &entity.BatteryState{
+			ReadAt:     p.Timestamp,
+			Voltage:    *p.Voltage,
+			BusVoltage: p.BusVoltage,
+			Current:    p.Current,
+			Soc:        cfg.soc(*p.Voltage),
+			State:      cfg.state(p.Current),
+		}
+		if p.BusVoltage != nil && p.Current != nil {
+			watts := *p.BusVoltage * *p.Current / 1000
+			state.Power = &watts
+		}
+		return state
+	}
+	return nil
+}
+
+// voltageTrend fits a least-squares line to battery voltage over the trend window
+func voltageTrend(cfg PowerConfig, points []entity.PowerPoint, now time.Time) *entity.PowerTrend {
+	from := now.Add(-cfg.TrendWindow)
+
+	var n, sumX, sumY, sumXY, sumXX float64
+	for _, p := range points {
+		if p.Timestamp.Before(from) || p.Voltage == nil || *p.Voltage <= 0 {
+			continue
+		}
+		x := p.Timestamp.Sub(from).Hours()
+		y := *p.Voltage
+		n++
+		sumX += x
+		sumY += y
+		sumXY += x * y
+		sumXX += x * x
+	}
+
+	denom := n*sumXX - sumX*sumX
+	if n < 2 || denom == 0 {
+		return nil
+	}
+
+	slope := (n*sumXY - sumX*sumY) / denom
+	trend := &entity.PowerTrend{
+		WindowHours:  cfg.TrendWindow.Hours(),
+		VoltageSlope: slope,
+		Direction:    entity.BatteryIdle,
+		Samples:      int(n),
+	}
+	deadband := trendDeadband * float64(cfg.Cells)
+	switch {
+	case slope > deadband:
+		trend.Direction = entity.BatteryCharging
+	case slope < -deadband:
+		trend.Direction = entity.BatteryDischarging
+	}
+	return trend
+}
+
+// dailyEnergy integrates bus voltage x current per local day with the
+// trapezoid rule, skipping gaps longer than powerMaxGap
+func dailyEnergy(points []entity.PowerPoint, firstDay, lastDay time.Time) []entity.PowerDaily {
+	byDate := make(map[string]*entity.PowerDaily)
+	day := func(t time.Time) *entity.PowerDaily {
+		date := t.In(firstDay.Location()).Format("2006-01-02")
+		d := byDate[date]
+		if d == nil {
+			d = &entity.PowerDaily{Date: date}
+			byDate[date] = d
+		}
+		return d
+	}
+
+	var prev *entity.PowerPoint
+	for i := range points {
+		p := &points[i]
+		if p.Timestamp.Before(firstDay) {
+			continue
+		}
+		d := day(p.Timestamp)
+
+		if p.Voltage != nil && *p.Voltage > 0 {
+			if d.MinVoltage == nil || *p.Voltage < *d.MinVoltage {
+				v := *p.Voltage
+				d.MinVoltage = &v
+			}
+			if d.MaxVoltage == nil || *p.Voltage > *d.MaxVoltage {
+				v := *p.Voltage
+				d.MaxVoltage = &v
+			}
+		}
+
+		if p.BusVoltage == nil || p.Current == nil {
+			continue
+		}
+		if prev != nil {
+			dt := p.Timestamp.Sub(prev.Timestamp)
+			if dt > 0 && dt <= powerMaxGap {
+				w1 := *prev.BusVoltage * *prev.Current / 1000
+				w2 := *p.BusVoltage * *p.Current / 1000
+				wh := (w1 + w2) / 2 * dt.Hours()
+
+				// attribute the segment to the day it started in
+				seg := day(prev.Timestamp)
+				if wh >= 0 {
+					seg.ConsumedWh += wh
+				} else {
+					seg.ChargedWh += -wh
+				}
+				seg.CoveredHours += dt.Hours()
+			}
+		}
+		prev = p
+	}
+
+	daily := make([]entity.PowerDaily, 0)
+	for d := firstDay; !d.After(lastDay); d = d.AddDate(0, 0, 1) {
+		date := d.Format("2006-01-02")
+		entry := entity.PowerDaily{Date: date}
+		if found := byDate[date]; found != nil {
+			entry = *found
+		}
+		entry.ConsumedWh = round3(entry.ConsumedWh)
+		entry.ChargedWh = round3(entry.ChargedWh)
+		entry.NetWh = round3(entry.ChargedWh - entry.ConsumedWh)
+		entry.CoveredHours = round3(entry.CoveredHours)
+		daily = append(daily, entry)
+	}
+	return daily
+}
+
+func round3(x float64) float64 {
+	return math.Round(x*1000) / 1000
+}
@@ -0,0 +1,61 @@
+package usecase
+
+import (
+	"EWSBE/internal/entity"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestPowerTrackerSeedsFromStoredReadings(t *testing.T) {
+	now := time.Date(2025, 1, 3, 12, 0, 0, 0, time.UTC)
+	charged, idle := 4.15, 3.8
+	stored := []entity.PowerPoint{
+		{Timestamp: now.Add(-60 * time.Hour), Voltage: &idle},
+		{Timestamp: now.Add(-40 * time.Hour), Voltage: &charged},
+		{Timestamp: now.Add(-time.Hour), Voltage: &idle},
+	}
+	station := uint(1)
+	reading := func(at time.Time) *entity.SensorData {
+		return &entity.SensorData{StationID: &station, Timestamp: at, Voltage: idle, Current: 50}
+	}
+
+	// after a restart the clock continues from the last stored charge
+	tr := newPowerTracker(PowerConfig{}, func(uint, time.Time, time.Time) ([]entity.PowerPoint, error) {
+		return stored, nil
+	})
+	if got := tr.Observe(reading(now))[entity.MetricHoursSinceCharge]; got != 40 {
+		t.Errorf("hours since charge = %v, want 40", got)
+	}
+
+	// without a stored charge it runs from the oldest stored reading
+	stored = stored[2:]
+	stored = append([]entity.PowerPoint{{Timestamp: now.Add(-50 * time.Hour), Voltage: &idle}}, stored...)
+	tr = newPowerTracker(PowerConfig{}, func(uint, time.Time, time.Time) ([]entity.PowerPoint, error) {
+		return stored, nil
+	})
+	if got := tr.Observe(reading(now))[entity.MetricHoursSinceCharge]; got != 50 {
+		t.Errorf("hours since charge without a charge = %v, want 50", got)
+	}
+
+	// a failed lookup reports no clock rather than one restarted at zero
+	fail := true
+	tr = newPowerTracker(PowerConfig{}, func(uint, time.Time, time.Time) ([]entity.PowerPoint, error) {
+		if fail {
+			return nil, errors.New("connection refused")
+		}
+		return nil, nil
+	})
+	metrics := tr.Observe(reading(now))
+	if _, ok := metrics[entity.MetricHoursSinceCharge]; ok {
+		t.Errorf("hours since charge reported without history: %v", metrics)
+	}
+	if _, ok := metrics[entity.MetricBatterySoc]; !ok {
+		t.Error("state of charge dropped with the history")
+	}
+	fail = false
+	tr.Observe(reading(now))
+	if got := tr.Observe(reading(now.Add(2 * time.Hour)))[entity.MetricHoursSinceCharge]; got != 2 {
+		t.Errorf("hours since charge after a retried lookup = %v, want 2", got)
+	}
+}
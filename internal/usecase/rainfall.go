@@ -0,0 +1,117 @@
+package usecase
+
+import (
+	"EWSBE/internal/entity"
+	"time"
+)
+
+// rolling accumulation windows reported by GetRainfallSummary
+var rainfallWindows = []struct {
+	label    string
+	duration time.Duration
+}{
+	{"1h", time.Hour},
+	{"3h", 3 * time.Hour},
+	{"6h", 6 * time.Hour},
+	{"24h", 24 * time.Hour},
+}
+
+// GetRainfallSummary returns rolling accumulations ending now, daily totals
+// and rain events over the last days for one station. A dry spell of at
+// least eventGap ends an event.
+func (uc *DataUsecase) GetRainfallSummary(stationID uint, days int, eventGap time.Duration) (*entity.RainfallSummary, error) {
+	now := time.Now()
+	startOfToday := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	start := startOfToday.AddDate(0, 0, -(days - 1))
+	if w := now.Add(-24 * time.Hour); w.Before(start) {
+		start = w
+	}
+
+	points, err := uc.repo.GetRainfall(stationID, start, now)
+	if err != nil {
+		return nil, err
+	}
+
+	summary := &entity.RainfallSummary{
+		AsOf:          now,
+		Accumulations: make(map[string]float64, len(rainfallWindows)),
+		Daily:         make([]entity.RainfallDaily, 0, days),
+		Events:        rainEvents(points, eventGap, now),
+	}
+
+	for _, w := range rainfallWindows {
+		from := now.Add(-w.duration)
+		total := 0.0
+		for _, p := range points {
+			if p.Timestamp.After(from) {
+				total += p.Rainfall
+			}
+		}
+		summary.Accumulations[w.label] = total
+	}
+
+	totals := make(map[string]float64)
+	for _, p := range points {
+		totals[p.Timestamp.In(now.Location()).Format("2006-01-02")] += p.Rainfall
+	}
+	for d := startOfToday.AddDate(0, 0, -(days - 1)); !d.After(startOfToday); d = d.AddDate(0, 0, 1) {
+		date := d.Format("2006-01-02")
+		summary.Daily = append(summary.Daily, entity.RainfallDaily{Date: date, Total: totals[date]})
+	}
+
+	return summary, nil
+}
+
+// rainEvents segments an ascending rainfall series into events
+func rainEvents(points []entity.RainfallPoint, gap time.Duration, now time.Time) []entity.RainEvent {
+	events := []entity.RainEvent{}
+
+	var wet []entity.RainfallPoint
+	flush := func() {
+		if len(wet) == 0 {
+			return
+		}
+		ev := entity.RainEvent{
+			Start:         wet[0].Timestamp,
+			End:           wet[len(wet)-1].Timestamp,
+			PeakIntensity: peakHourly(wet),
+		}
+		for _, p := range wet {
+			ev.Total += p.Rainfall
+		}
+		ev.DurationMinutes = ev.End.Sub(ev.Start).Minutes()
+		ev.Ongoing = now.Sub(ev.End) < gap
+		events = append(events, ev)
+		wet = nil
+	}
+
+	for _, p := range points {
+		if p.Rainfall <= 0 {
+			continue
+		}
+		if len(wet) > 0 && p.Timestamp.Sub(wet[len(wet)-1].Timestamp) >= gap {
+			flush()
+		}
+		wet = append(wet, p)
+	}
+	flush()
+
+	return events
+}
+
+// peakHourly is the largest total within any trailing one-hour window, in mm/h
+func peakHourly(points []entity.RainfallPoint) float64 {
+	peak, sum := 0.0, 0.0
+	left := 0
+	for _, p := range points {
+		sum += p.Rainfall
+		for p.Timestamp.Sub(points[left].Timestamp) >= time.Hour {
+			sum -= points[left].Rainfall
+			left++
+		}
+		if sum > peak {
+			peak = sum
+		}
+	}
+	return peak
+}
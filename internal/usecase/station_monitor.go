@@ -0,0 +1,289 @@
+package usecase
+
+import (
+	"EWSBE/internal/entity"
+	"EWSBE/internal/repository"
+	"context"
+	"log"
+	"sort"
+	"sync"
+	"time"
+)
+
+type MonitorConfig struct {
+	DefaultInterval time.Duration // for stations without an expected interval
+	StaleFactor     float64       // silent for this many intervals -> stale
+	OfflineFactor   float64       // silent for this many intervals -> offline
+	CheckEvery      time.Duration
+}
+
+type heartbeat struct {
+	station entity.Station // heartbeat fields are authoritative here, the rest is a copy
+	dirty   bool           // heartbeat not yet persisted
+}
+
+// StationMonitor tracks when each station last reported and marks silent
+// stations stale or offline
+type StationMonitor struct {
+	repo     repository.StationRepository
+	stations *StationUsecase
+	cfg      MonitorConfig
+
+	mu          sync.Mutex
+	beats       map[uint]*heartbeat
+	subscribers []func(entity.StationStatusEvent)
+}
+
+func NewStationMonitor(repo repository.StationRepository, stations *StationUsecase, cfg MonitorConfig) *StationMonitor {
+	if cfg.DefaultInterval <= 0 {
+		cfg.DefaultInterval = time.Minute
+	}
+	if cfg.StaleFactor <= 0 {
+		cfg.StaleFactor = 2
+	}
+	if cfg.OfflineFactor <= cfg.StaleFactor {
+		cfg.OfflineFactor = cfg.StaleFactor * 2.5
+	}
+	if cfg.CheckEvery <= 0 {
+		cfg.CheckEvery = 15 * time.Second
+	}
+
+	return &StationMonitor{
+		repo:     repo,
+		stations: stations,
+		cfg:      cfg,
+		beats:    make(map[uint]*heartbeat),
+	}
+}
+
+// Load restores the persisted heartbeat of every station
+func (m *StationMonitor) Load() error {
+	stations, err := m.repo.GetAllStations()
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, s := range stations {
+		if s.Status == "" {
+			s.Status = entity.StationUnknown
+		}
+		m.beats[s.ID] = &heartbeat{station: s}
+	}
+	return nil
+}
+
+// Subscribe registers fn to be called on every status change
+func (m *StationMonitor) Subscribe(fn func(entity.StationStatusEvent)) {
+	m.mu.Lock()
+	m.subscribers = append(m.subscribers, fn)
+	m.mu.Unlock()
+}
+
+// Seen records a message from the station received at the given time
+func (m *StationMonitor) Seen(stationID uint, at time.Time) {
+	station, err := m.stations.GetStationByID(stationID)
+	if err != nil {
+		log.Printf("monitor: station %d lookup failed: %v", stationID, err)
+		return
+	}
+
+	m.mu.Lock()
+	hb := m.beat(station)
+	if hb.station.LastSeenAt != nil && at.Before(*hb.station.LastSeenAt) {
+		m.mu.Unlock()
+		return
+	}
+	seen := at
+	hb.station.LastSeenAt = &seen
+	hb.dirty = true
+
+	var events []entity.StationStatusEvent
+	if hb.station.Status != entity.StationOnline {
+		events = append(events, m.transition(hb, entity.StationOnline, at))
+	}
+	subscribers := m.subscribers
+	m.mu.Unlock()
+
+	m.publish(subscribers, events)
+}
+
+// Run checks all stations every CheckEvery until ctx is done, then persists
+// the last heartbeats
+func (m *StationMonitor) Run(ctx context.Context) {
+	ticker := time.NewTicker(m.cfg.CheckEvery)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			m.flush()
+			return
+		case now := <-ticker.C:
+			m.Check(now)
+			m.flush()
+		}
+	}
+}
+
+// Check marks stations that have been silent too long
+func (m *StationMonitor) Check(now time.Time) {
+	m.mu.Lock()
+	var events []entity.StationStatusEvent
+	for _, hb := range m.beats {
+		if hb.station.LastSeenAt == nil {
+			continue
+		}
+
+		status := m.statusAt(hb, now)
+		if status != hb.station.Status {
+			if hb.station.Status == entity.StationOnline {
+				hb.station.GapCount++
+			}
+			events = append(events, m.transition(hb, status, now))
+		}
+	}
+	subscribers := m.subscribers
+	m.mu.Unlock()
+
+	m.publish(subscribers, events)
+}
+
+// Statuses returns the heartbeat of every known station ordered by code
+func (m *StationMonitor) Statuses() ([]entity.StationStatus, error) {
+	stations, err := m.stations.GetAllStations()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	statuses := make([]entity.StationStatus, 0, len(stations))
+	for i := range stations {
+		statuses = append(statuses, m.snapshot(m.beat(&stations[i]), now))
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Code < statuses[j].Code })
+	return statuses, nil
+}
+
+// Status returns the heartbeat of one station
+func (m *StationMonitor) Status(station *entity.Station) entity.StationStatus {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.snapshot(m.beat(station), time.Now())
+}
+
+// Counts returns the number of stations per status
+func (m *StationMonitor) Counts() map[string]int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	counts := map[string]int{
+		entity.StationOnline:  0,
+		entity.StationStale:   0,
+		entity.StationOffline: 0,
+		entity.StationUnknown: 0,
+	}
+	for _, hb := range m.beats {
+		counts[hb.station.Status]++
+	}
+	return counts
+}
+
+// beat returns the state for station, refreshing its non-heartbeat fields
+// so interval changes take effect; callers hold m.mu
+func (m *StationMonitor) beat(station *entity.Station) *heartbeat {
+	hb, ok := m.beats[station.ID]
+	if !ok {
+		hb = &heartbeat{station: *station}
+		if hb.station.Status == "" {
+			hb.station.Status = entity.StationUnknown
+		}
+		m.beats[station.ID] = hb
+		return hb
+	}
+
+	hb.station.Code = station.Code
+	hb.station.Name = station.Name
+	hb.station.ExpectedInterval = station.ExpectedInterval
+	return hb
+}
+
+func (m *StationMonitor) interval(hb *heartbeat) time.Duration {
+	if hb.station.ExpectedInterval > 0 {
+		return time.Duration(hb.station.ExpectedInterval) * time.Second
+	}
+	return m.cfg.DefaultInterval
+}
+
+func (m *StationMonitor) statusAt(hb *heartbeat, now time.Time) string {
+	silent := now.Sub(*hb.station.LastSeenAt)
+	interval := m.interval(hb)
+	switch {
+	case silent >= time.Duration(m.cfg.OfflineFactor*float64(interval)):
+		return entity.StationOffline
+	case silent >= time.Duration(m.cfg.StaleFactor*float64(interval)):
+		return entity.StationStale
+	default:
+		return entity.StationOnline
+	}
+}
+
+// transition changes the status and returns the event; callers hold m.mu
+func (m *StationMonitor) transition(hb *heartbeat, status string, at time.Time) entity.StationStatusEvent {
+	previous := hb.station.Status
+	changed := at
+	hb.station.Status = status
+	hb.station.StatusChangedAt = &changed
+	hb.dirty = true
+	return entity.StationStatusEvent{PreviousStatus: previous, Station: m.snapshot(hb, at)}
+}
+
+func (m *StationMonitor) snapshot(hb *heartbeat, now time.Time) entity.StationStatus {
+	s := entity.StationStatus{
+		StationID:        hb.station.ID,
+		Code:             hb.station.Code,
+		Name:             hb.station.Name,
+		Status:           hb.station.Status,
+		LastSeenAt:       hb.station.LastSeenAt,
+		ExpectedInterval: int(m.interval(hb) / time.Second),
+		StatusChangedAt:  hb.station.StatusChangedAt,
+		GapCount:         hb.station.GapCount,
+	}
+	if s.LastSeenAt != nil {
+		since := now.Sub(*s.LastSeenAt).Seconds()
+		s.SecondsSinceSeen = &since
+	}
+	return s
+}
+
+func (m *StationMonitor) publish(subscribers []func(entity.StationStatusEvent), events []entity.StationStatusEvent) {
+	for _, ev := range events {
+		for _, fn := range subscribers {
+			fn(ev)
+		}
+	}
+}
+
+// flush persists changed heartbeats; last-seen times are written at most
+// once per check instead of on every message
+func (m *StationMonitor) flush() {
+	m.mu.Lock()
+	var pending []entity.Station
+	for _, hb := range m.beats {
+		if hb.dirty {
+			pending = append(pending, hb.station)
+			hb.dirty = false
+		}
+	}
+	m.mu.Unlock()
+
+	for i := range pending {
+		if err := m.repo.UpdateHeartbeat(&pending[i]); err != nil {
+			log.Printf("monitor: failed to persist heartbeat of station %s: %v", pending[i].Code, err)
+		}
+	}
+}
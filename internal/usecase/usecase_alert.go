@@ -0,0 +1,573 @@
+package usecase
+
+import (
+	"EWSBE/internal/entity"
+	"EWSBE/internal/repository"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+)
+
+// built-in rules, seeded once each with their name as seed key; they can be
+// renamed and tuned through the API without being seeded again
+var defaultAlertRules = []entity.AlertRule{
+	{
+		Name:        "flood_level",
+		Description: "Water surface approaching the ultrasonic sensor (distance in cm)",
+		Field:       "distance",
+		Operator:    entity.RuleOperatorBelow,
+		Watch:       floatPtr(150),
+		Warning:     floatPtr(100),
+		Danger:      floatPtr(50),
+		Hysteresis:  10,
+		Enabled:     true,
+	},
+	{
+		Name:        "heavy_rain",
+		Description: "Rainfall per reading interval (mm)",
+		Field:       "rainfall",
+		Operator:    entity.RuleOperatorAbove,
+		Watch:       floatPtr(5),
+		Warning:     floatPtr(10),
+		Danger:      floatPtr(20),
+		Hysteresis:  1,
+		Enabled:     true,
+	},
+	{
+		Name:        "storm_wind",
+		Description: "Wind speed (m/s), Beaufort 6 / 8 / 10",
+		Field:       "windSpeed",
+		Operator:    entity.RuleOperatorAbove,
+		Watch:       floatPtr(10.8),
+		Warning:     floatPtr(17.2),
+		Danger:      floatPtr(24.5),
+		Hysteresis:  1.5,
+		Enabled:     true,
+	},
+	{
+		Name:        "low_battery",
+		Description: "Battery state of charge (%) estimated from voltage",
+		Field:       entity.MetricBatterySoc,
+		Operator:    entity.RuleOperatorBelow,
+		Watch:       floatPtr(30),
+		Warning:     floatPtr(20),
+		Danger:      floatPtr(10),
+		Hysteresis:  3,
+		Enabled:     true,
+	},
+	{
+		Name:        "charging_failure",
+		Description: "Hours since the battery last took charge from the solar panel",
+		Field:       entity.MetricHoursSinceCharge,
+		Operator:    entity.RuleOperatorAbove,
+		Watch:       floatPtr(24),
+		Warning:     floatPtr(36),
+		Danger:      floatPtr(48),
+		Hysteresis:  1,
+		Enabled:     true,
+	},
+}
+
+// alerts are tracked independently for every station and rule pair
+type alertKey struct {
+	stationID uint
+	ruleID    uint
+}
+
+func keyOf(a *entity.Alert) alertKey {
+	k := alertKey{ruleID: a.RuleID}
+	if a.StationID != nil {
+		k.stationID = *a.StationID
+	}
+	return k
+}
+
+type AlertUsecase struct {
+	repo repository.AlertRepository
+
+	mu          sync.Mutex
+	rules       []entity.AlertRule
+	active      map[alertKey]*entity.Alert // open alert per station and rule
+	subscribers []func(entity.AlertEvent)
+}
+
+func NewAlertUsecase(repo repository.AlertRepository) *AlertUsecase {
+	return &AlertUsecase{
+		repo:   repo,
+		active: make(map[alertKey]*entity.Alert),
+	}
+}
+
+// Load seeds the default rules not seeded before and restores open alerts
+// so the engine resumes where it left off after a restart
+func (uc *AlertUsecase) Load() error {
+	rules, err := uc.repo.GetRules()
+	if err != nil {
+		return err
+	}
+	if rules, err = uc.seedRules(rules); err != nil {
+		return err
+	}
+
+	open, err := uc.repo.GetOpenAlerts()
+	if err != nil {
+		return err
+	}
+
+	uc.mu.Lock()
+	defer uc.mu.Unlock()
+
+	uc.rules = rules
+	for i := range open {
+		uc.active[keyOf(&open[i])] = &open[i]
+	}
+	return nil
+}
+
+// seedRules creates the default rules whose seed key no stored rule carries.
+// Rules seeded before seed keys existed are adopted instead when they still
+// carry the default's name.
+func (uc *AlertUsecase) seedRules(rules []entity.AlertRule) ([]entity.AlertRule, error) {
+	seeded := make(map[string]bool, len(rules))
+	for _, r := range rules {
+		if r.SeedKey != nil {
+			seeded[*r.SeedKey] = true
+		}
+	}
+
+	for i := range defaultAlertRules {
+		rule := defaultAlertRules[i]
+		key := rule.Name
+		if seeded[key] {
+			continue
+		}
+
+		if j := adoptableRule(rules, &rule); j >= 0 {
+			rules[j].SeedKey = &key
+			if err := uc.repo.UpdateRule(&rules[j]); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		rule.SeedKey = &key
+		if err := uc.repo.CreateRule(&rule); err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// adoptableRule returns the index of the unkeyed rule named like def, or -1
+func adoptableRule(rules []entity.AlertRule, def *entity.AlertRule) int {
+	for i := range rules {
+		if rules[i].SeedKey == nil && rules[i].Name == def.Name {
+			return i
+		}
+	}
+	return -1
+}
+
+// Subscribe registers a callback invoked for every alert state change
+func (uc *AlertUsecase) Subscribe(fn func(entity.AlertEvent)) {
+	uc.mu.Lock()
+	defer uc.mu.Unlock()
+	uc.subscribers = append(uc.subscribers, fn)
+}
+
+// Evaluate runs every enabled rule against a stored reading, raising,
+// escalating and clearing alerts as needed
+func (uc *AlertUsecase) Evaluate(d *entity.SensorData) error {
+	return uc.evaluate(d.StationID, d.ID, func(field string) (float64, bool) {
+		value, ok := d.FieldValue(field)
+		return value, ok && !d.Quality.IsBad(field)
+	})
+}
+
+// EvaluateMetrics runs rules on derived values such as battery state of
+// charge; readingID is the reading the values were derived from
+func (uc *AlertUsecase) EvaluateMetrics(stationID *uint, readingID uint, metrics map[string]float64) error {
+	return uc.evaluate(stationID, readingID, func(field string) (float64, bool) {
+		value, ok := metrics[field]
+		return value, ok
+	})
+}
+
+func (uc *AlertUsecase) evaluate(stationID *uint, readingID uint, lookup func(field string) (float64, bool)) error {
+	uc.mu.Lock()
+	var events []entity.AlertEvent
+	var firstErr error
+
+	for i := range uc.rules {
+		rule := uc.rules[i]
+		if !rule.Enabled {
+			continue
+		}
+
+		value, ok := lookup(rule.Field)
+		if !ok {
+			continue
+		}
+
+		event, err := uc.apply(rule, stationID, value, readingID)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		if event != nil {
+			events = append(events, *event)
+		}
+	}
+
+	uc.mu.Unlock()
+
+	uc.publish(events...)
+	return firstErr
+}
+
+// publish delivers events to subscribers; must be called without uc.mu held
+func (uc *AlertUsecase) publish(events ...entity.AlertEvent) {
+	uc.mu.Lock()
+	subscribers := uc.subscribers
+	uc.mu.Unlock()
+
+	for _, ev := range events {
+		for _, fn := range subscribers {
+			fn(ev)
+		}
+	}
+}
+
+// apply moves the rule's alert to the level implied by value; caller holds uc.mu
+func (uc *AlertUsecase) apply(rule entity.AlertRule, stationID *uint, value float64, readingID uint) (*entity.AlertEvent, error) {
+	key := alertKey{ruleID: rule.ID}
+	if stationID != nil {
+		key.stationID = *stationID
+	}
+
+	current := entity.SeverityNormal
+	alert := uc.active[key]
+	if alert != nil {
+		current = alert.Severity
+	}
+
+	level := rule.Level(value, current)
+	if alert != nil {
+		alert.LastValue = value
+	}
+
+	if level == current {
+		return nil, nil
+	}
+
+	now := time.Now()
+
+	// raise
+	if alert == nil {
+		alert = &entity.Alert{
+			RuleID:       rule.ID,
+			StationID:    stationID,
+			Status:       entity.AlertStatusOpen,
+			Severity:     level,
+			PeakSeverity: level,
+			Value:        value,
+			LastValue:    value,
+			ReadingID:    readingID,
+			OpenedAt:     now,
+		}
+		if err := uc.repo.CreateAlert(alert); err != nil {
+			return nil, err
+		}
+		alert.Rule = rule
+		uc.active[key] = alert
+		return &entity.AlertEvent{Type: entity.AlertEventOpened, PreviousSeverity: current, Alert: *alert}, nil
+	}
+
+	eventType := entity.AlertEventEscalated
+	switch {
+	case level == entity.SeverityNormal:
+		eventType = entity.AlertEventCleared
+		alert.Status = entity.AlertStatusResolved
+		alert.ResolvedAt = &now
+	case level.Rank() < current.Rank():
+		eventType = entity.AlertEventDeescalated
+	default:
+		// an escalation needs a fresh acknowledgement
+		alert.Status = entity.AlertStatusOpen
+	}
+
+	alert.Severity = level
+	if level.Rank() > alert.PeakSeverity.Rank() {
+		alert.PeakSeverity = level
+	}
+
+	if err := uc.repo.UpdateAlert(alert); err != nil {
+		return nil, err
+	}
+
+	if eventType == entity.AlertEventCleared {
+		delete(uc.active, key)
+	}
+
+	alert.Rule = rule
+	return &entity.AlertEvent{Type: eventType, PreviousSeverity: current, Alert: *alert}, nil
+}
+
+func (uc *AlertUsecase) GetRules() []entity.AlertRule {
+	uc.mu.Lock()
+	defer uc.mu.Unlock()
+
+	rules := make([]entity.AlertRule, len(uc.rules))
+	copy(rules, uc.rules)
+	return rules
+}
+
+func (uc *AlertUsecase) CreateRule(rule *entity.AlertRule) error {
+	if err := validateAlertRule(rule); err != nil {
+		return err
+	}
+	rule.SeedKey = nil // only built-in rules carry one
+
+	if err := uc.repo.CreateRule(rule); err != nil {
+		return err
+	}
+
+	uc.mu.Lock()
+	uc.rules = append(uc.rules, *rule)
+	uc.mu.Unlock()
+	return nil
+}
+
+// UpdateRule applies the fields present in input and leaves the rest as they are
+func (uc *AlertUsecase) UpdateRule(id uint, input *entity.AlertRuleUpdate) (*entity.AlertRule, error) {
+	rule, err := uc.repo.GetRuleByID(id)
+	if err != nil {
+		return nil, errors.New("rule not found")
+	}
+
+	if input.Name != nil {
+		rule.Name = *input.Name
+	}
+	if input.Description != nil {
+		rule.Description = *input.Description
+	}
+	if input.Field != nil {
+		rule.Field = *input.Field
+	}
+	if input.Operator != nil {
+		rule.Operator = *input.Operator
+	}
+	if input.Watch.Set {
+		rule.Watch = input.Watch.Value
+	}
+	if input.Warning.Set {
+		rule.Warning = input.Warning.Value
+	}
+	if input.Danger.Set {
+		rule.Danger = input.Danger.Value
+	}
+	if input.Hysteresis != nil {
+		rule.Hysteresis = *input.Hysteresis
+	}
+	if input.Enabled != nil {
+		rule.Enabled = *input.Enabled
+	}
+
+	if err := validateAlertRule(rule); err != nil {
+		return nil, err
+	}
+
+	if err := uc.repo.UpdateRule(rule); err != nil {
+		return nil, err
+	}
+
+	uc.mu.Lock()
+	for i := range uc.rules {
+		if uc.rules[i].ID == rule.ID {
+			uc.rules[i] = *rule
+		}
+	}
+
+	// a disabled rule can no longer clear its own alerts, so close them now
+	var events []entity.AlertEvent
+	for key, alert := range uc.active {
+		if key.ruleID != rule.ID || rule.Enabled {
+			continue
+		}
+		prev := alert.Severity
+		now := time.Now()
+		alert.Status = entity.AlertStatusResolved
+		alert.Severity = entity.SeverityNormal
+		alert.ResolvedAt = &now
+		if err := uc.repo.UpdateAlert(alert); err != nil {
+			uc.mu.Unlock()
+			return nil, err
+		}
+		delete(uc.active, key)
+		alert.Rule = *rule
+		events = append(events, entity.AlertEvent{Type: entity.AlertEventCleared, PreviousSeverity: prev, Alert: *alert})
+	}
+	uc.mu.Unlock()
+
+	uc.publish(events...)
+	return rule, nil
+}
+
+// GetActiveAlerts returns alerts that are open or acknowledged
+func (uc *AlertUsecase) GetActiveAlerts() []entity.Alert {
+	uc.mu.Lock()
+	defer uc.mu.Unlock()
+
+	alerts := make([]entity.Alert, 0, len(uc.active))
+	for _, a := range uc.active {
+		alerts = append(alerts, *a)
+	}
+	return alerts
+}
+
+func (uc *AlertUsecase) GetAlerts(filter entity.AlertFilter) ([]entity.Alert, int64, error) {
+	if filter.Limit <= 0 || filter.Limit > 500 {
+		filter.Limit = 100
+	}
+	return uc.repo.GetAlerts(filter)
+}
+
+func (uc *AlertUsecase) GetAlertByID(id uint) (*entity.Alert, error) {
+	alert, err := uc.repo.GetAlertByID(id)
+	if err != nil {
+		return nil, errors.New("alert not found")
+	}
+	return alert, nil
+}
+
+// Acknowledge records that userID has taken ownership of an active alert
+func (uc *AlertUsecase) Acknowledge(id, userID uint, comment string) (*entity.Alert, error) {
+	uc.mu.Lock()
+	alert, err := uc.activeAlert(id)
+	if err != nil {
+		uc.mu.Unlock()
+		return nil, err
+	}
+	if alert.Status == entity.AlertStatusAcknowledged {
+		uc.mu.Unlock()
+		return nil, errors.New("alert already acknowledged")
+	}
+
+	now := time.Now()
+	alert.Status = entity.AlertStatusAcknowledged
+	alert.AcknowledgedAt = &now
+	alert.AcknowledgedByID = &userID
+	alert.AckComment = comment
+
+	if err := uc.repo.UpdateAlert(alert); err != nil {
+		uc.mu.Unlock()
+		return nil, err
+	}
+	event := entity.AlertEvent{Type: entity.AlertEventAcknowledged, PreviousSeverity: alert.Severity, Alert: *alert}
+	uc.mu.Unlock()
+
+	uc.publish(event)
+	return uc.GetAlertByID(id)
+}
+
+// Resolve closes an active alert manually. If the condition persists the
+// next reading opens a new alert.
+func (uc *AlertUsecase) Resolve(id, userID uint, comment string) (*entity.Alert, error) {
+	uc.mu.Lock()
+	alert, err := uc.activeAlert(id)
+	if err != nil {
+		uc.mu.Unlock()
+		return nil, err
+	}
+
+	prev := alert.Severity
+	now := time.Now()
+	alert.Status = entity.AlertStatusResolved
+	alert.Severity = entity.SeverityNormal
+	alert.ResolvedAt = &now
+	alert.ResolvedByID = &userID
+	alert.ResolveComment = comment
+
+	if err := uc.repo.UpdateAlert(alert); err != nil {
+		uc.mu.Unlock()
+		return nil, err
+	}
+	delete(uc.active, keyOf(alert))
+	event := entity.AlertEvent{Type: entity.AlertEventResolved, PreviousSeverity: prev, Alert: *alert}
+	uc.mu.Unlock()
+
+	uc.publish(event)
+	return uc.GetAlertByID(id)
+}
+
+// activeAlert finds an unresolved alert by ID; caller holds uc.mu
+func (uc *AlertUsecase) activeAlert(id uint) (*entity.Alert, error) {
+	for _, a := range uc.active {
+		if a.ID == id {
+			return a, nil
+		}
+	}
+
+	if _, err := uc.repo.GetAlertByID(id); err != nil {
+		return nil, errors.New("alert not found")
+	}
+	return nil, errors.New("alert already resolved")
+}
+
+func validateAlertRule(rule *entity.AlertRule) error {
+	if strings.TrimSpace(rule.Name) == "" {
+		return errors.New("rule name cannot be empty")
+	}
+
+	probe := &entity.SensorData{WaterLevel: floatPtr(0), WaterLevelRate: floatPtr(0)}
+	if _, ok := probe.FieldValue(rule.Field); !ok && !isDerivedField(rule.Field) {
+		return errors.New("unknown rule field: " + rule.Field)
+	}
+
+	if rule.Operator != entity.RuleOperatorAbove && rule.Operator != entity.RuleOperatorBelow {
+		return errors.New("operator must be 'above' or 'below'")
+	}
+
+	if rule.Hysteresis < 0 {
+		return errors.New("hysteresis cannot be negative")
+	}
+
+	// thresholds must get stricter with each level
+	var prev *float64
+	for _, t := range []*float64{rule.Watch, rule.Warning, rule.Danger} {
+		if t == nil {
+			continue
+		}
+		if prev != nil {
+			if rule.Operator == entity.RuleOperatorAbove && *t < *prev {
+				return errors.New("thresholds must increase from watch to danger")
+			}
+			if rule.Operator == entity.RuleOperatorBelow && *t > *prev {
+				return errors.New("thresholds must decrease from watch to danger")
+			}
+		}
+		prev = t
+	}
+	if prev == nil {
+		return errors.New("at least one threshold is required")
+	}
+
+	return nil
+}
+
+func floatPtr(v float64) *float64 {
+	return &v
+}
+
+func isDerivedField(field string) bool {
+	for _, f := range entity.DerivedAlertFields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
@@ -5,45 +5,152 @@ import (
 	"EWSBE/internal/repository"
 	"errors"
 	"strings"
+	"time"
 
 	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
 )
 
+// who may use POST /auth/register
+const (
+	RegistrationOpen     = "open"     // anyone, as a viewer
+	RegistrationInvite   = "invite"   // only with an invite code, which sets the role
+	RegistrationDisabled = "disabled" // accounts are created by admins
+)
+
+const minPasswordLength = 8
+
 type AuthUsecase struct {
-	userRepo repository.UserRepository
+	userRepo     repository.UserRepository
+	registration string
+	guard        *LoginGuard
 }
 
-func NewAuthUsecase(userRepo repository.UserRepository) *AuthUsecase {
-	return &AuthUsecase{userRepo: userRepo}
+func NewAuthUsecase(userRepo repository.UserRepository, registration string, guard *LoginGuard) *AuthUsecase {
+	switch registration {
+	case RegistrationOpen, RegistrationInvite, RegistrationDisabled:
+	default:
+		registration = RegistrationInvite
+	}
+	return &AuthUsecase{userRepo: userRepo, registration: registration, guard: guard}
 }
 
-func (uc *AuthUsecase) Register(username, password string) error {
-	if strings.TrimSpace(username) == "" || strings.TrimSpace(password) == "" {
-		return errors.New("username and password cannot be empty")
+// RegistrationMode returns how self-registration is configured
+func (uc *AuthUsecase) RegistrationMode() string {
+	return uc.registration
+}
+
+func (uc *AuthUsecase) Register(username, password, inviteCode string) error {
+	switch uc.registration {
+	case RegistrationDisabled:
+		return errors.New("registration is disabled")
+	case RegistrationOpen:
+		if inviteCode == "" {
+			_, err := createUser(uc.userRepo, username, password, entity.RoleViewer)
+			return err
+		}
 	}
 
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	// invite mode, or an invite used while registration is open
+	if inviteCode == "" {
+		return errors.New("an invite code is required")
+	}
+	invite, err := uc.userRepo.GetInviteByHash(hashSecret(strings.TrimSpace(inviteCode)))
+	if err != nil || invite.UsedAt != nil || time.Now().After(invite.ExpiresAt) {
+		return errors.New("invalid or expired invite code")
+	}
+	if err := validateCredentials(username, password); err != nil {
+		return err
+	}
+
+	claimed, err := uc.userRepo.ClaimInvite(invite.ID, time.Now())
 	if err != nil {
 		return err
 	}
+	if !claimed {
+		return errors.New("invalid or expired invite code")
+	}
 
-	user := &entity.User{
-		Username: username,
-		Password: string(hashedPassword),
+	user, err := createUser(uc.userRepo, username, password, invite.Role)
+	if err != nil {
+		// give the invite back so the user can retry with another username
+		invite.UsedAt = nil
+		if releaseErr := uc.userRepo.UpdateInvite(invite); releaseErr != nil {
+			return releaseErr
+		}
+		return err
 	}
 
-	return uc.userRepo.CreateUser(user)
+	invite.UsedAt = &user.CreatedAt
+	invite.UsedByID = &user.ID
+	return uc.userRepo.UpdateInvite(invite)
 }
 
-func (uc *AuthUsecase) Login(username, password string) (*entity.User, error) {
+// Login checks credentials from a client at ip. Repeated failures make the
+// username and IP wait, reported as a *LoginBlockedError.
+func (uc *AuthUsecase) Login(username, password, ip, userAgent string) (*entity.User, error) {
+	if err := uc.guard.Begin(username, ip); err != nil {
+		return nil, err
+	}
+
 	user, err := uc.userRepo.GetUserByUsername(username)
 	if err != nil {
+		uc.guard.Record(username, ip, userAgent, nil, entity.LoginUnknownUser)
 		return nil, errors.New("invalid username or password")
 	}
 
 	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
+		uc.guard.Record(username, ip, userAgent, &user.ID, entity.LoginBadPassword)
 		return nil, errors.New("invalid username or password")
 	}
 
+	if user.DisabledAt != nil {
+		uc.guard.Record(username, ip, userAgent, &user.ID, entity.LoginDisabled)
+		return nil, errors.New("account disabled")
+	}
+
+	uc.guard.Record(username, ip, userAgent, &user.ID, entity.LoginOK)
+	return user, nil
+}
+
+func validateCredentials(username, password string) error {
+	if strings.TrimSpace(username) == "" || strings.TrimSpace(password) == "" {
+		return errors.New("username and password cannot be empty")
+	}
+	return validatePassword(password)
+}
+
+func validatePassword(password string) error {
+	if len(password) < minPasswordLength {
+		return errors.New("password must be at least 8 characters")
+	}
+	if len(password) > 72 {
+		return errors.New("password must be at most 72 bytes") // bcrypt limit
+	}
+	return nil
+}
+
+// createUser validates and stores a new account with a hashed password
+func createUser(repo repository.UserRepository, username, password, role string) (*entity.User, error) {
+	if err := validateCredentials(username, password); err != nil {
+		return nil, err
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+
+	user := &entity.User{
+		Username: username,
+		Password: string(hashedPassword),
+		Role:     role,
+	}
+	if err := repo.CreateUser(user); err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return nil, errors.New("username already taken")
+		}
+		return nil, err
+	}
 	return user, nil
 }
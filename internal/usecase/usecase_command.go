@@ -0,0 +1,329 @@
+package usecase
+
+import (
+	"EWSBE/internal/entity"
+	"EWSBE/internal/repository"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// CommandPublisher sends downlink messages to the broker
+type CommandPublisher interface {
+	Publish(topic string, qos byte, retained bool, payload interface{}) error
+	IsConnected() bool
+}
+
+type CommandConfig struct {
+	Topic       string // command topic, "{station}" is replaced by the station code
+	QoS         byte
+	Timeout     time.Duration // default wait for the acknowledgement
+	MaxTimeout  time.Duration // upper bound for a per-command timeout
+	SweepEvery  time.Duration // how often overdue commands are marked timed out
+	MinInterval time.Duration // bounds for set_interval
+	MaxInterval time.Duration
+}
+
+func (c CommandConfig) withDefaults() CommandConfig {
+	if c.Topic == "" {
+		c.Topic = "ewsbe/{station}/cmd"
+	}
+	if c.Timeout <= 0 {
+		c.Timeout = time.Minute
+	}
+	if c.MaxTimeout < c.Timeout {
+		c.MaxTimeout = c.Timeout
+	}
+	if c.SweepEvery <= 0 {
+		c.SweepEvery = 5 * time.Second
+	}
+	if c.MinInterval <= 0 {
+		c.MinInterval = 5 * time.Second
+	}
+	if c.MaxInterval <= 0 {
+		c.MaxInterval = 24 * time.Hour
+	}
+	if c.MaxInterval < c.MinInterval {
+		c.MaxInterval = c.MinInterval
+	}
+	return c
+}
+
+// request to send a command; Timeout is in seconds, 0 uses the default
+type CommandRequest struct {
+	Type    string                 `json:"type" binding:"required"`
+	Params  map[string]interface{} `json:"params"`
+	Timeout int                    `json:"timeout"`
+}
+
+// CommandUsecase publishes commands to stations and tracks their
+// acknowledgements. A command stays pending until the station acks it or
+// its timeout passes.
+type CommandUsecase struct {
+	repo      repository.CommandRepository
+	stations  *StationUsecase
+	cfg       CommandConfig
+	publisher CommandPublisher
+
+	mu          sync.Mutex // serialises state transitions
+	subscribers []func(entity.StationCommand)
+}
+
+func NewCommandUsecase(repo repository.CommandRepository, stations *StationUsecase, cfg CommandConfig) *CommandUsecase {
+	return &CommandUsecase{repo: repo, stations: stations, cfg: cfg.withDefaults()}
+}
+
+// SetPublisher attaches the MQTT connection; without one Send fails
+func (uc *CommandUsecase) SetPublisher(p CommandPublisher) {
+	uc.mu.Lock()
+	defer uc.mu.Unlock()
+	uc.publisher = p
+}
+
+// Subscribe registers a callback invoked whenever a command changes state
+func (uc *CommandUsecase) Subscribe(fn func(entity.StationCommand)) {
+	uc.mu.Lock()
+	defer uc.mu.Unlock()
+	uc.subscribers = append(uc.subscribers, fn)
+}
+
+// publish delivers state changes to subscribers; must be called without uc.mu held
+func (uc *CommandUsecase) publish(cmds ...entity.StationCommand) {
+	uc.mu.Lock()
+	subscribers := uc.subscribers
+	uc.mu.Unlock()
+
+	for _, cmd := range cmds {
+		for _, fn := range subscribers {
+			fn(cmd)
+		}
+	}
+}
+
+// Topic returns the command topic of a station
+func (uc *CommandUsecase) Topic(code string) string {
+	return strings.ReplaceAll(uc.cfg.Topic, "{station}", code)
+}
+
+// Send stores a command for the station and publishes it
+func (uc *CommandUsecase) Send(code string, userID uint, req CommandRequest) (*entity.StationCommand, error) {
+	station, err := uc.stations.GetStationByCode(code)
+	if err != nil {
+		return nil, errors.New("station not found")
+	}
+
+	params, err := uc.validate(req.Type, req.Params)
+	if err != nil {
+		return nil, err
+	}
+
+	timeout := uc.cfg.Timeout
+	if req.Timeout < 0 || time.Duration(req.Timeout)*time.Second > uc.cfg.MaxTimeout {
+		return nil, fmt.Errorf("timeout must be between 1 and %d seconds", int(uc.cfg.MaxTimeout.Seconds()))
+	}
+	if req.Timeout > 0 {
+		timeout = time.Duration(req.Timeout) * time.Second
+	}
+
+	uc.mu.Lock()
+	publisher := uc.publisher
+	uc.mu.Unlock()
+	if publisher == nil || !publisher.IsConnected() {
+		return nil, errors.New("mqtt not connected")
+	}
+
+	now := time.Now()
+	if req.Type == entity.CommandSyncClock {
+		params = map[string]interface{}{"epoch": now.UnixMilli()}
+	}
+
+	cmd := &entity.StationCommand{
+		CommandID:  uuid.NewString(),
+		StationID:  station.ID,
+		Type:       req.Type,
+		Params:     params,
+		Topic:      uc.Topic(station.Code),
+		Status:     entity.CommandPending,
+		IssuedByID: userID,
+		SentAt:     &now,
+		ExpiresAt:  now.Add(timeout),
+	}
+
+	payload, err := json.Marshal(entity.CommandMessage{
+		ID:      cmd.CommandID,
+		Cmd:     cmd.Type,
+		Params:  cmd.Params,
+		Ts:      now.UnixMilli(),
+		Expires: cmd.ExpiresAt.UnixMilli(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// stored before publishing so a fast acknowledgement finds it
+	if err := uc.repo.CreateCommand(cmd); err != nil {
+		return nil, err
+	}
+
+	if err := publisher.Publish(cmd.Topic, uc.cfg.QoS, false, payload); err != nil {
+		uc.mu.Lock()
+		cmd.Status = entity.CommandFailed
+		cmd.Error = err.Error()
+		cmd.SentAt = nil
+		updateErr := uc.repo.UpdateCommand(cmd)
+		uc.mu.Unlock()
+		if updateErr != nil {
+			log.Printf("command %s: failed to record publish error: %v", cmd.CommandID, updateErr)
+		}
+	}
+
+	uc.publish(*cmd)
+	return uc.GetCommandByID(cmd.ID)
+}
+
+// HandleAck records the acknowledgement a station published for a command.
+// Late acknowledgements still update timed out commands, the device did act.
+func (uc *CommandUsecase) HandleAck(code string, ack entity.CommandAck) error {
+	if ack.ID == "" {
+		return errors.New("ack without command id")
+	}
+
+	uc.mu.Lock()
+	cmd, err := uc.repo.GetCommandByCommandID(ack.ID)
+	if err != nil {
+		uc.mu.Unlock()
+		return errors.New("command not found")
+	}
+
+	if code != "" {
+		station, err := uc.stations.GetStationByCode(code)
+		if err != nil || station.ID != cmd.StationID {
+			uc.mu.Unlock()
+			return fmt.Errorf("command %s was not sent to station %q", ack.ID, code)
+		}
+	}
+
+	if cmd.Status != entity.CommandPending && cmd.Status != entity.CommandTimedOut {
+		uc.mu.Unlock()
+		return nil // duplicate delivery
+	}
+
+	now := time.Now()
+	cmd.AckedAt = &now
+	cmd.Result = ack.Result
+	switch strings.ToLower(ack.Status) {
+	case "", "ok":
+		cmd.Status = entity.CommandAcked
+		cmd.Error = ""
+	default:
+		cmd.Status = entity.CommandFailed
+		cmd.Error = ack.Message
+		if cmd.Error == "" {
+			cmd.Error = "rejected by device: " + ack.Status
+		}
+	}
+
+	if err := uc.repo.UpdateCommand(cmd); err != nil {
+		uc.mu.Unlock()
+		return err
+	}
+	uc.mu.Unlock()
+
+	uc.publish(*cmd)
+	return nil
+}
+
+// Run marks overdue commands as timed out until ctx is cancelled
+func (uc *CommandUsecase) Run(ctx context.Context) {
+	ticker := time.NewTicker(uc.cfg.SweepEvery)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if err := uc.Sweep(now); err != nil {
+				log.Printf("command: sweep failed: %v", err)
+			}
+		}
+	}
+}
+
+// Sweep times out pending commands whose deadline is before now
+func (uc *CommandUsecase) Sweep(now time.Time) error {
+	uc.mu.Lock()
+	expired, err := uc.repo.GetExpiredCommands(now)
+	if err != nil {
+		uc.mu.Unlock()
+		return err
+	}
+
+	var changed []entity.StationCommand
+	for i := range expired {
+		cmd := &expired[i]
+		cmd.Status = entity.CommandTimedOut
+		cmd.Error = "no acknowledgement before timeout"
+		if err := uc.repo.UpdateCommand(cmd); err != nil {
+			uc.mu.Unlock()
+			uc.publish(changed...)
+			return err
+		}
+		changed = append(changed, *cmd)
+	}
+	uc.mu.Unlock()
+
+	uc.publish(changed...)
+	return nil
+}
+
+func (uc *CommandUsecase) GetCommands(filter entity.CommandFilter) ([]entity.StationCommand, int64, error) {
+	if filter.Limit <= 0 || filter.Limit > 500 {
+		filter.Limit = 100
+	}
+	return uc.repo.GetCommands(filter)
+}
+
+func (uc *CommandUsecase) GetCommandByID(id uint) (*entity.StationCommand, error) {
+	cmd, err := uc.repo.GetCommandByID(id)
+	if err != nil {
+		return nil, errors.New("command not found")
+	}
+	return cmd, nil
+}
+
+// validate checks the command type and returns the params sent to the device
+func (uc *CommandUsecase) validate(cmdType string, params map[string]interface{}) (map[string]interface{}, error) {
+	switch cmdType {
+	case entity.CommandSetInterval:
+		v, ok := params["seconds"].(float64)
+		if !ok || v != math.Trunc(v) {
+			return nil, errors.New("set_interval needs an integer params.seconds")
+		}
+		min, max := uc.cfg.MinInterval.Seconds(), uc.cfg.MaxInterval.Seconds()
+		if v < min || v > max {
+			return nil, fmt.Errorf("interval must be between %d and %d seconds", int(min), int(max))
+		}
+		if len(params) > 1 {
+			return nil, errors.New("set_interval only takes params.seconds")
+		}
+		return map[string]interface{}{"seconds": int(v)}, nil
+
+	case entity.CommandResetRain, entity.CommandReboot, entity.CommandReadNow, entity.CommandSyncClock:
+		// sync_clock params are filled in when sending
+		if len(params) > 0 {
+			return nil, fmt.Errorf("%s takes no params", cmdType)
+		}
+		return nil, nil
+	}
+
+	return nil, fmt.Errorf("unknown command type %q (want one of %s)", cmdType, strings.Join(entity.CommandTypes, ", "))
+}
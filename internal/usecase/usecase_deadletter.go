@@ -0,0 +1,190 @@
+package usecase
+
+import (
+	"EWSBE/internal/entity"
+	"EWSBE/internal/repository"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// max dead letters per replay request
+const maxReplayBatch = 500
+
+// DeadLetterReplayer runs a dead letter through the ingestion pipeline again
+// and returns the ID of the stored reading; for a reading that was already
+// stored it returns ErrDuplicateReading with the stored reading's ID, if known
+type DeadLetterReplayer func(dl entity.DeadLetter) (uint, error)
+
+type DeadLetterConfig struct {
+	Retention  time.Duration // how long dead letters are kept
+	PurgeEvery time.Duration
+}
+
+func (c DeadLetterConfig) withDefaults() DeadLetterConfig {
+	if c.Retention <= 0 {
+		c.Retention = 30 * 24 * time.Hour
+	}
+	if c.PurgeEvery <= 0 {
+		c.PurgeEvery = time.Hour
+	}
+	return c
+}
+
+// DeadLetterUsecase keeps MQTT messages the ingestion pipeline rejected so
+// they can be inspected and replayed after a decoder or schema fix
+type DeadLetterUsecase struct {
+	repo repository.DeadLetterRepository
+	cfg  DeadLetterConfig
+
+	mu       sync.Mutex // serialises replays so a letter is not stored twice
+	replayer DeadLetterReplayer
+}
+
+func NewDeadLetterUsecase(repo repository.DeadLetterRepository, cfg DeadLetterConfig) *DeadLetterUsecase {
+	return &DeadLetterUsecase{repo: repo, cfg: cfg.withDefaults()}
+}
+
+// SetReplayer attaches the ingestion pipeline; without one Replay fails
+func (uc *DeadLetterUsecase) SetReplayer(fn DeadLetterReplayer) {
+	uc.mu.Lock()
+	defer uc.mu.Unlock()
+	uc.replayer = fn
+}
+
+// Record stores a rejected message
+func (uc *DeadLetterUsecase) Record(topic, pattern string, payload []byte, stage, decoder string, cause error, receivedAt time.Time) error {
+	dl := &entity.DeadLetter{
+		Topic:      topic,
+		Pattern:    pattern,
+		Payload:    payload,
+		Size:       len(payload),
+		Stage:      stage,
+		Decoder:    decoder,
+		Status:     entity.DeadLetterPending,
+		ReceivedAt: receivedAt,
+	}
+	if cause != nil {
+		dl.Error = cause.Error()
+	}
+	return uc.repo.CreateDeadLetter(dl)
+}
+
+func (uc *DeadLetterUsecase) GetDeadLetters(filter entity.DeadLetterFilter) ([]entity.DeadLetter, int64, error) {
+	if filter.Limit <= 0 || filter.Limit > 500 {
+		filter.Limit = 100
+	}
+	return uc.repo.GetDeadLetters(filter)
+}
+
+// GetDeadLetterByID returns a dead letter with its payload, also as text
+// when it is printable
+func (uc *DeadLetterUsecase) GetDeadLetterByID(id uint) (*entity.DeadLetter, error) {
+	dl, err := uc.repo.GetDeadLetterByID(id)
+	if err != nil {
+		return nil, errors.New("dead letter not found")
+	}
+	if utf8.Valid(dl.Payload) {
+		dl.PayloadText = string(dl.Payload)
+	}
+	return dl, nil
+}
+
+func (uc *DeadLetterUsecase) DeleteDeadLetter(id uint) error {
+	if _, err := uc.repo.GetDeadLetterByID(id); err != nil {
+		return errors.New("dead letter not found")
+	}
+	return uc.repo.DeleteDeadLetter(id)
+}
+
+// Replay feeds the selected dead letters through the ingestion pipeline in
+// the given order and backfills them as historical readings. Letters that
+// were already replayed are skipped, letters whose reading is already stored
+// are marked duplicate and linked to it, and failures stay pending with the
+// replay error recorded.
+func (uc *DeadLetterUsecase) Replay(ids []uint) ([]entity.DeadLetterReplay, error) {
+	if len(ids) == 0 {
+		return nil, errors.New("no dead letters selected")
+	}
+	if len(ids) > maxReplayBatch {
+		return nil, fmt.Errorf("at most %d dead letters per replay", maxReplayBatch)
+	}
+
+	uc.mu.Lock()
+	defer uc.mu.Unlock()
+	if uc.replayer == nil {
+		return nil, errors.New("ingestion pipeline not available")
+	}
+
+	results := make([]entity.DeadLetterReplay, 0, len(ids))
+	for _, id := range ids {
+		result := entity.DeadLetterReplay{ID: id}
+
+		dl, err := uc.repo.GetDeadLetterByID(id)
+		switch {
+		case err != nil:
+			result.Status = "not_found"
+		case dl.Status == entity.DeadLetterReplayed, dl.Status == entity.DeadLetterDuplicate:
+			result.Status = dl.Status
+			result.ReadingID = dl.ReadingID
+			result.Error = "already replayed"
+		default:
+			readingID, replayErr := uc.replayer(*dl)
+
+			now := time.Now()
+			dl.ReplayCount++
+			dl.LastReplayAt = &now
+			switch {
+			case errors.Is(replayErr, ErrDuplicateReading):
+				dl.Status = entity.DeadLetterDuplicate
+				dl.ReplayError = ""
+				if readingID != 0 {
+					dl.ReadingID = &readingID
+					result.ReadingID = &readingID
+				}
+			case replayErr != nil:
+				dl.ReplayError = replayErr.Error()
+				result.Error = replayErr.Error()
+			default:
+				dl.Status = entity.DeadLetterReplayed
+				dl.ReplayError = ""
+				dl.ReadingID = &readingID
+				result.ReadingID = &readingID
+				result.Backfilled = true
+			}
+			result.Status = dl.Status
+
+			if err := uc.repo.UpdateDeadLetter(dl); err != nil {
+				return results, err
+			}
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// Run deletes dead letters older than the retention period until ctx is
+// cancelled
+func (uc *DeadLetterUsecase) Run(ctx context.Context) {
+	ticker := time.NewTicker(uc.cfg.PurgeEvery)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			uc.purge(now)
+		}
+	}
+}
+
+func (uc *DeadLetterUsecase) purge(now time.Time) {
+	if _, err := uc.repo.DeleteDeadLetters(now.Add(-uc.cfg.Retention)); err != nil {
+		log.Printf("dead letters: purge failed: %v", err)
+	}
+}
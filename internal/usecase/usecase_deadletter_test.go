@@ -0,0 +1,117 @@
+package usecase
+
+import (
+	"EWSBE/internal/entity"
+	"errors"
+	"testing"
+	"time"
+)
+
+// memDeadLetters is an in-memory DeadLetterRepository
+type memDeadLetters struct {
+	letters map[uint]*entity.DeadLetter
+}
+
+func (m *memDeadLetters) CreateDeadLetter(dl *entity.DeadLetter) error {
+	dl.ID = uint(len(m.letters) + 1)
+	cp := *dl
+	m.letters[dl.ID] = &cp
+	return nil
+}
+
+func (m *memDeadLetters) GetDeadLetters(entity.DeadLetterFilter) ([]entity.DeadLetter, int64, error) {
+	return nil, 0, nil
+}
+
+func (m *memDeadLetters) GetDeadLetterByID(id uint) (*entity.DeadLetter, error) {
+	if dl, ok := m.letters[id]; ok {
+		cp := *dl
+		return &cp, nil
+	}
+	return nil, errors.New("record not found")
+}
+
+func (m *memDeadLetters) UpdateDeadLetter(dl *entity.DeadLetter) error {
+	cp := *dl
+	m.letters[dl.ID] = &cp
+	return nil
+}
+
+func (m *memDeadLetters) DeleteDeadLetter(id uint) error {
+	delete(m.letters, id)
+	return nil
+}
+
+func (m *memDeadLetters) DeleteDeadLetters(before time.Time) (int64, error) {
+	var n int64
+	for id, dl := range m.letters {
+		if dl.ReceivedAt.Before(before) {
+			delete(m.letters, id)
+			n++
+		}
+	}
+	return n, nil
+}
+
+func TestReplayMarksDuplicates(t *testing.T) {
+	repo := &memDeadLetters{letters: map[uint]*entity.DeadLetter{}}
+	uc := NewDeadLetterUsecase(repo, DeadLetterConfig{})
+	for i := 0; i < 3; i++ {
+		if err := uc.Record("sensors/ewsbe/st1", "sensors/ewsbe/+", []byte("{}"), entity.DeadLetterStageStore, DecoderJSON, errors.New("timeout"), time.Now()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	outcomes := map[uint]func() (uint, error){
+		1: func() (uint, error) { return 10, nil },
+		2: func() (uint, error) { return 7, ErrDuplicateReading },
+		3: func() (uint, error) { return 0, errors.New("station not found") },
+	}
+	uc.SetReplayer(func(dl entity.DeadLetter) (uint, error) { return outcomes[dl.ID]() })
+
+	results, err := uc.Replay([]uint{1, 2, 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		status    string
+		readingID uint
+	}{{entity.DeadLetterReplayed, 10}, {entity.DeadLetterDuplicate, 7}, {entity.DeadLetterPending, 0}}
+	for i, w := range want {
+		dl := repo.letters[uint(i+1)]
+		if dl.Status != w.status || results[i].Status != w.status {
+			t.Errorf("letter %d: status %s (result %s), want %s", i+1, dl.Status, results[i].Status, w.status)
+		}
+		if w.readingID != 0 && (dl.ReadingID == nil || *dl.ReadingID != w.readingID) {
+			t.Errorf("letter %d: reading %v, want %d", i+1, dl.ReadingID, w.readingID)
+		}
+	}
+	if repo.letters[3].ReplayError != "station not found" {
+		t.Errorf("replay error = %q", repo.letters[3].ReplayError)
+	}
+
+	// a duplicate is resolved and not replayed again
+	outcomes[2] = func() (uint, error) {
+		t.Error("duplicate letter replayed again")
+		return 0, nil
+	}
+	if results, _ := uc.Replay([]uint{2}); results[0].Status != entity.DeadLetterDuplicate {
+		t.Errorf("second replay: %+v", results[0])
+	}
+}
+
+func TestDeadLetterPurge(t *testing.T) {
+	repo := &memDeadLetters{letters: map[uint]*entity.DeadLetter{}}
+	uc := NewDeadLetterUsecase(repo, DeadLetterConfig{Retention: 24 * time.Hour})
+	now := time.Now()
+	uc.Record("t", "t", nil, entity.DeadLetterStageDecode, "", nil, now.Add(-25*time.Hour))
+	uc.Record("t", "t", nil, entity.DeadLetterStageDecode, "", nil, now.Add(-time.Hour))
+
+	uc.purge(now)
+	if _, ok := repo.letters[1]; ok {
+		t.Error("dead letter past the retention period kept")
+	}
+	if _, ok := repo.letters[2]; !ok {
+		t.Error("recent dead letter purged")
+	}
+}
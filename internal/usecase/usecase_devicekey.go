@@ -0,0 +1,159 @@
+package usecase
+
+import (
+	"EWSBE/internal/entity"
+	"EWSBE/internal/repository"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+// DeviceKeyPrefix starts every station API key so it can be told apart from a JWT
+const DeviceKeyPrefix = "ewsk_"
+
+const (
+	deviceKeyShownChars = len(DeviceKeyPrefix) + 8 // stored in the clear to identify a key
+	deviceKeyTouchEvery = time.Minute              // how often last_used_at is written
+	maxRotationGrace    = 7 * 24 * time.Hour
+)
+
+// DeviceKeyUsecase manages the API keys stations use to post readings.
+// Only a SHA-256 of each key is stored; the key is returned once on creation.
+type DeviceKeyUsecase struct {
+	repo     repository.DeviceKeyRepository
+	stations *StationUsecase
+}
+
+func NewDeviceKeyUsecase(repo repository.DeviceKeyRepository, stations *StationUsecase) *DeviceKeyUsecase {
+	return &DeviceKeyUsecase{repo: repo, stations: stations}
+}
+
+// Create issues a new key for the station and returns it with the plaintext key
+func (uc *DeviceKeyUsecase) Create(code, name string, userID uint) (*entity.DeviceKey, string, error) {
+	station, err := uc.stations.GetStationByCode(code)
+	if err != nil {
+		return nil, "", errors.New("station not found")
+	}
+	return uc.issue(station.ID, name, userID)
+}
+
+func (uc *DeviceKeyUsecase) issue(stationID uint, name string, userID uint) (*entity.DeviceKey, string, error) {
+	plain, err := generateSecret(DeviceKeyPrefix)
+	if err != nil {
+		return nil, "", err
+	}
+
+	key := &entity.DeviceKey{
+		StationID:   stationID,
+		Name:        strings.TrimSpace(name),
+		Prefix:      plain[:deviceKeyShownChars],
+		Hash:        hashSecret(plain),
+		CreatedByID: userID,
+	}
+	if err := uc.repo.CreateDeviceKey(key); err != nil {
+		return nil, "", err
+	}
+	return key, plain, nil
+}
+
+func (uc *DeviceKeyUsecase) GetKeys(code string) ([]entity.DeviceKey, error) {
+	station, err := uc.stations.GetStationByCode(code)
+	if err != nil {
+		return nil, errors.New("station not found")
+	}
+	return uc.repo.GetDeviceKeys(station.ID)
+}
+
+// stationKey loads a key and checks it belongs to the station
+func (uc *DeviceKeyUsecase) stationKey(code string, id uint) (*entity.DeviceKey, error) {
+	station, err := uc.stations.GetStationByCode(code)
+	if err != nil {
+		return nil, errors.New("station not found")
+	}
+	key, err := uc.repo.GetDeviceKeyByID(id)
+	if err != nil || key.StationID != station.ID {
+		return nil, errors.New("device key not found")
+	}
+	return key, nil
+}
+
+// Revoke disables a key immediately
+func (uc *DeviceKeyUsecase) Revoke(code string, id uint) (*entity.DeviceKey, error) {
+	key, err := uc.stationKey(code, id)
+	if err != nil {
+		return nil, err
+	}
+	if key.RevokedAt != nil {
+		return key, nil
+	}
+
+	now := time.Now()
+	key.RevokedAt = &now
+	if err := uc.repo.UpdateDeviceKey(key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// Rotate issues a replacement key. The old key keeps working for grace so
+// the station can be reflashed; 0 revokes it at once.
+func (uc *DeviceKeyUsecase) Rotate(code string, id uint, grace time.Duration, userID uint) (*entity.DeviceKey, string, error) {
+	if grace < 0 || grace > maxRotationGrace {
+		return nil, "", fmt.Errorf("grace period must be between 0 and %d seconds", int(maxRotationGrace.Seconds()))
+	}
+
+	old, err := uc.stationKey(code, id)
+	if err != nil {
+		return nil, "", err
+	}
+	now := time.Now()
+	if !old.Active(now) {
+		return nil, "", errors.New("device key is revoked or expired")
+	}
+
+	key, plain, err := uc.issue(old.StationID, old.Name, userID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if grace == 0 {
+		old.RevokedAt = &now
+	} else {
+		expires := now.Add(grace)
+		old.ExpiresAt = &expires
+	}
+	if err := uc.repo.UpdateDeviceKey(old); err != nil {
+		return nil, "", err
+	}
+	return key, plain, nil
+}
+
+// Authenticate returns the key, with its station, for a plaintext key that
+// is currently accepted
+func (uc *DeviceKeyUsecase) Authenticate(plain string) (*entity.DeviceKey, error) {
+	if !strings.HasPrefix(plain, DeviceKeyPrefix) {
+		return nil, errors.New("invalid api key")
+	}
+	key, err := uc.repo.GetDeviceKeyByHash(hashSecret(plain))
+	if err != nil {
+		return nil, errors.New("invalid api key")
+	}
+
+	now := time.Now()
+	if !key.Active(now) {
+		return nil, errors.New("api key revoked or expired")
+	}
+	if key.Station == nil {
+		return nil, errors.New("invalid api key")
+	}
+
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) >= deviceKeyTouchEvery {
+		if err := uc.repo.TouchDeviceKey(key.ID, now); err != nil {
+			log.Printf("device key %d: failed to record use: %v", key.ID, err)
+		}
+		key.LastUsedAt = &now
+	}
+	return key, nil
+}
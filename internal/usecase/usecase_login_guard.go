@@ -0,0 +1,294 @@
+package usecase
+
+import (
+	"EWSBE/internal/entity"
+	"EWSBE/internal/repository"
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+)
+
+const (
+	maxLockoutKey = 128 // longest username kept as a lockout key
+	maxUserAgent  = 256 // longest user agent kept in the audit trail
+)
+
+// LoginBlockedError is returned while a username or IP has to wait before
+// trying again
+type LoginBlockedError struct {
+	RetryAfter time.Duration
+	Locked     bool // locked out rather than just slowed down
+}
+
+func (e *LoginBlockedError) Error() string {
+	if e.Locked {
+		return "too many failed login attempts, account temporarily locked"
+	}
+	return "too many failed login attempts, try again later"
+}
+
+type LoginGuardConfig struct {
+	FreeAttempts   int           // failures allowed before any delay
+	BaseDelay      time.Duration // delay after the first counted failure, doubled per failure
+	MaxDelay       time.Duration
+	UsernameLockAt int           // failures that lock a username
+	IPLockAt       int           // failures that lock an IP, higher as IPs may be shared
+	LockFor        time.Duration // lockout period
+	Window         time.Duration // failures older than this are forgotten
+	Retention      time.Duration // how long login attempts are kept in the audit trail
+}
+
+func (c LoginGuardConfig) withDefaults() LoginGuardConfig {
+	if c.FreeAttempts <= 0 {
+		c.FreeAttempts = 3
+	}
+	if c.BaseDelay <= 0 {
+		c.BaseDelay = time.Second
+	}
+	if c.MaxDelay < c.BaseDelay {
+		c.MaxDelay = 30 * time.Second
+	}
+	if c.UsernameLockAt <= c.FreeAttempts {
+		c.UsernameLockAt = 10
+	}
+	if c.IPLockAt <= c.FreeAttempts {
+		c.IPLockAt = 50
+	}
+	if c.LockFor <= 0 {
+		c.LockFor = 15 * time.Minute
+	}
+	if c.Window <= 0 {
+		c.Window = time.Hour
+	}
+	if c.Retention <= 0 {
+		c.Retention = 90 * 24 * time.Hour
+	}
+	return c
+}
+
+// LoginGuard slows down and locks out repeated failed logins per username
+// and per IP, and keeps an audit trail of every checked attempt
+type LoginGuard struct {
+	repo repository.LoginRepository
+	cfg  LoginGuardConfig
+
+	mu       sync.Mutex     // serialises counter updates
+	inflight map[string]int // attempts passed by Begin and not yet recorded, per kind and key
+}
+
+func NewLoginGuard(repo repository.LoginRepository, cfg LoginGuardConfig) *LoginGuard {
+	return &LoginGuard{repo: repo, cfg: cfg.withDefaults(), inflight: make(map[string]int)}
+}
+
+func lockoutKey(username string) string {
+	if len(username) > maxLockoutKey {
+		return username[:maxLockoutKey]
+	}
+	return username
+}
+
+// counter an attempt is checked against
+type guardKey struct {
+	kind, key string
+	lockAt    int
+}
+
+func (k guardKey) String() string {
+	return k.kind + ":" + k.key
+}
+
+// guarded returns the counters for an attempt on username from ip
+func (g *LoginGuard) guarded(username, ip string) []guardKey {
+	keys := []guardKey{{entity.LockoutUsername, lockoutKey(username), g.cfg.UsernameLockAt}}
+	if ip != "" {
+		keys = append(keys, guardKey{entity.LockoutIP, ip, g.cfg.IPLockAt})
+	}
+	return keys
+}
+
+// Begin returns a *LoginBlockedError when the username or IP must wait.
+// Otherwise it reserves the attempt until Record is called with its outcome,
+// so concurrent attempts cannot all pass the check before any failure is
+// counted: no more attempts run at once than there are free attempts left,
+// and once those are used up only one per username or IP at a time.
+func (g *LoginGuard) Begin(username, ip string) error {
+	now := time.Now()
+	keys := g.guarded(username, ip)
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	var blocked *LoginBlockedError
+	block := func(wait time.Duration, locked bool) {
+		if blocked == nil || wait > blocked.RetryAfter {
+			blocked = &LoginBlockedError{RetryAfter: wait, Locked: locked}
+		}
+	}
+
+	for _, k := range keys {
+		failures := 0
+		if l, err := g.repo.GetLockout(k.kind, k.key); err == nil {
+			if l.Blocked(now) {
+				block(l.BlockedUntil.Sub(now), l.Locked)
+				continue
+			}
+			if now.Sub(l.LastFailureAt) <= g.cfg.Window {
+				failures = l.Failures
+			}
+		}
+
+		// free attempts may run side by side, later ones one at a time
+		allowed := g.cfg.FreeAttempts - failures
+		if allowed < 1 {
+			allowed = 1
+		}
+		if g.inflight[k.String()] >= allowed {
+			block(g.cfg.BaseDelay, false)
+		}
+	}
+	if blocked != nil {
+		return blocked
+	}
+
+	for _, k := range keys {
+		g.inflight[k.String()]++
+	}
+	return nil
+}
+
+// Record audits an attempt and updates the counters: a wrong username or
+// password counts against the username and IP, a success clears the
+// username's count. Every attempt let through by Begin must be recorded;
+// attempts Begin refused are not audited so a blocked client cannot flood
+// the trail.
+func (g *LoginGuard) Record(username, ip, userAgent string, userID *uint, result string) {
+	success := result == entity.LoginOK
+	if len(userAgent) > maxUserAgent {
+		userAgent = userAgent[:maxUserAgent]
+	}
+	attempt := &entity.LoginAttempt{
+		Username:  lockoutKey(username),
+		UserID:    userID,
+		IP:        ip,
+		UserAgent: userAgent,
+		Success:   success,
+		Result:    result,
+	}
+	if err := g.repo.CreateLoginAttempt(attempt); err != nil {
+		log.Printf("login audit: %v", err)
+	}
+
+	keys := g.guarded(username, ip)
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	for _, k := range keys {
+		if g.inflight[k.String()] <= 1 {
+			delete(g.inflight, k.String())
+		} else {
+			g.inflight[k.String()]--
+		}
+	}
+
+	switch {
+	case success:
+		if l, err := g.repo.GetLockout(entity.LockoutUsername, lockoutKey(username)); err == nil {
+			if err := g.repo.DeleteLockout(l.ID); err != nil {
+				log.Printf("login guard: failed to clear %s: %v", username, err)
+			}
+		}
+	// a disabled account had the right password
+	case result == entity.LoginBadPassword || result == entity.LoginUnknownUser:
+		for _, k := range keys {
+			g.fail(k.kind, k.key, k.lockAt)
+		}
+	}
+}
+
+// fail counts a failure; must be called with g.mu held
+func (g *LoginGuard) fail(kind, key string, lockAt int) {
+	now := time.Now()
+	l, err := g.repo.GetLockout(kind, key)
+	if err != nil {
+		l = &entity.LoginLockout{Kind: kind, Key: key}
+	}
+	if now.Sub(l.LastFailureAt) > g.cfg.Window && !l.Blocked(now) {
+		l.Failures = 0
+		l.Locked = false
+	}
+
+	l.Failures++
+	l.LastFailureAt = now
+	switch {
+	case l.Failures >= lockAt:
+		until := now.Add(g.cfg.LockFor)
+		l.BlockedUntil = &until
+		l.Locked = true
+	case l.Failures > g.cfg.FreeAttempts:
+		delay := g.cfg.BaseDelay << (l.Failures - g.cfg.FreeAttempts - 1)
+		if delay > g.cfg.MaxDelay || delay <= 0 {
+			delay = g.cfg.MaxDelay
+		}
+		until := now.Add(delay)
+		l.BlockedUntil = &until
+	}
+
+	if err := g.repo.SaveLockout(l); err != nil {
+		log.Printf("login guard: failed to count failure for %s %s: %v", kind, key, err)
+	}
+}
+
+func (g *LoginGuard) GetAttempts(filter entity.LoginAttemptFilter) ([]entity.LoginAttempt, int64, error) {
+	if filter.Limit <= 0 || filter.Limit > 500 {
+		filter.Limit = 100
+	}
+	return g.repo.GetLoginAttempts(filter)
+}
+
+// GetLockouts returns the usernames and IPs currently refused
+func (g *LoginGuard) GetLockouts() ([]entity.LoginLockout, error) {
+	return g.repo.GetBlockedLockouts(time.Now())
+}
+
+// ClearLockout lifts a block and resets its failure count
+func (g *LoginGuard) ClearLockout(id uint) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if _, err := g.repo.GetLockoutByID(id); err != nil {
+		return errors.New("lockout not found")
+	}
+	return g.repo.DeleteLockout(id)
+}
+
+// Run forgets counters that have gone quiet and deletes login attempts
+// older than the retention period until ctx is cancelled
+func (g *LoginGuard) Run(ctx context.Context) {
+	ticker := time.NewTicker(g.cfg.Window)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			g.purge(now)
+		}
+	}
+}
+
+func (g *LoginGuard) purge(now time.Time) {
+	g.mu.Lock()
+	_, err := g.repo.DeleteStaleLockouts(now.Add(-g.cfg.Window), now)
+	g.mu.Unlock()
+	if err != nil {
+		log.Printf("login guard: purge failed: %v", err)
+	}
+
+	if _, err := g.repo.DeleteLoginAttempts(now.Add(-g.cfg.Retention)); err != nil {
+		log.Printf("login audit: purge failed: %v", err)
+	}
+}
@@ -0,0 +1,282 @@
+package usecase
+
+import (
+	"EWSBE/internal/entity"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// memLogins is an in-memory LoginRepository
+type memLogins struct {
+	attempts []entity.LoginAttempt
+	lockouts map[uint]*entity.LoginLockout
+	nextID   uint
+}
+
+func newMemLogins() *memLogins {
+	return &memLogins{lockouts: map[uint]*entity.LoginLockout{}}
+}
+
+func (m *memLogins) CreateLoginAttempt(a *entity.LoginAttempt) error {
+	a.CreatedAt = time.Now()
+	m.attempts = append(m.attempts, *a)
+	return nil
+}
+
+func (m *memLogins) GetLoginAttempts(entity.LoginAttemptFilter) ([]entity.LoginAttempt, int64, error) {
+	return m.attempts, int64(len(m.attempts)), nil
+}
+
+func (m *memLogins) DeleteLoginAttempts(before time.Time) (int64, error) {
+	kept := m.attempts[:0]
+	for _, a := range m.attempts {
+		if !a.CreatedAt.Before(before) {
+			kept = append(kept, a)
+		}
+	}
+	n := int64(len(m.attempts) - len(kept))
+	m.attempts = kept
+	return n, nil
+}
+
+func (m *memLogins) GetLockout(kind, key string) (*entity.LoginLockout, error) {
+	for _, l := range m.lockouts {
+		if l.Kind == kind && l.Key == key {
+			cp := *l
+			return &cp, nil
+		}
+	}
+	return nil, errors.New("record not found")
+}
+
+func (m *memLogins) GetLockoutByID(id uint) (*entity.LoginLockout, error) {
+	if l, ok := m.lockouts[id]; ok {
+		cp := *l
+		return &cp, nil
+	}
+	return nil, errors.New("record not found")
+}
+
+func (m *memLogins) GetBlockedLockouts(now time.Time) ([]entity.LoginLockout, error) {
+	var blocked []entity.LoginLockout
+	for _, l := range m.lockouts {
+		if l.Blocked(now) {
+			blocked = append(blocked, *l)
+		}
+	}
+	return blocked, nil
+}
+
+func (m *memLogins) SaveLockout(l *entity.LoginLockout) error {
+	if l.ID == 0 {
+		m.nextID++
+		l.ID = m.nextID
+	}
+	cp := *l
+	m.lockouts[l.ID] = &cp
+	return nil
+}
+
+func (m *memLogins) DeleteLockout(id uint) error {
+	delete(m.lockouts, id)
+	return nil
+}
+
+func (m *memLogins) DeleteStaleLockouts(before, now time.Time) (int64, error) {
+	var n int64
+	for id, l := range m.lockouts {
+		if l.LastFailureAt.Before(before) && !l.Blocked(now) {
+			delete(m.lockouts, id)
+			n++
+		}
+	}
+	return n, nil
+}
+
+// unblock lets the next attempt through without waiting out the delay
+func (m *memLogins) unblock() {
+	for _, l := range m.lockouts {
+		l.BlockedUntil = nil
+	}
+}
+
+func (m *memLogins) failures(kind, key string) int {
+	if l, err := m.GetLockout(kind, key); err == nil {
+		return l.Failures
+	}
+	return 0
+}
+
+func newTestGuard() (*LoginGuard, *memLogins) {
+	repo := newMemLogins()
+	return NewLoginGuard(repo, LoginGuardConfig{
+		FreeAttempts:   3,
+		BaseDelay:      time.Minute,
+		MaxDelay:       10 * time.Minute,
+		UsernameLockAt: 6,
+		IPLockAt:       20,
+		LockFor:        time.Hour,
+		Window:         time.Hour,
+	}), repo
+}
+
+// failOnce runs one wrong-password attempt through the guard
+func failOnce(t *testing.T, g *LoginGuard, username, ip string) {
+	t.Helper()
+	if err := g.Begin(username, ip); err != nil {
+		t.Fatalf("Begin: %v", err)
+	}
+	g.Record(username, ip, "test", nil, entity.LoginBadPassword)
+}
+
+func blockedError(t *testing.T, err error) *LoginBlockedError {
+	t.Helper()
+	var blocked *LoginBlockedError
+	if !errors.As(err, &blocked) {
+		t.Fatalf("err = %v, want *LoginBlockedError", err)
+	}
+	return blocked
+}
+
+func TestLoginGuardCountsFailures(t *testing.T) {
+	g, repo := newTestGuard()
+
+	for i := 0; i < 3; i++ {
+		failOnce(t, g, "alice", "10.0.0.1")
+	}
+	if n := repo.failures(entity.LockoutUsername, "alice"); n != 3 {
+		t.Fatalf("username failures = %d, want 3", n)
+	}
+	if n := repo.failures(entity.LockoutIP, "10.0.0.1"); n != 3 {
+		t.Fatalf("ip failures = %d, want 3", n)
+	}
+	// the free attempts do not block
+	if err := g.Begin("alice", "10.0.0.1"); err != nil {
+		t.Fatalf("blocked after the free attempts: %v", err)
+	}
+	g.Record("alice", "10.0.0.1", "test", nil, entity.LoginBadPassword)
+
+	// each further failure doubles the delay
+	for i, want := range []time.Duration{time.Minute, 2 * time.Minute} {
+		blocked := blockedError(t, g.Begin("alice", "10.0.0.1"))
+		if blocked.Locked || blocked.RetryAfter > want || blocked.RetryAfter < want-time.Second {
+			t.Fatalf("failure %d: blocked %+v, want delay %v", i+4, blocked, want)
+		}
+		repo.unblock()
+		failOnce(t, g, "alice", "10.0.0.1")
+	}
+
+	// the sixth failure locks the username
+	blocked := blockedError(t, g.Begin("alice", "10.0.0.2"))
+	if !blocked.Locked || blocked.RetryAfter < 59*time.Minute {
+		t.Fatalf("after lockout: blocked %+v", blocked)
+	}
+	// the lock is per username; other accounts can still log in
+	if err := g.Begin("bob", "10.0.0.3"); err != nil {
+		t.Fatalf("unrelated user blocked: %v", err)
+	}
+	g.Record("bob", "10.0.0.3", "test", nil, entity.LoginOK)
+}
+
+func TestLoginGuardSuccessClearsUsername(t *testing.T) {
+	g, repo := newTestGuard()
+
+	failOnce(t, g, "alice", "10.0.0.1")
+	failOnce(t, g, "alice", "10.0.0.1")
+	if err := g.Begin("alice", "10.0.0.1"); err != nil {
+		t.Fatal(err)
+	}
+	uid := uint(1)
+	g.Record("alice", "10.0.0.1", "test", &uid, entity.LoginOK)
+
+	if n := repo.failures(entity.LockoutUsername, "alice"); n != 0 {
+		t.Errorf("username failures after success = %d, want 0", n)
+	}
+	// the IP keeps its count, it may be guessing other accounts
+	if n := repo.failures(entity.LockoutIP, "10.0.0.1"); n != 2 {
+		t.Errorf("ip failures after success = %d, want 2", n)
+	}
+}
+
+func TestLoginGuardOnlyCountsCheckedCredentials(t *testing.T) {
+	g, repo := newTestGuard()
+
+	if err := g.Begin("alice", "10.0.0.1"); err != nil {
+		t.Fatal(err)
+	}
+	g.Record("alice", "10.0.0.1", "test", nil, entity.LoginDisabled)
+	failOnce(t, g, "ghost", "10.0.0.1")
+
+	if n := repo.failures(entity.LockoutUsername, "alice"); n != 0 {
+		t.Errorf("disabled account counted as a failure")
+	}
+	if n := repo.failures(entity.LockoutUsername, "ghost"); n != 1 {
+		t.Errorf("unknown user failures = %d, want 1", n)
+	}
+}
+
+func TestLoginGuardReservesConcurrentAttempts(t *testing.T) {
+	g, repo := newTestGuard()
+
+	// three free attempts may be checked at once, a fourth has to wait
+	for i := 0; i < 3; i++ {
+		if err := g.Begin("alice", "10.0.0.1"); err != nil {
+			t.Fatalf("attempt %d: %v", i+1, err)
+		}
+	}
+	if err := g.Begin("alice", "10.0.0.9"); err == nil {
+		t.Fatal("fourth concurrent attempt was let through")
+	}
+	for i := 0; i < 3; i++ {
+		g.Record("alice", "10.0.0.1", "test", nil, entity.LoginBadPassword)
+	}
+
+	// past the free attempts only one is checked at a time
+	if err := g.Begin("alice", "10.0.0.1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.Begin("alice", "10.0.0.1"); err == nil {
+		t.Fatal("second concurrent attempt past the free attempts was let through")
+	}
+	g.Record("alice", "10.0.0.1", "test", nil, entity.LoginBadPassword)
+	if n := repo.failures(entity.LockoutUsername, "alice"); n != 4 {
+		t.Errorf("failures = %d, want 4", n)
+	}
+	if len(g.inflight) != 0 {
+		t.Errorf("reservations left after recording: %v", g.inflight)
+	}
+}
+
+func TestLoginGuardAudit(t *testing.T) {
+	g, repo := newTestGuard()
+
+	for i := 0; i < 4; i++ {
+		failOnce(t, g, "alice", "10.0.0.1")
+	}
+	// refused attempts are not written to the trail
+	for i := 0; i < 10; i++ {
+		if err := g.Begin("alice", "10.0.0.1"); err == nil {
+			t.Fatal("attempt during the delay was let through")
+		}
+	}
+	if len(repo.attempts) != 4 {
+		t.Errorf("audited %d attempts, want 4", len(repo.attempts))
+	}
+
+	repo.unblock()
+	if err := g.Begin("alice", "10.0.0.1"); err != nil {
+		t.Fatal(err)
+	}
+	g.Record("alice", "10.0.0.1", strings.Repeat("x", 1000), nil, entity.LoginBadPassword)
+	if got := len(repo.attempts[len(repo.attempts)-1].UserAgent); got != maxUserAgent {
+		t.Errorf("user agent kept %d bytes, want %d", got, maxUserAgent)
+	}
+
+	repo.attempts[0].CreatedAt = time.Now().Add(-g.cfg.Retention - time.Hour)
+	g.purge(time.Now())
+	if len(repo.attempts) != 4 {
+		t.Errorf("%d attempts after purge, want 4", len(repo.attempts))
+	}
+}
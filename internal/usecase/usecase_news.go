@@ -11,7 +11,8 @@ import (
 )
 
 type NewsUsecase struct {
-	newsRepo repository.NewsRepository
+	newsRepo    repository.NewsRepository
+	subscribers []func(entity.News)
 }
 
 func NewNewsUsecase(newsRepo repository.NewsRepository) *NewsUsecase {
@@ -46,6 +47,10 @@ func (uc *NewsUsecase) CreateNews(title, content string, bannerPhoto *string, au
 		return nil, err
 	}
 
+	for _, fn := range uc.subscribers {
+		fn(*newsWithAuthor)
+	}
+
 	return newsWithAuthor, nil
 }
 
@@ -102,6 +107,11 @@ func (uc *NewsUsecase) GetNewsByAuthorID(authorID uint) ([]entity.News, error) {
 	return uc.newsRepo.GetNewsByAuthorID(authorID)
 }
 
+// Subscribe registers fn to be called with every newly published article
+func (uc *NewsUsecase) Subscribe(fn func(entity.News)) {
+	uc.subscribers = append(uc.subscribers, fn)
+}
+
 // Helper function to generate slug
 func generateSlug(title string) string {
 	slug := strings.ToLower(title)
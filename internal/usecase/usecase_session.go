@@ -0,0 +1,203 @@
+package usecase
+
+import (
+	"EWSBE/internal/entity"
+	"EWSBE/internal/repository"
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// RefreshTokenPrefix starts every refresh token
+const RefreshTokenPrefix = "ewsr_"
+
+type SessionConfig struct {
+	AccessTTL  time.Duration // lifetime of an access token
+	RefreshTTL time.Duration // lifetime of a refresh token; each refresh starts a new one
+	PurgeEvery time.Duration // how often expired refresh tokens and stale sessions are deleted
+}
+
+func (c SessionConfig) withDefaults() SessionConfig {
+	if c.AccessTTL <= 0 {
+		c.AccessTTL = 15 * time.Minute
+	}
+	if c.RefreshTTL <= 0 {
+		c.RefreshTTL = 7 * 24 * time.Hour
+	}
+	if c.PurgeEvery <= 0 {
+		c.PurgeEvery = time.Hour
+	}
+	return c
+}
+
+// SessionUsecase issues and rotates refresh tokens. Each login starts a
+// session; presenting a refresh token a second time means it leaked, and
+// the whole session is revoked.
+type SessionUsecase struct {
+	repo  repository.SessionRepository
+	users repository.UserRepository
+	cfg   SessionConfig
+}
+
+func NewSessionUsecase(repo repository.SessionRepository, users repository.UserRepository, cfg SessionConfig) *SessionUsecase {
+	return &SessionUsecase{repo: repo, users: users, cfg: cfg.withDefaults()}
+}
+
+// AccessTTL is how long access tokens issued for a session are valid
+func (uc *SessionUsecase) AccessTTL() time.Duration {
+	return uc.cfg.AccessTTL
+}
+
+// Start opens a session for a user who just logged in and returns it with
+// its first refresh token
+func (uc *SessionUsecase) Start(user *entity.User, userAgent, ip string) (*entity.Session, string, error) {
+	session := &entity.Session{
+		FamilyID:  uuid.NewString(),
+		UserID:    user.ID,
+		UserAgent: userAgent,
+		IP:        ip,
+	}
+	if err := uc.repo.CreateSession(session); err != nil {
+		return nil, "", err
+	}
+
+	refresh, err := uc.issue(session.ID)
+	if err != nil {
+		return nil, "", err
+	}
+	return session, refresh, nil
+}
+
+func (uc *SessionUsecase) issue(sessionID uint) (string, error) {
+	plain, err := generateSecret(RefreshTokenPrefix)
+	if err != nil {
+		return "", err
+	}
+
+	token := &entity.RefreshToken{
+		SessionID: sessionID,
+		TokenHash: hashSecret(plain),
+		ExpiresAt: time.Now().Add(uc.cfg.RefreshTTL),
+	}
+	if err := uc.repo.CreateRefreshToken(token); err != nil {
+		return "", err
+	}
+	return plain, nil
+}
+
+// Refresh exchanges a refresh token for the next one and returns the user,
+// reloaded so role changes apply, and the session
+func (uc *SessionUsecase) Refresh(plain string) (*entity.User, *entity.Session, string, error) {
+	token, err := uc.repo.GetRefreshTokenByHash(hashSecret(plain))
+	if err != nil || token.Session == nil {
+		return nil, nil, "", errors.New("invalid refresh token")
+	}
+	session := token.Session
+	now := time.Now()
+
+	if session.RevokedAt != nil {
+		return nil, nil, "", errors.New("session revoked")
+	}
+	if token.UsedAt != nil {
+		uc.revoke(session, entity.RevokedReuse)
+		return nil, nil, "", errors.New("refresh token reuse detected, session revoked")
+	}
+	if now.After(token.ExpiresAt) {
+		return nil, nil, "", errors.New("refresh token expired")
+	}
+
+	// a concurrent refresh with the same token loses and counts as reuse
+	used, err := uc.repo.UseRefreshToken(token.ID, now)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	if !used {
+		uc.revoke(session, entity.RevokedReuse)
+		return nil, nil, "", errors.New("refresh token reuse detected, session revoked")
+	}
+
+	user, err := uc.users.GetUserByID(session.UserID)
+	if err != nil {
+		uc.revoke(session, entity.RevokedDeleted)
+		return nil, nil, "", errors.New("invalid refresh token")
+	}
+	if user.DisabledAt != nil {
+		uc.revoke(session, entity.RevokedDisabled)
+		return nil, nil, "", errors.New("account disabled")
+	}
+
+	next, err := uc.issue(session.ID)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	session.LastRefreshAt = &now
+	if err := uc.repo.UpdateSession(session); err != nil {
+		return nil, nil, "", err
+	}
+	return user, session, next, nil
+}
+
+// Logout revokes the session a refresh token belongs to
+func (uc *SessionUsecase) Logout(plain string) error {
+	token, err := uc.repo.GetRefreshTokenByHash(hashSecret(plain))
+	if err != nil || token.Session == nil {
+		return errors.New("invalid refresh token")
+	}
+	if token.Session.RevokedAt != nil {
+		return nil
+	}
+	return uc.repo.UpdateSession(withRevoked(token.Session, entity.RevokedLogout))
+}
+
+// RevokeUser ends every session of a user
+func (uc *SessionUsecase) RevokeUser(userID uint, reason string) error {
+	return uc.repo.RevokeUserSessions(userID, time.Now(), reason)
+}
+
+// Active reports whether access tokens of the session are still accepted
+func (uc *SessionUsecase) Active(familyID string) bool {
+	session, err := uc.repo.GetSessionByFamilyID(familyID)
+	return err == nil && session.RevokedAt == nil
+}
+
+func (uc *SessionUsecase) revoke(session *entity.Session, reason string) {
+	if err := uc.repo.UpdateSession(withRevoked(session, reason)); err != nil {
+		log.Printf("session %s: failed to revoke: %v", session.FamilyID, err)
+	}
+}
+
+func withRevoked(session *entity.Session, reason string) *entity.Session {
+	now := time.Now()
+	session.RevokedAt = &now
+	session.RevokedReason = reason
+	return session
+}
+
+// Run deletes expired refresh tokens until ctx is cancelled, and sessions
+// that were revoked or left unrefreshed for longer than the refresh TTL
+func (uc *SessionUsecase) Run(ctx context.Context) {
+	ticker := time.NewTicker(uc.cfg.PurgeEvery)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			uc.purge(now)
+		}
+	}
+}
+
+func (uc *SessionUsecase) purge(now time.Time) {
+	if _, err := uc.repo.DeleteExpiredRefreshTokens(now); err != nil {
+		log.Printf("session: purge failed: %v", err)
+	}
+	// a session idle this long has no usable refresh token left
+	if _, err := uc.repo.DeleteStaleSessions(now.Add(-uc.cfg.RefreshTTL)); err != nil {
+		log.Printf("session: purge failed: %v", err)
+	}
+}
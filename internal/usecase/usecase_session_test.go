@@ -0,0 +1,252 @@
+package usecase
+
+import (
+	"EWSBE/internal/entity"
+	"EWSBE/internal/repository"
+	"errors"
+	"testing"
+	"time"
+)
+
+// memSessions is an in-memory SessionRepository
+type memSessions struct {
+	sessions map[uint]*entity.Session
+	tokens   map[uint]*entity.RefreshToken
+}
+
+func newMemSessions() *memSessions {
+	return &memSessions{sessions: map[uint]*entity.Session{}, tokens: map[uint]*entity.RefreshToken{}}
+}
+
+func (m *memSessions) CreateSession(s *entity.Session) error {
+	s.ID = uint(len(m.sessions) + 1)
+	s.CreatedAt = time.Now()
+	cp := *s
+	m.sessions[s.ID] = &cp
+	return nil
+}
+
+func (m *memSessions) GetSessionByFamilyID(familyID string) (*entity.Session, error) {
+	for _, s := range m.sessions {
+		if s.FamilyID == familyID {
+			cp := *s
+			return &cp, nil
+		}
+	}
+	return nil, errors.New("record not found")
+}
+
+func (m *memSessions) UpdateSession(s *entity.Session) error {
+	cp := *s
+	m.sessions[s.ID] = &cp
+	return nil
+}
+
+func (m *memSessions) RevokeUserSessions(userID uint, at time.Time, reason string) error {
+	for _, s := range m.sessions {
+		if s.UserID == userID && s.RevokedAt == nil {
+			s.RevokedAt = &at
+			s.RevokedReason = reason
+		}
+	}
+	return nil
+}
+
+func (m *memSessions) CreateRefreshToken(t *entity.RefreshToken) error {
+	t.ID = uint(len(m.tokens) + 1)
+	cp := *t
+	m.tokens[t.ID] = &cp
+	return nil
+}
+
+func (m *memSessions) GetRefreshTokenByHash(hash string) (*entity.RefreshToken, error) {
+	for _, t := range m.tokens {
+		if t.TokenHash == hash {
+			cp := *t
+			session := *m.sessions[t.SessionID]
+			cp.Session = &session
+			return &cp, nil
+		}
+	}
+	return nil, errors.New("record not found")
+}
+
+func (m *memSessions) UseRefreshToken(id uint, at time.Time) (bool, error) {
+	t := m.tokens[id]
+	if t == nil || t.UsedAt != nil {
+		return false, nil
+	}
+	t.UsedAt = &at
+	return true, nil
+}
+
+func (m *memSessions) DeleteExpiredRefreshTokens(before time.Time) (int64, error) {
+	var n int64
+	for id, t := range m.tokens {
+		if t.ExpiresAt.Before(before) {
+			delete(m.tokens, id)
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (m *memSessions) DeleteStaleSessions(before time.Time) (int64, error) {
+	var n int64
+	for id, s := range m.sessions {
+		last := s.CreatedAt
+		if s.LastRefreshAt != nil {
+			last = *s.LastRefreshAt
+		}
+		if (s.RevokedAt != nil && s.RevokedAt.Before(before)) || last.Before(before) {
+			for tid, t := range m.tokens {
+				if t.SessionID == id {
+					delete(m.tokens, tid)
+				}
+			}
+			delete(m.sessions, id)
+			n++
+		}
+	}
+	return n, nil
+}
+
+// memUsers serves GetUserByID from a map; the other methods are not used
+type memUsers struct {
+	repository.UserRepository
+	users map[uint]*entity.User
+}
+
+func (m *memUsers) GetUserByID(id uint) (*entity.User, error) {
+	if u, ok := m.users[id]; ok {
+		cp := *u
+		return &cp, nil
+	}
+	return nil, errors.New("record not found")
+}
+
+func newTestSessions() (*SessionUsecase, *memSessions, *entity.User) {
+	user := &entity.User{ID: 1, Username: "operator", Role: entity.RoleEditor}
+	repo := newMemSessions()
+	uc := NewSessionUsecase(repo, &memUsers{users: map[uint]*entity.User{user.ID: user}}, SessionConfig{})
+	return uc, repo, user
+}
+
+func TestRefreshRotatesToken(t *testing.T) {
+	uc, _, user := newTestSessions()
+
+	session, first, err := uc.Start(user, "test", "127.0.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, refreshed, second, err := uc.Refresh(first)
+	if err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+	if got.ID != user.ID || refreshed.FamilyID != session.FamilyID {
+		t.Errorf("Refresh returned user %d session %s", got.ID, refreshed.FamilyID)
+	}
+	if second == first {
+		t.Error("Refresh returned the same token")
+	}
+	if _, _, _, err := uc.Refresh(second); err != nil {
+		t.Errorf("Refresh with the rotated token: %v", err)
+	}
+	if !uc.Active(session.FamilyID) {
+		t.Error("session inactive after normal refreshes")
+	}
+}
+
+func TestRefreshReuseRevokesSession(t *testing.T) {
+	uc, repo, user := newTestSessions()
+
+	session, first, _ := uc.Start(user, "test", "127.0.0.1")
+	_, _, second, err := uc.Refresh(first)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the old token shows up again: someone else holds a copy
+	if _, _, _, err := uc.Refresh(first); err == nil || err.Error() != "refresh token reuse detected, session revoked" {
+		t.Fatalf("reused token: err = %v", err)
+	}
+	if uc.Active(session.FamilyID) {
+		t.Error("session still active after reuse")
+	}
+	if s, _ := repo.GetSessionByFamilyID(session.FamilyID); s.RevokedReason != entity.RevokedReuse {
+		t.Errorf("revoked reason = %q", s.RevokedReason)
+	}
+
+	// the legitimate holder's latest token stops working too
+	if _, _, _, err := uc.Refresh(second); err == nil || err.Error() != "session revoked" {
+		t.Errorf("latest token after reuse: err = %v", err)
+	}
+}
+
+func TestRefreshConcurrentUseCountsAsReuse(t *testing.T) {
+	uc, repo, user := newTestSessions()
+
+	session, first, _ := uc.Start(user, "test", "127.0.0.1")
+	// another request marks the token used between the lookup and the update
+	uc.repo = &racyTokens{memSessions: repo}
+	if _, _, _, err := uc.Refresh(first); err == nil || err.Error() != "refresh token reuse detected, session revoked" {
+		t.Fatalf("lost race: err = %v", err)
+	}
+	if uc.Active(session.FamilyID) {
+		t.Error("session still active after losing the race")
+	}
+}
+
+// racyTokens loses every UseRefreshToken race
+type racyTokens struct {
+	*memSessions
+}
+
+func (r *racyTokens) UseRefreshToken(uint, time.Time) (bool, error) {
+	return false, nil
+}
+
+func TestRefreshExpiredAndDisabled(t *testing.T) {
+	uc, repo, user := newTestSessions()
+
+	_, token, _ := uc.Start(user, "test", "127.0.0.1")
+	for _, tok := range repo.tokens {
+		tok.ExpiresAt = time.Now().Add(-time.Minute)
+	}
+	if _, _, _, err := uc.Refresh(token); err == nil || err.Error() != "refresh token expired" {
+		t.Errorf("expired token: err = %v", err)
+	}
+
+	session, token, _ := uc.Start(user, "test", "127.0.0.1")
+	now := time.Now()
+	uc.users.(*memUsers).users[user.ID].DisabledAt = &now
+	if _, _, _, err := uc.Refresh(token); err == nil || err.Error() != "account disabled" {
+		t.Errorf("disabled user: err = %v", err)
+	}
+	if uc.Active(session.FamilyID) {
+		t.Error("session of a disabled user still active")
+	}
+}
+
+func TestPurgeDeletesStaleSessions(t *testing.T) {
+	uc, repo, user := newTestSessions()
+
+	idle, _, _ := uc.Start(user, "test", "127.0.0.1")
+	live, _, _ := uc.Start(user, "test", "127.0.0.1")
+	old := time.Now().Add(-uc.cfg.RefreshTTL - time.Hour)
+	repo.sessions[idle.ID].CreatedAt = old
+
+	uc.purge(time.Now())
+	if _, err := repo.GetSessionByFamilyID(idle.FamilyID); err == nil {
+		t.Error("idle session was not purged")
+	}
+	if _, err := repo.GetSessionByFamilyID(live.FamilyID); err != nil {
+		t.Error("live session was purged")
+	}
+	for _, tok := range repo.tokens {
+		if tok.SessionID == idle.ID {
+			t.Error("refresh token of a purged session left behind")
+		}
+	}
+}
@@ -0,0 +1,183 @@
+package usecase
+
+import (
+	"EWSBE/internal/entity"
+	"EWSBE/internal/repository"
+	"errors"
+	"log"
+	"strings"
+	"sync"
+
+	"gorm.io/gorm"
+)
+
+type StationUsecase struct {
+	repo repository.StationRepository
+
+	mu     sync.RWMutex
+	byCode map[string]*entity.Station // resolved stations, hit on every ingested message
+	byID   map[uint]*entity.Station
+}
+
+func NewStationUsecase(repo repository.StationRepository) *StationUsecase {
+	return &StationUsecase{
+		repo:   repo,
+		byCode: make(map[string]*entity.Station),
+		byID:   make(map[uint]*entity.Station),
+	}
+}
+
+func (uc *StationUsecase) GetAllStations() ([]entity.Station, error) {
+	return uc.repo.GetAllStations()
+}
+
+func (uc *StationUsecase) GetStationByCode(code string) (*entity.Station, error) {
+	uc.mu.RLock()
+	station, ok := uc.byCode[code]
+	uc.mu.RUnlock()
+	if ok {
+		return station, nil
+	}
+
+	station, err := uc.repo.GetStationByCode(code)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("station not found")
+		}
+		return nil, err
+	}
+
+	uc.cache(station)
+	return station, nil
+}
+
+func (uc *StationUsecase) GetStationByID(id uint) (*entity.Station, error) {
+	uc.mu.RLock()
+	station, ok := uc.byID[id]
+	uc.mu.RUnlock()
+	if ok {
+		return station, nil
+	}
+
+	station, err := uc.repo.GetStationByID(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("station not found")
+		}
+		return nil, err
+	}
+
+	uc.cache(station)
+	return station, nil
+}
+
+func (uc *StationUsecase) CreateStation(station *entity.Station) error {
+	station.Code = strings.TrimSpace(station.Code)
+	if station.Code == "" {
+		return errors.New("station code cannot be empty")
+	}
+	if strings.ContainsAny(station.Code, "/+#") {
+		return errors.New("station code cannot contain '/', '+' or '#'")
+	}
+	if strings.TrimSpace(station.Name) == "" {
+		station.Name = station.Code
+	}
+	if station.ExpectedInterval < 0 {
+		return errors.New("expected interval cannot be negative")
+	}
+	station.LastSeenAt = nil
+	station.Status = entity.StationUnknown
+	station.StatusChangedAt = nil
+	station.GapCount = 0
+
+	if err := uc.repo.CreateStation(station); err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return errors.New("station code already exists")
+		}
+		return err
+	}
+
+	uc.cache(station)
+	return nil
+}
+
+// UpdateStation applies the fields present in input and leaves the rest as they are
+func (uc *StationUsecase) UpdateStation(code string, input *entity.StationUpdate) (*entity.Station, error) {
+	station, err := uc.repo.GetStationByCode(code)
+	if err != nil {
+		return nil, errors.New("station not found")
+	}
+
+	if input.Name != nil {
+		if strings.TrimSpace(*input.Name) == "" {
+			return nil, errors.New("station name cannot be empty")
+		}
+		station.Name = *input.Name
+	}
+	if input.Latitude != nil {
+		station.Latitude = *input.Latitude
+	}
+	if input.Longitude != nil {
+		station.Longitude = *input.Longitude
+	}
+	if input.Elevation != nil {
+		station.Elevation = *input.Elevation
+	}
+	if input.InstalledAt != nil {
+		station.InstalledAt = input.InstalledAt
+	}
+	if input.SensorHeight.Set {
+		station.SensorHeight = input.SensorHeight.Value
+	}
+	if input.DatumName != nil {
+		station.DatumName = *input.DatumName
+	}
+	if input.LevelOffset != nil {
+		station.LevelOffset = *input.LevelOffset
+	}
+	if input.ExpectedInterval != nil {
+		if *input.ExpectedInterval < 0 {
+			return nil, errors.New("expected interval cannot be negative")
+		}
+		station.ExpectedInterval = *input.ExpectedInterval
+	}
+
+	if err := uc.repo.UpdateStation(station); err != nil {
+		return nil, err
+	}
+
+	uc.cache(station)
+	return station, nil
+}
+
+// Resolve returns the station for a topic code; with register set unknown
+// codes are registered so readings from a newly deployed station are not lost
+func (uc *StationUsecase) Resolve(code string, register bool) (*entity.Station, error) {
+	station, err := uc.GetStationByCode(code)
+	if err == nil {
+		return station, nil
+	}
+	if err.Error() != "station not found" || !register {
+		return nil, err
+	}
+
+	station = &entity.Station{Code: code, Name: code}
+	if err := uc.CreateStation(station); err != nil {
+		// another message may have registered it first
+		if existing, getErr := uc.repo.GetStationByCode(code); getErr == nil {
+			uc.cache(existing)
+			return existing, nil
+		}
+		return nil, err
+	}
+
+	log.Printf("station: registered new station %q", code)
+	return station, nil
+}
+
+func (uc *StationUsecase) cache(station *entity.Station) {
+	uc.mu.Lock()
+	uc.byCode[station.Code] = station
+	uc.byID[station.ID] = station
+	uc.mu.Unlock()
+}
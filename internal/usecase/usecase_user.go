@@ -0,0 +1,290 @@
+package usecase
+
+import (
+	"EWSBE/internal/entity"
+	"EWSBE/internal/repository"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// InvitePrefix starts every invite code
+const InvitePrefix = "ewsi_"
+
+const (
+	inviteShownChars = len(InvitePrefix) + 8
+	defaultInviteTTL = 7 * 24 * time.Hour
+	maxInviteTTL     = 90 * 24 * time.Hour
+)
+
+// UserUsecase is account administration: users, roles and invites. It keeps
+// at least one active admin at all times.
+type UserUsecase struct {
+	repo     repository.UserRepository
+	sessions *SessionUsecase // logs users out when they are disabled or their password changes
+}
+
+func NewUserUsecase(repo repository.UserRepository, sessions *SessionUsecase) *UserUsecase {
+	return &UserUsecase{repo: repo, sessions: sessions}
+}
+
+// Bootstrap creates the first admin when the database has no users yet and
+// reports whether it did
+func (uc *UserUsecase) Bootstrap(username, password string) (bool, error) {
+	count, err := uc.repo.CountUsers()
+	if err != nil {
+		return false, err
+	}
+	if count > 0 {
+		return false, nil
+	}
+	if username == "" || password == "" {
+		return false, errors.New("no users yet and no bootstrap admin configured")
+	}
+
+	if _, err := createUser(uc.repo, username, password, entity.RoleAdmin); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// PromoteFirstAdmin makes an existing user admin while there is no active
+// admin, e.g. after upgrading a database from before roles, and reports
+// whether it did
+func (uc *UserUsecase) PromoteFirstAdmin(username string) (bool, error) {
+	if username == "" {
+		return false, nil
+	}
+	admins, err := uc.repo.CountActiveUsersByRole(entity.RoleAdmin)
+	if err != nil || admins > 0 {
+		return false, err
+	}
+
+	user, err := uc.repo.GetUserByUsername(username)
+	if err != nil {
+		return false, errors.New("user " + username + " not found")
+	}
+	user.Role = entity.RoleAdmin
+	user.DisabledAt = nil
+	if err := uc.repo.UpdateUser(user); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (uc *UserUsecase) GetUsers() ([]entity.User, error) {
+	return uc.repo.GetAllUsers()
+}
+
+func (uc *UserUsecase) GetUserByID(id uint) (*entity.User, error) {
+	user, err := uc.repo.GetUserByID(id)
+	if err != nil {
+		return nil, errors.New("user not found")
+	}
+	return user, nil
+}
+
+func (uc *UserUsecase) CreateUser(username, password, role string) (*entity.User, error) {
+	if role == "" {
+		role = entity.RoleViewer
+	}
+	if err := validateRole(role); err != nil {
+		return nil, err
+	}
+	return createUser(uc.repo, username, password, role)
+}
+
+// SetRole changes a user's role
+func (uc *UserUsecase) SetRole(id uint, role string) (*entity.User, error) {
+	if err := validateRole(role); err != nil {
+		return nil, err
+	}
+
+	user, err := uc.GetUserByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if user.Role == role {
+		return user, nil
+	}
+	if err := uc.keepAnAdmin(user); err != nil {
+		return nil, err
+	}
+
+	user.Role = role
+	if err := uc.repo.UpdateUser(user); err != nil {
+		return nil, err
+	}
+	// tokens carry the old permissions; the user logs in again to get new ones
+	if err := uc.sessions.RevokeUser(user.ID, entity.RevokedRole); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// Disable blocks a user from logging in; actorID is the admin doing it
+func (uc *UserUsecase) Disable(id, actorID uint) (*entity.User, error) {
+	if id == actorID {
+		return nil, errors.New("cannot disable your own account")
+	}
+
+	user, err := uc.GetUserByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if user.DisabledAt != nil {
+		return user, nil
+	}
+	if err := uc.keepAnAdmin(user); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	user.DisabledAt = &now
+	if err := uc.repo.UpdateUser(user); err != nil {
+		return nil, err
+	}
+	if err := uc.sessions.RevokeUser(user.ID, entity.RevokedDisabled); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+func (uc *UserUsecase) Enable(id uint) (*entity.User, error) {
+	user, err := uc.GetUserByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if user.DisabledAt == nil {
+		return user, nil
+	}
+
+	user.DisabledAt = nil
+	if err := uc.repo.UpdateUser(user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// Delete removes an account. Users who authored news or issued commands
+// are referenced elsewhere and can only be disabled.
+func (uc *UserUsecase) Delete(id, actorID uint) error {
+	if id == actorID {
+		return errors.New("cannot delete your own account")
+	}
+
+	user, err := uc.GetUserByID(id)
+	if err != nil {
+		return err
+	}
+	if err := uc.keepAnAdmin(user); err != nil {
+		return err
+	}
+
+	if err := uc.repo.DeleteUser(id); err != nil {
+		if errors.Is(err, gorm.ErrForeignKeyViolated) {
+			return errors.New("user has authored content, disable the account instead")
+		}
+		return err
+	}
+	return uc.sessions.RevokeUser(id, entity.RevokedDeleted)
+}
+
+// ResetPassword sets a new password for a user and ends their sessions
+func (uc *UserUsecase) ResetPassword(id uint, password string) error {
+	if err := validatePassword(password); err != nil {
+		return err
+	}
+
+	user, err := uc.GetUserByID(id)
+	if err != nil {
+		return err
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	user.Password = string(hashedPassword)
+	if err := uc.repo.UpdateUser(user); err != nil {
+		return err
+	}
+	return uc.sessions.RevokeUser(user.ID, entity.RevokedPassword)
+}
+
+// keepAnAdmin refuses changes that would take away the last active admin
+func (uc *UserUsecase) keepAnAdmin(user *entity.User) error {
+	if user.Role != entity.RoleAdmin || user.DisabledAt != nil {
+		return nil
+	}
+	admins, err := uc.repo.CountActiveUsersByRole(entity.RoleAdmin)
+	if err != nil {
+		return err
+	}
+	if admins <= 1 {
+		return errors.New("cannot remove the last active admin")
+	}
+	return nil
+}
+
+// CreateInvite issues a single-use invite code for the role and returns the
+// invite with its code; ttl 0 uses the default of a week
+func (uc *UserUsecase) CreateInvite(role, note string, ttl time.Duration, actorID uint) (*entity.Invite, string, error) {
+	if role == "" {
+		role = entity.RoleViewer
+	}
+	if err := validateRole(role); err != nil {
+		return nil, "", err
+	}
+	if ttl == 0 {
+		ttl = defaultInviteTTL
+	}
+	if ttl < 0 || ttl > maxInviteTTL {
+		return nil, "", fmt.Errorf("invite lifetime must be at most %d hours", int(maxInviteTTL.Hours()))
+	}
+
+	code, err := generateSecret(InvitePrefix)
+	if err != nil {
+		return nil, "", err
+	}
+
+	invite := &entity.Invite{
+		Prefix:      code[:inviteShownChars],
+		CodeHash:    hashSecret(code),
+		Role:        role,
+		Note:        strings.TrimSpace(note),
+		CreatedByID: actorID,
+		ExpiresAt:   time.Now().Add(ttl),
+	}
+	if err := uc.repo.CreateInvite(invite); err != nil {
+		return nil, "", err
+	}
+	return invite, code, nil
+}
+
+func (uc *UserUsecase) GetInvites() ([]entity.Invite, error) {
+	return uc.repo.GetInvites()
+}
+
+// DeleteInvite withdraws an invite; used invites are kept as a record
+func (uc *UserUsecase) DeleteInvite(id uint) error {
+	invite, err := uc.repo.GetInviteByID(id)
+	if err != nil {
+		return errors.New("invite not found")
+	}
+	if invite.UsedAt != nil {
+		return errors.New("invite already used")
+	}
+	return uc.repo.DeleteInvite(id)
+}
+
+func validateRole(role string) error {
+	if !entity.ValidRole(role) {
+		return fmt.Errorf("unknown role %q (want one of %s)", role, strings.Join(entity.Roles, ", "))
+	}
+	return nil
+}
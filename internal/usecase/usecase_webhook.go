@@ -0,0 +1,203 @@
+package usecase
+
+import (
+	"EWSBE/internal/entity"
+	"EWSBE/internal/repository"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// WebhookDispatcher delivers signed payloads asynchronously
+type WebhookDispatcher interface {
+	Enqueue(hook entity.Webhook, deliveryID, event string, body []byte) bool
+}
+
+type WebhookUsecase struct {
+	repo       repository.WebhookRepository
+	dispatcher WebhookDispatcher
+}
+
+func NewWebhookUsecase(repo repository.WebhookRepository, dispatcher WebhookDispatcher) *WebhookUsecase {
+	return &WebhookUsecase{repo: repo, dispatcher: dispatcher}
+}
+
+func (uc *WebhookUsecase) GetAllWebhooks() ([]entity.Webhook, error) {
+	return uc.repo.GetAllWebhooks()
+}
+
+func (uc *WebhookUsecase) GetWebhookByID(id uint) (*entity.Webhook, error) {
+	hook, err := uc.repo.GetWebhookByID(id)
+	if err != nil {
+		return nil, errors.New("webhook not found")
+	}
+	return hook, nil
+}
+
+// CreateWebhook registers a target; a signing secret is generated when none is given
+func (uc *WebhookUsecase) CreateWebhook(hook *entity.Webhook) error {
+	if err := validateWebhook(hook); err != nil {
+		return err
+	}
+
+	if hook.Secret == "" {
+		secret, err := generateSecret("whsec_")
+		if err != nil {
+			return err
+		}
+		hook.Secret = secret
+	}
+
+	return uc.repo.CreateWebhook(hook)
+}
+
+func (uc *WebhookUsecase) UpdateWebhook(id uint, input *entity.Webhook) (*entity.Webhook, error) {
+	hook, err := uc.GetWebhookByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if input.Name != "" {
+		hook.Name = input.Name
+	}
+	if input.URL != "" {
+		hook.URL = input.URL
+	}
+	if input.Events != nil {
+		hook.Events = input.Events
+	}
+	if input.Secret != "" {
+		hook.Secret = input.Secret
+	}
+	hook.Enabled = input.Enabled
+
+	if err := validateWebhook(hook); err != nil {
+		return nil, err
+	}
+
+	if err := uc.repo.UpdateWebhook(hook); err != nil {
+		return nil, err
+	}
+	return hook, nil
+}
+
+func (uc *WebhookUsecase) DeleteWebhook(id uint) error {
+	if _, err := uc.GetWebhookByID(id); err != nil {
+		return err
+	}
+	return uc.repo.DeleteWebhook(id)
+}
+
+func (uc *WebhookUsecase) GetDeliveries(id uint, limit int) ([]entity.WebhookDelivery, error) {
+	if limit <= 0 || limit > 500 {
+		limit = 100
+	}
+	return uc.repo.GetDeliveries(id, limit)
+}
+
+// Test sends a ping event to one webhook regardless of its event filter
+func (uc *WebhookUsecase) Test(id uint) (string, error) {
+	hook, err := uc.GetWebhookByID(id)
+	if err != nil {
+		return "", err
+	}
+
+	return uc.send(*hook, entity.WebhookEventPing, map[string]string{"message": "webhook test from EWSBE"})
+}
+
+// Publish notifies every enabled webhook subscribed to event
+func (uc *WebhookUsecase) Publish(event string, data interface{}) {
+	hooks, err := uc.repo.GetAllWebhooks()
+	if err != nil {
+		log.Printf("webhook: failed to load webhooks for %s: %v", event, err)
+		return
+	}
+
+	for _, hook := range hooks {
+		if !hook.Enabled || !hook.Wants(event) {
+			continue
+		}
+		if _, err := uc.send(hook, event, data); err != nil {
+			log.Printf("webhook: %s to %s not queued: %v", event, hook.URL, err)
+		}
+	}
+}
+
+func (uc *WebhookUsecase) send(hook entity.Webhook, event string, data interface{}) (string, error) {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return "", err
+	}
+
+	envelope := entity.WebhookEnvelope{
+		ID:        uuid.New().String(),
+		Event:     event,
+		CreatedAt: time.Now(),
+		Data:      raw,
+	}
+	body, err := json.Marshal(envelope)
+	if err != nil {
+		return "", err
+	}
+
+	if !uc.dispatcher.Enqueue(hook, envelope.ID, event, body) {
+		return "", errors.New("delivery queue full")
+	}
+	return envelope.ID, nil
+}
+
+func validateWebhook(hook *entity.Webhook) error {
+	if strings.TrimSpace(hook.Name) == "" {
+		return errors.New("webhook name cannot be empty")
+	}
+
+	u, err := url.Parse(hook.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.New("webhook url must be an absolute http(s) URL")
+	}
+
+	if len(hook.Events) == 0 {
+		return errors.New("at least one event is required")
+	}
+	for _, e := range hook.Events {
+		if e == entity.WebhookEventAll {
+			continue
+		}
+		known := false
+		for _, k := range entity.WebhookEvents {
+			if e == k {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return errors.New("unknown webhook event: " + e)
+		}
+	}
+
+	return nil
+}
+
+// generateSecret returns prefix followed by 32 random bytes in hex
+func generateSecret(prefix string) (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return prefix + hex.EncodeToString(b), nil
+}
+
+// hashSecret returns the SHA-256 of a generated secret in hex, for storing
+// keys and codes that only need to be recognised later
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,172 @@
+package usecase
+
+import (
+	"EWSBE/internal/entity"
+	"math"
+	"sync"
+	"time"
+)
+
+// limits for one reading field
+type fieldLimits struct {
+	Min, Max               float64 // outside -> bad (sensor range or error code)
+	SuspectMin, SuspectMax float64 // outside -> suspect (physically possible but unusual)
+	MaxRatePerMin          float64 // larger change per minute -> suspect, 0 disables
+	StuckCount             int     // identical consecutive values -> suspect, 0 disables
+}
+
+// limits tuned for the BME280, MH-Z19, JSN-SR04T, anemometer, tipping bucket
+// and INA219 on the current station hardware
+var defaultFieldLimits = map[string]fieldLimits{
+	"temperature":   {Min: -40, Max: 85, SuspectMin: 5, SuspectMax: 45, MaxRatePerMin: 3, StuckCount: 60},
+	"humidity":      {Min: 0.01, Max: 100, SuspectMin: 5, SuspectMax: 100, MaxRatePerMin: 20, StuckCount: 60},
+	"pressure":      {Min: 300, Max: 1100, SuspectMin: 850, SuspectMax: 1050, MaxRatePerMin: 2, StuckCount: 120},
+	"altitude":      {Min: -500, Max: 9000, SuspectMin: -100, SuspectMax: 3500},
+	"co2":           {Min: 0.01, Max: 10000, SuspectMin: 350, SuspectMax: 5000, MaxRatePerMin: 500, StuckCount: 120},
+	"distance":      {Min: 0.01, Max: 800, SuspectMin: 20, SuspectMax: 600, MaxRatePerMin: 100, StuckCount: 0},
+	"windSpeed":     {Min: 0, Max: 75, SuspectMin: 0, SuspectMax: 50},
+	"windDirection": {Min: 0, Max: 360, SuspectMin: 0, SuspectMax: 360},
+	"rainfall":      {Min: 0, Max: 200, SuspectMin: 0, SuspectMax: 50},
+	"voltage":       {Min: 0, Max: 30, SuspectMin: 3, SuspectMax: 20, MaxRatePerMin: 2},
+	"busVoltage":    {Min: 0, Max: 32, SuspectMin: 0, SuspectMax: 26},
+	"current":       {Min: -5000, Max: 5000, SuspectMin: -3200, SuspectMax: 3200},
+}
+
+// previous readings further apart than this are not used for rate checks
+const rateCheckWindow = 30 * time.Minute
+
+type fieldHistory struct {
+	lastGood   float64
+	lastGoodAt time.Time
+	lastValue  float64
+	repeats    int
+}
+
+// validator flags reading fields per station; it keeps the recent history
+// needed for rate-of-change and stuck-value checks
+type validator struct {
+	limits map[string]fieldLimits
+
+	mu      sync.Mutex
+	history map[uint]map[string]*fieldHistory // station -> field -> history
+}
+
+func newValidator() *validator {
+	return &validator{
+		limits:  defaultFieldLimits,
+		history: make(map[uint]map[string]*fieldHistory),
+	}
+}
+
+// Validate sets d.Quality and zeroes non-finite values so the row can still
+// be stored and serialized; the rest of the reading is kept
+func (v *validator) Validate(d *entity.SensorData) {
+	var stationID uint
+	if d.StationID != nil {
+		stationID = *d.StationID
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	history := v.history[stationID]
+	if history == nil {
+		history = make(map[string]*fieldHistory)
+		v.history[stationID] = history
+	}
+
+	if d.Quality == nil {
+		d.Quality = make(entity.QualityFlags, len(v.limits))
+	}
+
+	for field, limits := range v.limits {
+		ptr := fieldPointer(d, field)
+		if ptr == nil || d.Quality[field] == entity.QualityMissing {
+			continue
+		}
+
+		h := history[field]
+		if h == nil {
+			h = &fieldHistory{}
+			history[field] = h
+		}
+
+		d.Quality[field] = checkField(ptr, limits, h, d.Timestamp)
+	}
+}
+
+func checkField(value *float64, limits fieldLimits, h *fieldHistory, at time.Time) entity.QualityFlag {
+	if math.IsNaN(*value) || math.IsInf(*value, 0) {
+		*value = 0
+		return entity.QualityBad
+	}
+
+	x := *value
+	if x < limits.Min || x > limits.Max {
+		return entity.QualityBad
+	}
+
+	flag := entity.QualityGood
+	if x < limits.SuspectMin || x > limits.SuspectMax {
+		flag = entity.QualitySuspect
+	}
+
+	// stuck sensor: the same value over and over
+	if x == h.lastValue {
+		h.repeats++
+	} else {
+		h.repeats = 0
+		h.lastValue = x
+	}
+	if limits.StuckCount > 0 && h.repeats >= limits.StuckCount {
+		flag = entity.QualitySuspect
+	}
+
+	// rate of change against the last good value
+	if limits.MaxRatePerMin > 0 && !h.lastGoodAt.IsZero() {
+		dt := at.Sub(h.lastGoodAt)
+		if dt > 0 && dt <= rateCheckWindow {
+			minutes := math.Max(dt.Minutes(), 1.0/60)
+			if math.Abs(x-h.lastGood)/minutes > limits.MaxRatePerMin {
+				flag = entity.QualitySuspect
+			}
+		}
+	}
+
+	if flag == entity.QualityGood {
+		h.lastGood = x
+		h.lastGoodAt = at
+	}
+	return flag
+}
+
+func fieldPointer(d *entity.SensorData, field string) *float64 {
+	switch field {
+	case "temperature":
+		return &d.Temperature
+	case "humidity":
+		return &d.Humidity
+	case "pressure":
+		return &d.Pressure
+	case "altitude":
+		return &d.Altitude
+	case "co2":
+		return &d.Co2
+	case "distance":
+		return &d.Distance
+	case "windSpeed":
+		return &d.WindSpeed
+	case "windDirection":
+		return &d.WindDirection
+	case "rainfall":
+		return &d.Rainfall
+	case "voltage":
+		return &d.Voltage
+	case "busVoltage":
+		return &d.BusVoltage
+	case "current":
+		return &d.Current
+	default:
+		return nil
+	}
+}
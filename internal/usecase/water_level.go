@@ -0,0 +1,82 @@
+package usecase
+
+import (
+	"EWSBE/internal/entity"
+	"sync"
+	"time"
+)
+
+const (
+	// rate of rise is the least-squares slope over this trailing window,
+	// which smooths out the centimetre jitter of the ultrasonic sensor
+	levelRateWindow = 30 * time.Minute
+	// minimum span of samples before a rate is reported
+	levelRateMinSpan = 5 * time.Minute
+)
+
+type levelSample struct {
+	at    time.Time
+	level float64
+}
+
+// levelTracker derives water level and its rate of rise per station
+type levelTracker struct {
+	mu      sync.Mutex
+	samples map[uint][]levelSample
+}
+
+func newLevelTracker() *levelTracker {
+	return &levelTracker{samples: make(map[uint][]levelSample)}
+}
+
+// Apply sets d.WaterLevel and d.WaterLevelRate from the station calibration.
+// Readings with a bad distance get no level.
+func (t *levelTracker) Apply(d *entity.SensorData, station *entity.Station) {
+	if station == nil || d.Quality.IsBad("distance") {
+		return
+	}
+
+	level, ok := station.WaterLevel(d.Distance)
+	if !ok {
+		return
+	}
+	d.WaterLevel = &level
+	d.Quality["waterLevel"] = d.Quality["distance"]
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	samples := append(t.samples[station.ID], levelSample{at: d.Timestamp, level: level})
+	cutoff := d.Timestamp.Add(-levelRateWindow)
+	for len(samples) > 0 && samples[0].at.Before(cutoff) {
+		samples = samples[1:]
+	}
+	t.samples[station.ID] = samples
+
+	if len(samples) < 2 || samples[len(samples)-1].at.Sub(samples[0].at) < levelRateMinSpan {
+		return
+	}
+
+	rate := levelSlope(samples) * 3600
+	d.WaterLevelRate = &rate
+}
+
+// levelSlope is the least-squares slope in cm per second
+func levelSlope(samples []levelSample) float64 {
+	origin := samples[0].at
+	var n, sumX, sumY, sumXY, sumXX float64
+	for _, s := range samples {
+		x := s.at.Sub(origin).Seconds()
+		n++
+		sumX += x
+		sumY += s.level
+		sumXY += x * s.level
+		sumXX += x * x
+	}
+
+	denom := n*sumXX - sumX*sumX
+	if denom == 0 {
+		return 0
+	}
+	return (n*sumXY - sumX*sumY) / denom
+}
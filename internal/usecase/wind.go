@@ -0,0 +1,66 @@
+package usecase
+
+import (
+	"EWSBE/internal/entity"
+	"fmt"
+	"time"
+)
+
+// GetWindRose returns the frequency of wind by compass sector and speed bin
+func (uc *DataUsecase) GetWindRose(stationID uint, start, end time.Time) (*entity.WindRose, error) {
+	cells, err := uc.repo.GetWindRose(stationID, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	edges := entity.WindRoseSpeedEdges
+	bins := make([]string, 0, len(edges))
+	for i := 1; i < len(edges); i++ {
+		bins = append(bins, fmt.Sprintf("%g-%g", edges[i-1], edges[i]))
+	}
+	bins = append(bins, fmt.Sprintf(">%g", edges[len(edges)-1]))
+
+	rose := &entity.WindRose{
+		Start:     start,
+		End:       end,
+		SpeedBins: bins,
+		Sectors:   make([]entity.WindRoseSector, len(entity.CompassSectors)),
+	}
+	for i, name := range entity.CompassSectors {
+		rose.Sectors[i] = entity.WindRoseSector{
+			Direction:   name,
+			Angle:       float64(i) * 22.5,
+			Counts:      make([]int64, len(bins)),
+			Frequencies: make([]float64, len(bins)),
+		}
+	}
+
+	for _, cell := range cells {
+		rose.Total += cell.Count
+		if cell.Bin == 0 {
+			rose.Calm += cell.Count
+			continue
+		}
+		if cell.Sector < 0 || cell.Sector >= len(rose.Sectors) || cell.Bin > len(bins) {
+			continue
+		}
+		rose.Sectors[cell.Sector].Counts[cell.Bin-1] += cell.Count
+	}
+
+	if rose.Total == 0 {
+		return rose, nil
+	}
+
+	percent := func(n int64) float64 { return float64(n) * 100 / float64(rose.Total) }
+	rose.CalmFrequency = percent(rose.Calm)
+	for i := range rose.Sectors {
+		var sectorTotal int64
+		for b, n := range rose.Sectors[i].Counts {
+			rose.Sectors[i].Frequencies[b] = percent(n)
+			sectorTotal += n
+		}
+		rose.Sectors[i].Frequency = percent(sectorTotal)
+	}
+
+	return rose, nil
+}
@@ -0,0 +1,261 @@
+package webhook
+
+import (
+	"EWSBE/internal/entity"
+	"EWSBE/internal/repository"
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	SignatureHeader = "X-EWSBE-Signature" // t=<unix seconds>,v1=<hex HMAC-SHA256 of "<t>.<body>">
+	EventHeader     = "X-EWSBE-Event"
+	DeliveryHeader  = "X-EWSBE-Delivery"
+
+	maxResponseBody = 1024
+)
+
+type Config struct {
+	Workers     int
+	QueueSize   int
+	MaxAttempts int
+	BaseBackoff time.Duration // delay before the first retry, doubled for each further one
+	MaxBackoff  time.Duration
+	Timeout     time.Duration // per request
+	Client      *http.Client  // optional; tests can point this at a local stand-in
+}
+
+type job struct {
+	hook       entity.Webhook
+	deliveryID string
+	event      string
+	body       []byte
+	attempt    int
+}
+
+// Dispatcher delivers webhook payloads asynchronously, retrying failures
+// with exponential backoff and logging every attempt
+type Dispatcher struct {
+	repo   repository.WebhookRepository
+	cfg    Config
+	client *http.Client
+
+	queue   chan job
+	pending sync.WaitGroup // queued jobs plus scheduled retries
+	workers sync.WaitGroup
+
+	mu      sync.Mutex
+	stopped bool // no new deliveries; retries still run until the queue closes
+	closed  bool // queue closed, workers exiting
+}
+
+func NewDispatcher(repo repository.WebhookRepository, cfg Config) *Dispatcher {
+	if cfg.Workers <= 0 {
+		cfg.Workers = 2
+	}
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = 256
+	}
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = 5
+	}
+	if cfg.BaseBackoff <= 0 {
+		cfg.BaseBackoff = 2 * time.Second
+	}
+	if cfg.MaxBackoff <= 0 {
+		cfg.MaxBackoff = 5 * time.Minute
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 10 * time.Second
+	}
+
+	client := cfg.Client
+	if client == nil {
+		client = &http.Client{Timeout: cfg.Timeout}
+	}
+
+	return &Dispatcher{
+		repo:   repo,
+		cfg:    cfg,
+		client: client,
+		queue:  make(chan job, cfg.QueueSize),
+	}
+}
+
+func (d *Dispatcher) Start() {
+	for i := 0; i < d.cfg.Workers; i++ {
+		d.workers.Add(1)
+		go func() {
+			defer d.workers.Done()
+			for j := range d.queue {
+				d.deliver(j)
+			}
+		}()
+	}
+}
+
+// Stop refuses new deliveries, waits for queued ones and pending retries
+// until ctx expires, then shuts the workers down
+func (d *Dispatcher) Stop(ctx context.Context) {
+	d.mu.Lock()
+	if d.stopped {
+		d.mu.Unlock()
+		return
+	}
+	d.stopped = true
+	d.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		d.pending.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		log.Printf("webhook: shutdown with deliveries still pending")
+	}
+
+	d.mu.Lock()
+	d.closed = true
+	close(d.queue)
+	d.mu.Unlock()
+	d.workers.Wait()
+}
+
+// Enqueue schedules the first delivery attempt; it returns false when the
+// queue is full or the dispatcher is stopped
+func (d *Dispatcher) Enqueue(hook entity.Webhook, deliveryID, event string, body []byte) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	// checked under mu so pending cannot grow once Stop is waiting on it
+	if d.stopped {
+		return false
+	}
+
+	d.pending.Add(1)
+	select {
+	case d.queue <- job{hook: hook, deliveryID: deliveryID, event: event, body: body, attempt: 1}:
+		return true
+	default:
+		d.pending.Done()
+		return false
+	}
+}
+
+// push queues a retry of a job that is already counted in pending
+func (d *Dispatcher) push(j job) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.closed {
+		return false
+	}
+
+	select {
+	case d.queue <- j:
+		return true
+	default:
+		return false
+	}
+}
+
+func (d *Dispatcher) deliver(j job) {
+	start := time.Now()
+	status, respBody, err := d.send(j)
+
+	record := &entity.WebhookDelivery{
+		WebhookID:    j.hook.ID,
+		DeliveryID:   j.deliveryID,
+		Event:        j.event,
+		Attempt:      j.attempt,
+		Payload:      string(j.body),
+		StatusCode:   status,
+		Success:      err == nil,
+		ResponseBody: respBody,
+		DurationMs:   time.Since(start).Milliseconds(),
+	}
+	if err != nil {
+		record.Error = err.Error()
+	}
+	if logErr := d.repo.CreateDelivery(record); logErr != nil {
+		log.Printf("webhook: failed to log delivery %s: %v", j.deliveryID, logErr)
+	}
+
+	if err == nil || j.attempt >= d.cfg.MaxAttempts {
+		if err != nil {
+			log.Printf("webhook: giving up on %s to %s after %d attempts: %v", j.event, j.hook.URL, j.attempt, err)
+		}
+		d.pending.Done()
+		return
+	}
+
+	// retry later without holding a worker
+	next := j
+	next.attempt++
+	time.AfterFunc(d.backoff(j.attempt), func() {
+		if !d.push(next) {
+			log.Printf("webhook: dropped retry of %s to %s (queue full or stopped)", next.event, next.hook.URL)
+			d.pending.Done()
+		}
+	})
+}
+
+// backoff returns the delay after the given failed attempt
+func (d *Dispatcher) backoff(attempt int) time.Duration {
+	delay := d.cfg.BaseBackoff
+	for i := 1; i < attempt; i++ {
+		delay *= 2
+		if delay >= d.cfg.MaxBackoff {
+			return d.cfg.MaxBackoff
+		}
+	}
+	return delay
+}
+
+func (d *Dispatcher) send(j job) (int, string, error) {
+	req, err := http.NewRequest(http.MethodPost, j.hook.URL, bytes.NewReader(j.body))
+	if err != nil {
+		return 0, "", err
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "EWSBE-Webhook/1.0")
+	req.Header.Set(EventHeader, j.event)
+	req.Header.Set(DeliveryHeader, j.deliveryID)
+	req.Header.Set(SignatureHeader, fmt.Sprintf("t=%s,v1=%s", timestamp, Sign(j.hook.Secret, timestamp, j.body)))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, "", err
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxResponseBody))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, string(body), errors.New("unexpected status " + resp.Status)
+	}
+	return resp.StatusCode, string(body), nil
+}
+
+// Sign computes the hex HMAC-SHA256 of "<timestamp>.<body>"; receivers
+// recompute it with their copy of the secret and compare in constant time
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,185 @@
+package webhook
+
+import (
+	"EWSBE/internal/entity"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeRepo keeps delivery records in memory
+type fakeRepo struct {
+	mu         sync.Mutex
+	deliveries []entity.WebhookDelivery
+}
+
+func (r *fakeRepo) CreateWebhook(*entity.Webhook) error                       { return nil }
+func (r *fakeRepo) GetAllWebhooks() ([]entity.Webhook, error)                 { return nil, nil }
+func (r *fakeRepo) GetWebhookByID(uint) (*entity.Webhook, error)              { return nil, nil }
+func (r *fakeRepo) UpdateWebhook(*entity.Webhook) error                       { return nil }
+func (r *fakeRepo) DeleteWebhook(uint) error                                  { return nil }
+func (r *fakeRepo) GetDeliveries(uint, int) ([]entity.WebhookDelivery, error) { return nil, nil }
+
+func (r *fakeRepo) CreateDelivery(d *entity.WebhookDelivery) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.deliveries = append(r.deliveries, *d)
+	return nil
+}
+
+func (r *fakeRepo) records() []entity.WebhookDelivery {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]entity.WebhookDelivery(nil), r.deliveries...)
+}
+
+func testConfig() Config {
+	return Config{
+		Workers:     1,
+		QueueSize:   8,
+		MaxAttempts: 3,
+		BaseBackoff: 20 * time.Millisecond,
+		MaxBackoff:  time.Second,
+		Timeout:     time.Second,
+	}
+}
+
+func stop(t *testing.T, d *Dispatcher) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	d.Stop(ctx)
+}
+
+func TestDispatcherSignsAndRetries(t *testing.T) {
+	const secret = "whsec_test"
+	body := []byte(`{"event":"alert.triggered"}`)
+
+	var mu sync.Mutex
+	var calls []time.Time
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, _ := io.ReadAll(r.Body)
+		var ts, sig string
+		for _, part := range strings.Split(r.Header.Get(SignatureHeader), ",") {
+			if v, ok := strings.CutPrefix(part, "t="); ok {
+				ts = v
+			}
+			if v, ok := strings.CutPrefix(part, "v1="); ok {
+				sig = v
+			}
+		}
+		if ts == "" || sig != Sign(secret, ts, got) {
+			t.Errorf("bad signature header %q", r.Header.Get(SignatureHeader))
+		}
+		if r.Header.Get(EventHeader) != "alert.triggered" || r.Header.Get(DeliveryHeader) != "dlv-1" {
+			t.Errorf("unexpected headers %v", r.Header)
+		}
+
+		mu.Lock()
+		calls = append(calls, time.Now())
+		n := len(calls)
+		mu.Unlock()
+		if n < 3 {
+			http.Error(w, "try again", http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprint(w, "ok")
+	}))
+	defer srv.Close()
+
+	repo := &fakeRepo{}
+	d := NewDispatcher(repo, testConfig())
+	d.Start()
+
+	hook := entity.Webhook{ID: 7, URL: srv.URL, Secret: secret}
+	if !d.Enqueue(hook, "dlv-1", "alert.triggered", body) {
+		t.Fatal("Enqueue returned false")
+	}
+	stop(t, d)
+
+	records := repo.records()
+	if len(records) != 3 {
+		t.Fatalf("logged %d attempts, want 3", len(records))
+	}
+	for i, rec := range records {
+		if rec.Attempt != i+1 || rec.WebhookID != 7 || rec.DeliveryID != "dlv-1" || rec.Payload != string(body) {
+			t.Errorf("attempt %d logged as %+v", i+1, rec)
+		}
+		wantSuccess := i == 2
+		if rec.Success != wantSuccess {
+			t.Errorf("attempt %d success = %v, want %v", i+1, rec.Success, wantSuccess)
+		}
+	}
+	if records[0].StatusCode != http.StatusServiceUnavailable || records[0].Error == "" {
+		t.Errorf("failed attempt logged as %+v", records[0])
+	}
+	if records[2].StatusCode != http.StatusOK || records[2].ResponseBody != "ok" {
+		t.Errorf("successful attempt logged as %+v", records[2])
+	}
+
+	// the second retry waits twice as long as the first
+	if gap := calls[1].Sub(calls[0]); gap < 20*time.Millisecond {
+		t.Errorf("first retry after %v, want at least 20ms", gap)
+	}
+	if gap := calls[2].Sub(calls[1]); gap < 40*time.Millisecond {
+		t.Errorf("second retry after %v, want at least 40ms", gap)
+	}
+}
+
+func TestDispatcherGivesUpAfterMaxAttempts(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	repo := &fakeRepo{}
+	d := NewDispatcher(repo, testConfig())
+	d.Start()
+	d.Enqueue(entity.Webhook{ID: 1, URL: srv.URL, Secret: "s"}, "dlv-2", "ping", []byte(`{}`))
+	stop(t, d)
+
+	records := repo.records()
+	if len(records) != 3 {
+		t.Fatalf("logged %d attempts, want 3", len(records))
+	}
+	for _, rec := range records {
+		if rec.Success {
+			t.Errorf("attempt %d logged as successful", rec.Attempt)
+		}
+	}
+}
+
+func TestDispatcherRefusesAfterStop(t *testing.T) {
+	d := NewDispatcher(&fakeRepo{}, testConfig())
+	d.Start()
+	stop(t, d)
+
+	if d.Enqueue(entity.Webhook{ID: 1, URL: "http://127.0.0.1:1"}, "dlv-3", "ping", nil) {
+		t.Fatal("Enqueue accepted a delivery after Stop")
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	d := NewDispatcher(&fakeRepo{}, Config{BaseBackoff: time.Second, MaxBackoff: 5 * time.Second})
+	tests := []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{1, time.Second},
+		{2, 2 * time.Second},
+		{3, 4 * time.Second},
+		{4, 5 * time.Second},
+		{10, 5 * time.Second},
+	}
+	for _, tt := range tests {
+		if got := d.backoff(tt.attempt); got != tt.want {
+			t.Errorf("backoff(%d) = %v, want %v", tt.attempt, got, tt.want)
+		}
+	}
+}
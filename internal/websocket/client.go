@@ -46,6 +46,24 @@ func NewClient(hub *Hub, conn *websocket.Conn) *Client {
 	}
 }
 
+// SendEvent queues an event for this client only, dropping it if the
+// client's buffer is full
+func (c *Client) SendEvent(event string, data interface{}) error {
+	message, err := json.Marshal(map[string]interface{}{
+		"event": event,
+		"data":  data,
+	})
+	if err != nil {
+		return err
+	}
+
+	select {
+	case c.send <- message:
+	default:
+	}
+	return nil
+}
+
 func (c *Client) ReadPump() {
 	defer func() {
 		c.hub.unregister <- c
@@ -73,6 +73,54 @@ func (h *Hub) BroadcastSensorData(data *entity.SensorData) error {
 	return nil
 }
 
+// BroadcastAlert pushes an alert state change to all clients
+func (h *Hub) BroadcastAlert(ev entity.AlertEvent) error {
+	event := map[string]interface{}{
+		"event": "alert:update",
+		"data":  ev,
+	}
+
+	message, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	h.broadcast <- message
+	return nil
+}
+
+// BroadcastStationStatus pushes a station online/stale/offline change to all clients
+func (h *Hub) BroadcastStationStatus(ev entity.StationStatusEvent) error {
+	event := map[string]interface{}{
+		"event": "station:status",
+		"data":  ev,
+	}
+
+	message, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	h.broadcast <- message
+	return nil
+}
+
+// BroadcastCommand pushes a downlink command state change to all clients
+func (h *Hub) BroadcastCommand(cmd entity.StationCommand) error {
+	event := map[string]interface{}{
+		"event": "station:command",
+		"data":  cmd,
+	}
+
+	message, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	h.broadcast <- message
+	return nil
+}
+
 func (h *Hub) ClientCount() int {
 	h.mu.RLock()
 	defer h.mu.RUnlock()